@@ -0,0 +1,256 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hibiken/asynq"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/token"
+	"github.com/kratos069/message-app/util"
+	"github.com/kratos069/message-app/worker"
+	"github.com/rs/zerolog/log"
+)
+
+var errWrongPassword = errors.New("current password is incorrect")
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+// ChangePassword sets a new password and logs out every other session
+func (server *Server) changePassword(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	var req changePasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	user, err := server.store.GetUserByID(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	err = util.CheckPassword(req.CurrentPassword, user.PasswordHash)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errResponse(errWrongPassword))
+		return
+	}
+
+	hashedPassword, err := util.HashPassword(req.NewPassword)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	result, err := server.store.ChangePasswordTx(ctx, db.ChangePasswordTxParams{
+		Username:         user.Username,
+		PasswordHash:     hashedPassword,
+		CurrentSessionID: authPayload.SessionID,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	// tokens of the other sessions stop working on the next request
+	for _, sessionID := range result.BlockedSessionIDs {
+		err = server.sessionGuard.revokeSession(ctx, sessionID,
+			server.revocationTTL())
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errResponse(err))
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message":          "Password changed successfully",
+		"revoked_sessions": len(result.BlockedSessionIDs),
+	})
+}
+
+type changeEmailRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewEmail        string `json:"new_email" binding:"required,email"`
+}
+
+// ChangeEmail sets a new, unverified email address,
+// sends a confirmation to it and a notice to the old one
+func (server *Server) changeEmail(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	var req changeEmailRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	user, err := server.store.GetUserByID(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	err = util.CheckPassword(req.CurrentPassword, user.PasswordHash)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errResponse(errWrongPassword))
+		return
+	}
+
+	if req.NewEmail == user.Email {
+		ctx.JSON(http.StatusBadRequest,
+			gin.H{"error": "new email is the same as the current one"})
+		return
+	}
+
+	result, err := server.store.ChangeEmailTx(ctx, db.ChangeEmailTxParams{
+		Username: user.Username,
+		NewEmail: req.NewEmail,
+		AfterUpdate: func(oldUser db.User, newUser db.User) error {
+			opts := []asynq.Option{
+				asynq.MaxRetry(10),
+				asynq.ProcessIn(7 * time.Second),
+				asynq.Queue(worker.QueueCritical),
+			}
+
+			// confirmation goes to the new address
+			err := server.taskDistributor.DistributeTaskSendBerifyEmail(ctx,
+				&worker.PayloadSendVerifyEmail{
+					Username: newUser.Username,
+				}, opts...)
+			if err != nil {
+				return err
+			}
+
+			// notice goes to the old address
+			return server.taskDistributor.DistributeTaskSendEmailChanged(ctx,
+				&worker.PayloadSendEmailChanged{
+					Username: oldUser.Username,
+					OldEmail: oldUser.Email,
+					NewEmail: newUser.Email,
+				}, opts...)
+		},
+	})
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			ctx.JSON(http.StatusConflict, gin.H{
+				"error": "Email already exists",
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	// account is unverified again from now on
+	server.sessionGuard.forgetUser(result.User.ID)
+
+	log.Info().Str("username", result.User.Username).Msg("Email changed")
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"user":    newUserResponse(result.User),
+		"message": "Email changed. Please check your inbox to verify it.",
+	})
+}
+
+const defaultDeletionGracePeriod = 14 * 24 * time.Hour
+
+type deleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// DeleteAccount deactivates the account right away and purges it
+// after the grace period, logging in before then cancels the deletion
+func (server *Server) deleteAccount(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	var req deleteAccountRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	user, err := server.store.GetUserByID(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	err = util.CheckPassword(req.Password, user.PasswordHash)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errResponse(errWrongPassword))
+		return
+	}
+
+	// the last admin who can act has to hand over first
+	result, err := server.store.RequestUserDeletionTx(ctx,
+		db.RequestUserDeletionTxParams{
+			UserID:    user.ID,
+			AdminRole: util.AdminRole,
+		})
+	if err != nil {
+		if errors.Is(err, db.ErrLastAdmin) {
+			ctx.JSON(http.StatusConflict, errResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	user = result.User
+
+	gracePeriod := server.config.DeletionGracePeriod
+	if gracePeriod == 0 {
+		gracePeriod = defaultDeletionGracePeriod
+	}
+	purgeAt := user.DeletionRequestedAt.Time.Add(gracePeriod)
+
+	requestedAt := user.DeletionRequestedAt.Time.UnixMicro()
+	opts := []asynq.Option{
+		asynq.MaxRetry(10),
+		asynq.ProcessAt(purgeAt),
+		asynq.Queue(worker.QueueDefault),
+		// the same request is never purged twice
+		asynq.TaskID(fmt.Sprintf("purge_account:%d:%d", user.ID, requestedAt)),
+	}
+
+	err = server.taskDistributor.DistributeTaskPurgeAccount(ctx,
+		&worker.PayloadPurgeAccount{
+			UserID:      user.ID,
+			RequestedAt: requestedAt,
+		}, opts...)
+	if err != nil {
+		// without a purge the account must not stay deactivated
+		if cancelErr := server.store.CancelUserDeletion(ctx, user.ID); cancelErr != nil {
+			log.Error().Err(cancelErr).Int64("user_id", user.ID).
+				Msg("Failed to cancel account deletion")
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	// every session ends, logging in again is how the user cancels
+	err = server.store.BlockUserSessions(ctx, user.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	err = server.sessionGuard.revokeUser(ctx, user.ID, server.revocationTTL())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{
+		"message":  "Account deactivated, log in before the purge to cancel the deletion",
+		"purge_at": purgeAt,
+	})
+}
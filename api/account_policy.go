@@ -0,0 +1,131 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/util"
+)
+
+var (
+	errAccountBanned    = errors.New("account is banned")
+	errEmailNotVerified = errors.New("email address is not verified")
+	errAccountDeleted   = errors.New("account is scheduled for deletion, log in to cancel")
+)
+
+// accountPolicy decides what banned and unverified accounts may do.
+// It is applied at login, on every authenticated request
+// and again when a message is sent.
+type accountPolicy struct {
+	unverifiedAccess string
+}
+
+func newAccountPolicy(unverifiedAccess string) accountPolicy {
+	switch unverifiedAccess {
+	case util.UnverifiedAccessFull, util.UnverifiedAccessNone:
+	default:
+		unverifiedAccess = util.UnverifiedAccessReadOnly
+	}
+
+	return accountPolicy{
+		unverifiedAccess: unverifiedAccess,
+	}
+}
+
+// checks if the user may log in at all
+func (policy accountPolicy) checkLogin(user db.User) error {
+	if user.IsBanned {
+		return errAccountBanned
+	}
+
+	if !user.IsEmailVerified &&
+		policy.unverifiedAccess == util.UnverifiedAccessNone {
+		return errEmailNotVerified
+	}
+
+	return nil
+}
+
+// checks if the user may perform a request,
+// readOnly requests are allowed for unverified users in read-only mode
+func (policy accountPolicy) checkAccess(user db.User, readOnly bool) error {
+	err := policy.checkLogin(user)
+	if err != nil {
+		return err
+	}
+
+	// logging in cancels the deletion, until then nothing else works
+	if user.DeletionRequestedAt.Valid {
+		return errAccountDeleted
+	}
+
+	if !user.IsEmailVerified && !readOnly &&
+		policy.unverifiedAccess == util.UnverifiedAccessReadOnly {
+		return errEmailNotVerified
+	}
+
+	return nil
+}
+
+// checks if the user may send messages
+func (policy accountPolicy) checkSend(user db.User) error {
+	return policy.checkAccess(user, false)
+}
+
+// writes the refusal for a policy error,
+// banned users are told the reason of their ban
+func abortWithPolicyError(ctx *gin.Context, user db.User, err error) {
+	if errors.Is(err, errAccountBanned) {
+		ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error":  err.Error(),
+			"reason": user.BannedReason.String,
+		})
+		return
+	}
+
+	ctx.AbortWithStatusJSON(http.StatusForbidden, errResponse(err))
+}
+
+// routes unverified users may use in read-only mode, by method and path:
+// everything that only reads, plus looking people up, staying online,
+// reading messages, logging out, leaving, taking their data along and
+// fixing a mistyped email address. New routes are closed until added.
+var readOnlyRoutes = map[string]bool{
+	"GET /account/exports":                    true,
+	"GET /account/privacy":                    true,
+	"GET /account/blocks":                     true,
+	"GET /users/me":                           true,
+	"GET /users/:id":                          true,
+	"GET /contacts":                           true,
+	"GET /conversations":                      true,
+	"GET /conversations/:id":                  true,
+	"GET /debug/:conversation_id":             true,
+	"GET /messages/:conversation_id":          true,
+	"GET /typing/:conversation_id":            true,
+	"GET /bots":                               true,
+	"GET /bots/:bot_id/keys":                  true,
+	"GET /webhooks":                           true,
+	"GET /webhooks/:webhook_id/deliveries":    true,
+	"GET /incoming_webhooks/:conversation_id": true,
+	"GET /admin":                              true,
+	"GET /admin/:user_id":                     true,
+	"GET /admin/audit/roles":                  true,
+	"GET /admin/stats":                        true,
+
+	"POST /logout":                         true,
+	"POST /account/email":                  true,
+	"POST /account/delete":                 true,
+	"POST /account/export":                 true,
+	"POST /users/lookup":                   true,
+	"POST /users/search":                   true,
+	"POST /presence/heartbeat":             true,
+	"POST /presence/query":                 true,
+	"POST /messages/:conversation_id/read": true,
+}
+
+// reports whether the request's route is one of readOnlyRoutes
+func isReadOnlyRequest(ctx *gin.Context) bool {
+	return readOnlyRoutes[ctx.Request.Method+" "+ctx.FullPath()]
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/token"
+	"github.com/kratos069/message-app/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountPolicy(t *testing.T) {
+	verified := db.User{IsEmailVerified: true}
+	unverified := db.User{}
+	banned := db.User{IsEmailVerified: true, IsBanned: true}
+	deleted := db.User{IsEmailVerified: true,
+		DeletionRequestedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true}}
+
+	testCases := []struct {
+		name     string
+		access   string
+		user     db.User
+		readOnly bool
+		err      error
+	}{
+		{"Verified", util.UnverifiedAccessReadOnly, verified, false, nil},
+		{"Banned", util.UnverifiedAccessFull, banned, true, errAccountBanned},
+		{"UnverifiedRead", util.UnverifiedAccessReadOnly, unverified, true, nil},
+		{"UnverifiedWrite", util.UnverifiedAccessReadOnly, unverified, false, errEmailNotVerified},
+		{"UnverifiedFull", util.UnverifiedAccessFull, unverified, false, nil},
+		{"UnverifiedNone", util.UnverifiedAccessNone, unverified, true, errEmailNotVerified},
+		{"DefaultIsReadOnly", "", unverified, false, errEmailNotVerified},
+		{"PendingDeletion", util.UnverifiedAccessFull, deleted, true, errAccountDeleted},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			policy := newAccountPolicy(tc.access)
+			err := policy.checkAccess(tc.user, tc.readOnly)
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func TestAccountPolicyLoginDuringDeletion(t *testing.T) {
+	user := db.User{IsEmailVerified: true,
+		DeletionRequestedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true}}
+
+	// logging in is how a pending deletion is cancelled
+	policy := newAccountPolicy(util.UnverifiedAccessReadOnly)
+	require.NoError(t, policy.checkLogin(user))
+}
+
+func TestUnverifiedReadOnlyRoutes(t *testing.T) {
+	sessionID := uuid.New()
+	store := &fakeSessionStore{
+		sessions: map[uuid.UUID]db.Session{
+			sessionID: {ID: sessionID, Username: "user"},
+		},
+		users: map[int64]db.User{
+			300: {ID: 300, Username: "user", Role: "customer"},
+		},
+	}
+
+	testCases := []struct {
+		route        string
+		path         string
+		expectedCode int
+	}{
+		{"GET /conversations", "/conversations", http.StatusOK},
+		{"GET /messages/:conversation_id", "/messages/7", http.StatusOK},
+		{"POST /users/search", "/users/search", http.StatusOK},
+		{"POST /users/lookup", "/users/lookup", http.StatusOK},
+		{"POST /presence/query", "/presence/query", http.StatusOK},
+		{"POST /presence/heartbeat", "/presence/heartbeat", http.StatusOK},
+		{"POST /messages/:conversation_id/read", "/messages/7/read", http.StatusOK},
+		{"POST /account/delete", "/account/delete", http.StatusOK},
+		{"POST /account/export", "/account/export", http.StatusOK},
+		{"POST /account/email", "/account/email", http.StatusOK},
+		{"POST /logout", "/logout", http.StatusOK},
+		{"POST /messages/:conversation_id", "/messages/7", http.StatusForbidden},
+		{"POST /conversations/:other_user_id", "/conversations/2", http.StatusForbidden},
+		{"PUT /contacts/:user_id", "/contacts/2", http.StatusForbidden},
+		{"DELETE /typing/:conversation_id", "/typing/7", http.StatusForbidden},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.route, func(t *testing.T) {
+			server := newTestServer(t, nil, nil)
+			guard := newSessionGuard(store, token.NewMemoryRevocationList(), time.Minute)
+
+			method, route, _ := strings.Cut(tc.route, " ")
+			router := gin.New()
+			router.Handle(method, route,
+				authMiddleware(server.tokenMaker, guard, server.accountPolicy,
+					[]string{util.AdminRole, util.CustomerRole}),
+				func(ctx *gin.Context) {
+					ctx.JSON(http.StatusOK, gin.H{})
+				},
+			)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(method, tc.path, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker,
+				authorizationTypeBearer, "user", 300, "customer", sessionID, time.Minute)
+			router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.expectedCode, recorder.Code)
+		})
+	}
+}
+
+func TestReadOnlyRoutesExist(t *testing.T) {
+	server := newTestServer(t, nil, nil)
+
+	routes := map[string]bool{}
+	for _, route := range server.router.Routes() {
+		routes[route.Method+" "+route.Path] = true
+	}
+
+	// a typo would quietly lock unverified users out
+	for route := range readOnlyRoutes {
+		require.True(t, routes[route], route)
+	}
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/util"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAccountStore has one admin left who can act
+type fakeAccountStore struct {
+	db.Store
+	user db.User
+}
+
+func (store *fakeAccountStore) GetUserByID(ctx context.Context,
+	id int64) (db.User, error) {
+	return store.user, nil
+}
+
+func (store *fakeAccountStore) RequestUserDeletionTx(ctx context.Context,
+	arg db.RequestUserDeletionTxParams) (db.RequestUserDeletionTxResults, error) {
+	if store.user.Role == arg.AdminRole {
+		return db.RequestUserDeletionTxResults{}, db.ErrLastAdmin
+	}
+	return db.RequestUserDeletionTxResults{User: store.user}, nil
+}
+
+func TestDeleteAccountLastAdmin(t *testing.T) {
+	password := util.RandomString(8)
+	hashedPassword, err := util.HashPassword(password)
+	require.NoError(t, err)
+
+	store := &fakeAccountStore{user: db.User{ID: 1, Username: "admin",
+		Role: util.AdminRole, PasswordHash: hashedPassword}}
+	server := newTestServer(t, store, nil)
+
+	router := newProfileTestRouter(newPrivacyTestPayload(1), http.MethodPost,
+		"/account/delete", server.deleteAccount)
+	body, err := json.Marshal(gin.H{"password": password})
+	require.NoError(t, err)
+	request, err := http.NewRequest(http.MethodPost, "/account/delete",
+		bytes.NewReader(body))
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusConflict, recorder.Code)
+	require.Contains(t, recorder.Body.String(), db.ErrLastAdmin.Error())
+}
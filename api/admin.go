@@ -1,11 +1,16 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/token"
+	"github.com/kratos069/message-app/util"
+	"github.com/rs/zerolog/log"
 )
 
 // ListUsers returns all users (admin only)
@@ -96,12 +101,38 @@ func (server *Server) banUser(ctx *gin.Context) {
 		return
 	}
 
+	// end the user's sessions and reject their tokens right away
+	err = server.store.BlockUserSessions(ctx, userBefore.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError,
+			"Failed to block user sessions")
+		return
+	}
+
+	err = server.sessionGuard.revokeUser(ctx, userBefore.ID,
+		server.revocationTTL())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError,
+			"Failed to revoke user tokens")
+		return
+	}
+
 	userAfter, err := server.store.GetUserByID(ctx, reqUser.UserID)
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, "User not found")
 		return
 	}
 
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	server.publishWebhookEvent(ctx, util.WebhookEventUserBanned, pgtype.Int8{},
+		gin.H{
+			"user_id":   userAfter.ID,
+			"username":  userAfter.Username,
+			"reason":    req.Reason,
+			"banned_by": authPayload.UserID,
+			"banned_at": userAfter.BannedAt.Time,
+		})
+
 	ctx.JSON(http.StatusOK, gin.H{
 		"user":    userAfter,
 		"message": "User banned successfully",
@@ -129,6 +160,8 @@ func (server *Server) unbanUser(ctx *gin.Context) {
 		return
 	}
 
+	server.sessionGuard.forgetUser(userBefore.ID)
+
 	userAfter, err := server.store.GetUserByID(ctx, reqUser.UserID)
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, "User not found")
@@ -180,3 +213,84 @@ func (server *Server) getStats(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, stats)
 }
+
+type changeUserRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=admin customer"`
+}
+
+// ChangeUserRole promotes or demotes a user (admin only),
+// the last admin can't be demoted
+func (server *Server) changeUserRole(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	var reqUser userIDStruct
+	if err := ctx.ShouldBindUri(&reqUser); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	var req changeUserRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	result, err := server.store.ChangeRoleTx(ctx, db.ChangeRoleTxParams{
+		ActorID:   authPayload.UserID,
+		TargetID:  reqUser.UserID,
+		NewRole:   req.Role,
+		AdminRole: util.AdminRole,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			ctx.JSON(http.StatusNotFound, "User not found")
+		case errors.Is(err, db.ErrLastAdmin),
+			errors.Is(err, db.ErrRoleUnchanged):
+			ctx.JSON(http.StatusConflict, errResponse(err))
+		default:
+			ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		}
+		return
+	}
+
+	// tokens carry the old role, the user has to log in again
+	err = server.sessionGuard.revokeUser(ctx, result.User.ID,
+		server.revocationTTL())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError,
+			"Failed to revoke user tokens")
+		return
+	}
+
+	log.Info().Int64("actor_id", authPayload.UserID).
+		Str("username", result.User.Username).
+		Str("old_role", result.AuditLog.OldRole).
+		Str("new_role", result.AuditLog.NewRole).
+		Msg("User role changed")
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"user":    newUserResponse(result.User),
+		"message": "User role changed successfully",
+	})
+}
+
+// ListRoleAuditLogs returns the history of role changes (admin only)
+func (server *Server) listRoleAuditLogs(ctx *gin.Context) {
+	limit := ctx.DefaultQuery("limit", "50")
+	offset := ctx.DefaultQuery("offset", "0")
+
+	logs, err := server.store.ListRoleAuditLogs(ctx, db.ListRoleAuditLogsParams{
+		Limit:  parseInt32(limit, 50),
+		Offset: parseInt32(offset, 0),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"logs":  logs,
+		"count": len(logs),
+	})
+}
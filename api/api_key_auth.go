@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/token"
+	"github.com/kratos069/message-app/util"
+	"github.com/rs/zerolog/log"
+)
+
+const authorizationAPIKeyKey = "authorization_api_key"
+
+var (
+	errInvalidAPIKey = errors.New("API key is invalid, expired or revoked")
+	errMissingScope  = errors.New("API key lacks the scope for this request")
+)
+
+// the only routes API keys can call, with the scope each needs
+var apiKeyRouteScopes = map[string]string{
+	http.MethodGet + " /conversations":              util.ScopeMessagesRead,
+	http.MethodGet + " /conversations/:id":          util.ScopeMessagesRead,
+	http.MethodGet + " /messages/:conversation_id":  util.ScopeMessagesRead,
+	http.MethodPost + " /messages/:conversation_id": util.ScopeMessagesSend,
+}
+
+// returns the key's user, or an error if the key must not be accepted.
+// Keys aren't cached, so revoking one takes effect right away.
+func (guard *sessionGuard) checkAPIKey(ctx context.Context,
+	rawKey string) (db.User, db.APIKey, error) {
+	prefix, ok := util.ParseAPIKey(rawKey)
+	if !ok {
+		return db.User{}, db.APIKey{}, errInvalidAPIKey
+	}
+
+	apiKey, err := guard.store.GetAPIKeyByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.User{}, db.APIKey{}, errInvalidAPIKey
+		}
+		return db.User{}, db.APIKey{}, fmt.Errorf("failed to get API key: %w", err)
+	}
+
+	hash := util.HashSecret(rawKey)
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(apiKey.KeyHash)) != 1 {
+		return db.User{}, db.APIKey{}, errInvalidAPIKey
+	}
+	if apiKey.RevokedAt.Valid {
+		return db.User{}, db.APIKey{}, errInvalidAPIKey
+	}
+	if apiKey.ExpiresAt.Valid && time.Now().After(apiKey.ExpiresAt.Time) {
+		return db.User{}, db.APIKey{}, errInvalidAPIKey
+	}
+
+	user, err := guard.user(ctx, apiKey.UserID)
+	if err != nil {
+		return db.User{}, db.APIKey{}, err
+	}
+	if !user.IsBot {
+		return db.User{}, db.APIKey{}, errInvalidAPIKey
+	}
+
+	// at most once a minute, see the query
+	err = guard.store.TouchAPIKey(ctx, apiKey.ApiKeyID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to update API key usage")
+	}
+
+	return user, apiKey, nil
+}
+
+// authenticates a request made with an API key, which only reaches
+// the routes its scopes allow
+func authenticateAPIKey(ctx *gin.Context, guard *sessionGuard,
+	policy accountPolicy, rawKey string, accessibleRoles []string) bool {
+	user, apiKey, err := guard.checkAPIKey(ctx, rawKey)
+	if err != nil {
+		if errors.Is(err, errInvalidAPIKey) {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errResponse(err))
+			return false
+		}
+		ctx.AbortWithStatusJSON(sessionErrorStatus(err), errResponse(err))
+		return false
+	}
+
+	err = policy.checkAccess(user, isReadOnlyRequest(ctx))
+	if err != nil {
+		abortWithPolicyError(ctx, user, err)
+		return false
+	}
+
+	scope, ok := apiKeyRouteScopes[ctx.Request.Method+" "+ctx.FullPath()]
+	if !ok || !slices.Contains(apiKey.Scopes, scope) {
+		ctx.AbortWithStatusJSON(http.StatusForbidden, errResponse(errMissingScope))
+		return false
+	}
+
+	if !hasPermissions(user.Role, accessibleRoles) {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized,
+			errResponse(fmt.Errorf("permission denied")))
+		return false
+	}
+
+	// handlers read the caller from the payload like for tokens,
+	// there is no session behind an API key
+	ctx.Set(authorizationPayloadKey, &token.Payload{
+		UserID:    user.ID,
+		Username:  user.Username,
+		Role:      user.Role,
+		IssuedAt:  apiKey.CreatedAt,
+		ExpiredAt: apiKey.ExpiresAt.Time,
+	})
+	ctx.Set(authorizationAPIKeyKey, apiKey)
+
+	return true
+}
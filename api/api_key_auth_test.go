@@ -0,0 +1,115 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/token"
+	"github.com/kratos069/message-app/util"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAPIKey(userID int64, scopes ...string) (string, db.APIKey) {
+	key, prefix := util.GenerateAPIKey()
+
+	return key, db.APIKey{
+		ApiKeyID:  util.RandomInt(1, 1000),
+		UserID:    userID,
+		Prefix:    prefix,
+		KeyHash:   util.HashSecret(key),
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+	}
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	readKey, readAPIKey := newTestAPIKey(300, util.ScopeMessagesRead)
+	sendKey, sendAPIKey := newTestAPIKey(300, util.ScopeMessagesSend)
+	humanKey, humanAPIKey := newTestAPIKey(100, util.ScopeMessagesRead)
+
+	revokedKey, revokedAPIKey := newTestAPIKey(300, util.ScopeMessagesRead)
+	revokedAPIKey.RevokedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+
+	expiredKey, expiredAPIKey := newTestAPIKey(300, util.ScopeMessagesRead)
+	expiredAPIKey.ExpiresAt = pgtype.Timestamptz{
+		Time: time.Now().Add(-time.Minute), Valid: true}
+
+	// right id, wrong secret
+	forgedKey := readKey[:len(readKey)-1] + "x"
+	if forgedKey == readKey {
+		forgedKey = readKey[:len(readKey)-1] + "y"
+	}
+
+	store := &fakeSessionStore{
+		users: map[int64]db.User{
+			100: {ID: 100, Username: "user", Role: "customer", IsEmailVerified: true},
+			300: {ID: 300, Username: "bot", Role: "customer", IsEmailVerified: true,
+				IsBot: true},
+		},
+		apiKeys: map[string]db.APIKey{
+			readAPIKey.Prefix:    readAPIKey,
+			sendAPIKey.Prefix:    sendAPIKey,
+			humanAPIKey.Prefix:   humanAPIKey,
+			revokedAPIKey.Prefix: revokedAPIKey,
+			expiredAPIKey.Prefix: expiredAPIKey,
+		},
+	}
+
+	testCases := []struct {
+		name         string
+		method       string
+		path         string
+		key          string
+		expectedCode int
+	}{
+		{"ReadOK", http.MethodGet, "/messages/1", readKey, http.StatusOK},
+		{"SendOK", http.MethodPost, "/messages/1", sendKey, http.StatusOK},
+		{"MissingScope", http.MethodPost, "/messages/1", readKey, http.StatusForbidden},
+		{"RouteNotAllowed", http.MethodPost, "/logout", readKey, http.StatusForbidden},
+		{"RevokedKey", http.MethodGet, "/messages/1", revokedKey, http.StatusUnauthorized},
+		{"ExpiredKey", http.MethodGet, "/messages/1", expiredKey, http.StatusUnauthorized},
+		{"ForgedKey", http.MethodGet, "/messages/1", forgedKey, http.StatusUnauthorized},
+		{"NotABot", http.MethodGet, "/messages/1", humanKey, http.StatusUnauthorized},
+		{"UnknownKey", http.MethodGet, "/messages/1",
+			util.APIKeyPrefix + util.RandomString(12) + "_" + util.RandomString(40),
+			http.StatusUnauthorized},
+		{"MalformedKey", http.MethodGet, "/messages/1", util.APIKeyPrefix + "abc",
+			http.StatusUnauthorized},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			server := newTestServer(t, nil, nil)
+
+			guard := newSessionGuard(store, token.NewMemoryRevocationList(), time.Minute)
+			auth := authMiddleware(server.tokenMaker, guard, server.accountPolicy,
+				[]string{util.AdminRole, util.CustomerRole})
+			handler := func(ctx *gin.Context) {
+				payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+				require.Equal(t, int64(300), payload.UserID)
+				ctx.JSON(http.StatusOK, gin.H{})
+			}
+			// the server's own routes need a real store
+			router := gin.New()
+			router.GET("/messages/:conversation_id", auth, handler)
+			router.POST("/messages/:conversation_id", auth, handler)
+			router.POST("/logout", auth, handler)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(tc.method, tc.path, nil)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey,
+				authorizationTypeBearer+" "+tc.key)
+
+			router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.expectedCode, recorder.Code)
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/token"
+)
+
+var errBlockSelf = errors.New("you cannot block yourself")
+
+type blockedUserResponse struct {
+	UserID            int64     `json:"user_id"`
+	Username          string    `json:"username"`
+	ProfilePictureUrl *string   `json:"profile_picture_url"`
+	BlockedAt         time.Time `json:"blocked_at"`
+}
+
+// BlockUser stops another user from contacting the caller. The blocked
+// user isn't told, to them the caller just looks unreachable and offline.
+func (server *Server) blockUser(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	var req userIDStruct
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	if req.UserID == authPayload.UserID {
+		ctx.JSON(http.StatusBadRequest, errResponse(errBlockSelf))
+		return
+	}
+
+	_, err := server.store.GetUserByID(ctx, req.UserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errResponse(errProfileNotFound))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	// blocking twice is fine
+	err = server.store.BlockUser(ctx, db.BlockUserParams{
+		BlockerID: authPayload.UserID,
+		BlockedID: req.UserID,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"user_id": req.UserID,
+		"message": "User blocked",
+	})
+}
+
+// UnblockUser lets a blocked user contact the caller again
+func (server *Server) unblockUser(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	var req userIDStruct
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	err := server.store.UnblockUser(ctx, db.UnblockUserParams{
+		BlockerID: authPayload.UserID,
+		BlockedID: req.UserID,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"user_id": req.UserID,
+		"message": "User unblocked",
+	})
+}
+
+// ListBlockedUsers returns the users the caller blocked, newest first
+func (server *Server) listBlockedUsers(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	blocked, err := server.store.ListBlockedUsers(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	resp := make([]blockedUserResponse, 0, len(blocked))
+	for _, user := range blocked {
+		resp = append(resp, blockedUserResponse{
+			UserID:            user.BlockedID,
+			Username:          user.Username,
+			ProfilePictureUrl: textPtr(user.ProfilePictureUrl),
+			BlockedAt:         user.CreatedAt,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"blocked_users": resp,
+		"count":         len(resp),
+	})
+}
+
+// ==========================================================================
+// ================================Helper====================================
+// ==========================================================================
+
+// returns which of userIDs blocked userID or were blocked by them
+func (server *Server) blockedAmong(ctx context.Context, userID int64,
+	userIDs []int64) (map[int64]bool, error) {
+	blockedIDs, err := server.store.ListBlockedEitherWay(ctx,
+		db.ListBlockedEitherWayParams{
+			UserID:  userID,
+			UserIds: userIDs,
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return idSet(blockedIDs), nil
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/stretchr/testify/require"
+)
+
+func (store *fakePrivacyStore) BlockUser(ctx context.Context,
+	arg db.BlockUserParams) error {
+	key := [2]int64{arg.BlockerID, arg.BlockedID}
+	if _, ok := store.blocks[key]; !ok {
+		store.blocks[key] = time.Now()
+	}
+	return nil
+}
+
+func (store *fakePrivacyStore) UnblockUser(ctx context.Context,
+	arg db.UnblockUserParams) error {
+	delete(store.blocks, [2]int64{arg.BlockerID, arg.BlockedID})
+	return nil
+}
+
+func (store *fakePrivacyStore) ListBlockedUsers(ctx context.Context,
+	blockerID int64) ([]db.ListBlockedUsersRow, error) {
+	rows := []db.ListBlockedUsersRow{}
+	for key, blockedAt := range store.blocks {
+		if key[0] == blockerID {
+			rows = append(rows, db.ListBlockedUsersRow{
+				BlockedID: key[1],
+				CreatedAt: blockedAt,
+			})
+		}
+	}
+	// like ORDER BY created_at DESC
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].CreatedAt.After(rows[j].CreatedAt)
+	})
+	return rows, nil
+}
+
+func TestBlockUser(t *testing.T) {
+	store := newFakePrivacyStore()
+	server := newTestServer(t, store, nil)
+	payload := newPrivacyTestPayload(1)
+
+	send := func(method, path string) *httptest.ResponseRecorder {
+		router := newProfileTestRouter(payload, http.MethodPost,
+			"/account/blocks/:user_id", server.blockUser)
+		router.DELETE("/account/blocks/:user_id", func(ctx *gin.Context) {
+			ctx.Set(authorizationPayloadKey, payload)
+			server.unblockUser(ctx)
+		})
+		router.GET("/account/blocks", func(ctx *gin.Context) {
+			ctx.Set(authorizationPayloadKey, payload)
+			server.listBlockedUsers(ctx)
+		})
+
+		request, err := http.NewRequest(method, path, nil)
+		require.NoError(t, err)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	require.Equal(t, http.StatusBadRequest,
+		send(http.MethodPost, "/account/blocks/1").Code)
+	require.Equal(t, http.StatusBadRequest,
+		send(http.MethodPost, "/account/blocks/0").Code)
+
+	require.Equal(t, http.StatusOK, send(http.MethodPost, "/account/blocks/2").Code)
+	// blocking twice is fine
+	require.Equal(t, http.StatusOK, send(http.MethodPost, "/account/blocks/2").Code)
+	require.Equal(t, http.StatusOK, send(http.MethodPost, "/account/blocks/3").Code)
+
+	recorder := send(http.MethodGet, "/account/blocks")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var resp struct {
+		BlockedUsers []blockedUserResponse `json:"blocked_users"`
+		Count        int                   `json:"count"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	require.Equal(t, 2, resp.Count)
+
+	require.Equal(t, http.StatusOK, send(http.MethodDelete, "/account/blocks/2").Code)
+	require.Len(t, store.blocks, 1)
+	require.Contains(t, store.blocks, [2]int64{1, 3})
+}
+
+func TestBlockedUsersCantReachEachOther(t *testing.T) {
+	store := newFakePrivacyStore()
+	server := newTestServer(t, store, nil)
+
+	// 2 blocked 1
+	store.conversations[conversationKey(1, 2)] = 10
+	store.blocks[[2]int64{2, 1}] = time.Now()
+
+	// the blocked user sees the same answer as for a closed inbox
+	for _, senderID := range []int64{1, 2} {
+		router := newProfileTestRouter(newPrivacyTestPayload(senderID),
+			http.MethodPost, "/conversations/:other_user_id",
+			server.GetOrCreateDirectConversation)
+		request, err := http.NewRequest(http.MethodPost,
+			fmt.Sprintf("/conversations/%d", 3-senderID), nil)
+		require.NoError(t, err)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		require.Equal(t, http.StatusForbidden, recorder.Code)
+		require.Contains(t, recorder.Body.String(), errDirectMessagesClosed.Error())
+	}
+
+	// and the blocker always looks offline, without read receipts
+	view, err := server.newPrivacyView(context.Background(), 1, []int64{2})
+	require.NoError(t, err)
+	require.False(t, view.showOnline(2))
+	require.False(t, view.showLastSeen(2))
+	require.False(t, view.showReadReceipts(2))
+
+	view, err = server.newPrivacyView(context.Background(), 2, []int64{1})
+	require.NoError(t, err)
+	require.False(t, view.showOnline(1))
+}
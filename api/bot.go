@@ -0,0 +1,398 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/token"
+	"github.com/kratos069/message-app/util"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// bots get an email no one can receive mail at
+const botEmailDomain = "bots.invalid"
+
+var (
+	errBotNotFound     = errors.New("bot not found")
+	errAPIKeyNotFound  = errors.New("API key not found")
+	errBotCreatesBot   = errors.New("bots can't create bots")
+	errInvalidScope    = errors.New("unknown API key scope")
+	errAPIKeyIsRevoked = errors.New("API key is already revoked")
+)
+
+type botResponse struct {
+	BotID     int64     `json:"bot_id"`
+	Username  string    `json:"username"`
+	OwnerID   int64     `json:"owner_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newBotResponse(bot db.User) botResponse {
+	return botResponse{
+		BotID:     bot.ID,
+		Username:  bot.Username,
+		OwnerID:   bot.BotOwnerID.Int64,
+		CreatedAt: bot.CreatedAt,
+	}
+}
+
+// never contains the key itself, only createAPIKey and
+// rotateAPIKey return it, once
+type apiKeyResponse struct {
+	APIKeyID   int64      `json:"api_key_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func newAPIKeyResponse(apiKey db.APIKey) apiKeyResponse {
+	return apiKeyResponse{
+		APIKeyID:   apiKey.ApiKeyID,
+		Name:       apiKey.Name,
+		Prefix:     apiKey.Prefix,
+		Scopes:     apiKey.Scopes,
+		LastUsedAt: timestamptzPtr(apiKey.LastUsedAt),
+		ExpiresAt:  timestamptzPtr(apiKey.ExpiresAt),
+		RevokedAt:  timestamptzPtr(apiKey.RevokedAt),
+		CreatedAt:  apiKey.CreatedAt,
+	}
+}
+
+type createdAPIKeyResponse struct {
+	APIKey apiKeyResponse `json:"api_key"`
+	// shown only once, it is stored hashed
+	Key string `json:"key"`
+}
+
+type createBotRequest struct {
+	Username string `json:"username" binding:"required,min=3,max=50,alphanum"`
+}
+
+// CreateBot creates a bot owned by the caller
+func (server *Server) createBot(ctx *gin.Context) {
+	var req createBotRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	owner, err := server.store.GetUserByID(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	if owner.IsBot {
+		ctx.JSON(http.StatusForbidden, errResponse(errBotCreatesBot))
+		return
+	}
+
+	// bots can't log in, nobody knows this password
+	hashedPassword, err := bcrypt.GenerateFromPassword(
+		[]byte(util.RandomString(32)), bcrypt.DefaultCost)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	bot, err := server.store.CreateBot(ctx, db.CreateBotParams{
+		Username:     req.Username,
+		Email:        req.Username + "@" + botEmailDomain,
+		PasswordHash: string(hashedPassword),
+		Role:         util.CustomerRole,
+		BotOwnerID:   pgtype.Int8{Int64: owner.ID, Valid: true},
+	})
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			ctx.JSON(http.StatusConflict, gin.H{
+				"error": "Username already exists",
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newBotResponse(bot))
+}
+
+// ListBots returns the caller's bots
+func (server *Server) listBots(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	bots, err := server.store.ListBotsByOwner(ctx,
+		pgtype.Int8{Int64: authPayload.UserID, Valid: true})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	resp := make([]botResponse, 0, len(bots))
+	for _, bot := range bots {
+		resp = append(resp, newBotResponse(bot))
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"bots":  resp,
+		"count": len(resp),
+	})
+}
+
+type botIDRequest struct {
+	BotID int64 `uri:"bot_id" binding:"required,min=1"`
+}
+
+type createAPIKeyRequest struct {
+	Name   string   `json:"name" binding:"required,min=1,max=100"`
+	Scopes []string `json:"scopes" binding:"required,min=1,dive,required"`
+	// keys without expiry live until they are revoked
+	ExpiresInDays int `json:"expires_in_days" binding:"omitempty,min=1,max=3650"`
+}
+
+// CreateAPIKey creates a key for a bot of the caller (or any bot for admins)
+func (server *Server) createAPIKey(ctx *gin.Context) {
+	var uri botIDRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	var req createAPIKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	for _, scope := range req.Scopes {
+		if !util.IsValidScope(scope) {
+			ctx.JSON(http.StatusBadRequest, errResponse(errInvalidScope))
+			return
+		}
+	}
+
+	bot, ok := server.managedBot(ctx, uri.BotID)
+	if !ok {
+		return
+	}
+
+	var expiresAt pgtype.Timestamptz
+	if req.ExpiresInDays > 0 {
+		expiresAt = pgtype.Timestamptz{
+			Time:  time.Now().AddDate(0, 0, req.ExpiresInDays),
+			Valid: true,
+		}
+	}
+
+	key, prefix := util.GenerateAPIKey()
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	apiKey, err := server.store.CreateAPIKey(ctx, db.CreateAPIKeyParams{
+		UserID:    bot.ID,
+		Name:      req.Name,
+		Prefix:    prefix,
+		KeyHash:   util.HashSecret(key),
+		Scopes:    req.Scopes,
+		CreatedBy: pgtype.Int8{Int64: authPayload.UserID, Valid: true},
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, createdAPIKeyResponse{
+		APIKey: newAPIKeyResponse(apiKey),
+		Key:    key,
+	})
+}
+
+// ListAPIKeys returns the keys of a bot, revoked ones included
+func (server *Server) listAPIKeys(ctx *gin.Context) {
+	var uri botIDRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	bot, ok := server.managedBot(ctx, uri.BotID)
+	if !ok {
+		return
+	}
+
+	apiKeys, err := server.store.ListAPIKeys(ctx, bot.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	resp := make([]apiKeyResponse, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		resp = append(resp, newAPIKeyResponse(apiKey))
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"api_keys": resp,
+		"count":    len(resp),
+	})
+}
+
+type apiKeyIDRequest struct {
+	BotID    int64 `uri:"bot_id" binding:"required,min=1"`
+	APIKeyID int64 `uri:"key_id" binding:"required,min=1"`
+}
+
+// RotateAPIKey replaces a key with a new one with the same name,
+// scopes and lifetime, the old key stops working right away
+func (server *Server) rotateAPIKey(ctx *gin.Context) {
+	var uri apiKeyIDRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	oldKey, ok := server.managedAPIKey(ctx, uri)
+	if !ok {
+		return
+	}
+
+	// the new key lives as long as the old one was meant to
+	var expiresAt pgtype.Timestamptz
+	if oldKey.ExpiresAt.Valid {
+		lifetime := oldKey.ExpiresAt.Time.Sub(oldKey.CreatedAt)
+		expiresAt = pgtype.Timestamptz{
+			Time:  time.Now().Add(lifetime),
+			Valid: true,
+		}
+	}
+
+	key, prefix := util.GenerateAPIKey()
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	result, err := server.store.RotateAPIKeyTx(ctx, db.RotateAPIKeyTxParams{
+		OldAPIKeyID: oldKey.ApiKeyID,
+		NewAPIKey: db.CreateAPIKeyParams{
+			UserID:    oldKey.UserID,
+			Name:      oldKey.Name,
+			Prefix:    prefix,
+			KeyHash:   util.HashSecret(key),
+			Scopes:    oldKey.Scopes,
+			CreatedBy: pgtype.Int8{Int64: authPayload.UserID, Valid: true},
+			ExpiresAt: expiresAt,
+		},
+	})
+	if err != nil {
+		// revoked by a concurrent request
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.JSON(http.StatusConflict, errResponse(errAPIKeyIsRevoked))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, createdAPIKeyResponse{
+		APIKey: newAPIKeyResponse(result.NewAPIKey),
+		Key:    key,
+	})
+}
+
+// RevokeAPIKey makes a key stop working right away
+func (server *Server) revokeAPIKey(ctx *gin.Context) {
+	var uri apiKeyIDRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	apiKey, ok := server.managedAPIKey(ctx, uri)
+	if !ok {
+		return
+	}
+
+	apiKey, err := server.store.RevokeAPIKey(ctx, apiKey.ApiKeyID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.JSON(http.StatusConflict, errResponse(errAPIKeyIsRevoked))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newAPIKeyResponse(apiKey))
+}
+
+// ==========================================================================
+// ================================Helper====================================
+// ==========================================================================
+
+// returns the bot if the caller owns it or is an admin,
+// otherwise it writes the response and reports false.
+// Other users' bots are reported as missing.
+func (server *Server) managedBot(ctx *gin.Context, botID int64) (db.User, bool) {
+	bot, err := server.store.GetUserByID(ctx, botID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errResponse(errBotNotFound))
+			return bot, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return bot, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	isOwner := bot.BotOwnerID.Valid && bot.BotOwnerID.Int64 == authPayload.UserID
+	if !bot.IsBot || (!isOwner && authPayload.Role != util.AdminRole) {
+		ctx.JSON(http.StatusNotFound, errResponse(errBotNotFound))
+		return bot, false
+	}
+
+	return bot, true
+}
+
+// returns a not yet revoked key of a bot the caller manages
+func (server *Server) managedAPIKey(ctx *gin.Context,
+	uri apiKeyIDRequest) (db.APIKey, bool) {
+	bot, ok := server.managedBot(ctx, uri.BotID)
+	if !ok {
+		return db.APIKey{}, false
+	}
+
+	apiKey, err := server.store.GetAPIKey(ctx, uri.APIKeyID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errResponse(errAPIKeyNotFound))
+			return apiKey, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return apiKey, false
+	}
+	if apiKey.UserID != bot.ID {
+		ctx.JSON(http.StatusNotFound, errResponse(errAPIKeyNotFound))
+		return apiKey, false
+	}
+	if apiKey.RevokedAt.Valid {
+		ctx.JSON(http.StatusConflict, errResponse(errAPIKeyIsRevoked))
+		return apiKey, false
+	}
+
+	return apiKey, true
+}
+
+func timestamptzPtr(ts pgtype.Timestamptz) *time.Time {
+	if !ts.Valid {
+		return nil
+	}
+	return &ts.Time
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/token"
+)
+
+var errContactSelf = errors.New("you cannot add yourself as a contact")
+
+type contactResponse struct {
+	UserID            int64     `json:"user_id"`
+	Username          string    `json:"username"`
+	Nickname          *string   `json:"nickname"`
+	ProfilePictureUrl *string   `json:"profile_picture_url"`
+	IsMutual          bool      `json:"is_mutual"`
+	AddedAt           time.Time `json:"added_at"`
+}
+
+// ListContacts returns the caller's contacts sorted by nickname or username
+func (server *Server) listContacts(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	contacts, err := server.store.ListContacts(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	resp := make([]contactResponse, 0, len(contacts))
+	for _, contact := range contacts {
+		resp = append(resp, contactResponse{
+			UserID:            contact.ContactID,
+			Username:          contact.Username,
+			Nickname:          textPtr(contact.Nickname),
+			ProfilePictureUrl: textPtr(contact.ProfilePictureUrl),
+			IsMutual:          contact.IsMutual,
+			AddedAt:           contact.CreatedAt,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"contacts": resp,
+		"count":    len(resp),
+	})
+}
+
+// the body is optional, an empty nickname removes it
+type addContactRequest struct {
+	Nickname string `json:"nickname" binding:"max=50"`
+}
+
+// AddContact adds a user to the caller's contacts or changes their nickname
+func (server *Server) addContact(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	var uri userIDStruct
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	var req addContactRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	if uri.UserID == authPayload.UserID {
+		ctx.JSON(http.StatusBadRequest, errResponse(errContactSelf))
+		return
+	}
+
+	user, err := server.store.GetUserByID(ctx, uri.UserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errResponse(errProfileNotFound))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	if user.DeletionRequestedAt.Valid {
+		ctx.JSON(http.StatusNotFound, errResponse(errProfileNotFound))
+		return
+	}
+
+	contact, err := server.store.UpsertContact(ctx, db.UpsertContactParams{
+		OwnerID:   authPayload.UserID,
+		ContactID: user.ID,
+		Nickname:  pgtype.Text{String: req.Nickname, Valid: req.Nickname != ""},
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	mutual, err := server.store.IsContact(ctx, db.IsContactParams{
+		OwnerID:   user.ID,
+		ContactID: authPayload.UserID,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, contactResponse{
+		UserID:            user.ID,
+		Username:          user.Username,
+		Nickname:          textPtr(contact.Nickname),
+		ProfilePictureUrl: textPtr(user.ProfilePictureUrl),
+		IsMutual:          mutual,
+		AddedAt:           contact.CreatedAt,
+	})
+}
+
+// RemoveContact removes a user from the caller's contacts
+func (server *Server) removeContact(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	var uri userIDStruct
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	err := server.store.RemoveContact(ctx, db.RemoveContactParams{
+		OwnerID:   authPayload.UserID,
+		ContactID: uri.UserID,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"user_id": uri.UserID,
+		"message": "Contact removed",
+	})
+}
+
+// ==========================================================================
+// ================================Helper====================================
+// ==========================================================================
+
+// returns which of userIDs userID added as contacts
+func (server *Server) contactsAmong(ctx context.Context, userID int64,
+	userIDs []int64) (map[int64]bool, error) {
+	contactIDs, err := server.store.ListContactIDs(ctx,
+		db.ListContactIDsParams{
+			OwnerID: userID,
+			UserIds: userIDs,
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return idSet(contactIDs), nil
+}
+
+// returns which of userIDs added userID as a contact
+func (server *Server) contactOwnersAmong(ctx context.Context, userID int64,
+	userIDs []int64) (map[int64]bool, error) {
+	ownerIDs, err := server.store.ListContactOwnerIDs(ctx,
+		db.ListContactOwnerIDsParams{
+			ContactID: userID,
+			UserIds:   userIDs,
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return idSet(ownerIDs), nil
+}
+
+func idSet(ids []int64) map[int64]bool {
+	set := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/stretchr/testify/require"
+)
+
+func (store *fakePrivacyStore) UpsertContact(ctx context.Context,
+	arg db.UpsertContactParams) (db.Contact, error) {
+	store.contacts[[2]int64{arg.OwnerID, arg.ContactID}] = arg.Nickname.String
+	return db.Contact{
+		OwnerID:   arg.OwnerID,
+		ContactID: arg.ContactID,
+		Nickname:  arg.Nickname,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+func (store *fakePrivacyStore) RemoveContact(ctx context.Context,
+	arg db.RemoveContactParams) error {
+	delete(store.contacts, [2]int64{arg.OwnerID, arg.ContactID})
+	return nil
+}
+
+func (store *fakePrivacyStore) IsContact(ctx context.Context,
+	arg db.IsContactParams) (bool, error) {
+	_, ok := store.contacts[[2]int64{arg.OwnerID, arg.ContactID}]
+	return ok, nil
+}
+
+func (store *fakePrivacyStore) ListContacts(ctx context.Context,
+	ownerID int64) ([]db.ListContactsRow, error) {
+	rows := []db.ListContactsRow{}
+	for key, nickname := range store.contacts {
+		if key[0] != ownerID {
+			continue
+		}
+		_, mutual := store.contacts[[2]int64{key[1], key[0]}]
+		rows = append(rows, db.ListContactsRow{
+			ContactID: key[1],
+			Nickname:  pgtype.Text{String: nickname, Valid: nickname != ""},
+			IsMutual:  mutual,
+		})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].ContactID < rows[j].ContactID })
+	return rows, nil
+}
+
+func TestContacts(t *testing.T) {
+	store := newFakePrivacyStore()
+	server := newTestServer(t, store, nil)
+
+	add := func(userID int64, path, body string) *httptest.ResponseRecorder {
+		router := newProfileTestRouter(newPrivacyTestPayload(userID),
+			http.MethodPut, "/contacts/:user_id", server.addContact)
+		request, err := http.NewRequest(http.MethodPut, path,
+			bytes.NewBufferString(body))
+		require.NoError(t, err)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+	list := func(userID int64) []contactResponse {
+		recorder := sendAs(t, userID, http.MethodGet, "/contacts", "/contacts",
+			server.listContacts)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		var resp struct {
+			Contacts []contactResponse `json:"contacts"`
+		}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+		return resp.Contacts
+	}
+
+	recorder := add(1, "/contacts/2", `{"nickname":"Bobby"}`)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var contact contactResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &contact))
+	require.Equal(t, int64(2), contact.UserID)
+	require.Equal(t, "Bobby", *contact.Nickname)
+	require.False(t, contact.IsMutual)
+
+	// the body is optional
+	recorder = add(2, "/contacts/1", "")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &contact))
+	require.Nil(t, contact.Nickname)
+	require.True(t, contact.IsMutual)
+
+	contacts := list(1)
+	require.Len(t, contacts, 1)
+	require.True(t, contacts[0].IsMutual)
+	require.Equal(t, "Bobby", *contacts[0].Nickname)
+
+	// adding again only changes the nickname
+	require.Equal(t, http.StatusOK, add(1, "/contacts/2", `{"nickname":""}`).Code)
+	contacts = list(1)
+	require.Len(t, contacts, 1)
+	require.Nil(t, contacts[0].Nickname)
+
+	require.Equal(t, http.StatusBadRequest, add(1, "/contacts/1", "").Code)
+	require.Equal(t, http.StatusBadRequest, add(1, "/contacts/3",
+		`{"nickname":"`+strings.Repeat("a", 51)+`"}`).Code)
+	require.Equal(t, http.StatusBadRequest, add(1, "/contacts/3", `{`).Code)
+
+	recorder = sendAs(t, 2, http.MethodDelete, "/contacts/:user_id",
+		"/contacts/1", server.removeContact)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Empty(t, list(2))
+	require.False(t, list(1)[0].IsMutual)
+}
@@ -1,14 +1,25 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/events"
 	"github.com/kratos069/message-app/token"
+	"github.com/kratos069/message-app/util"
 )
 
+// the primary inbox by default, message requests are listed separately
+type listConversationsQuery struct {
+	Inbox         string `form:"inbox" binding:"omitempty,oneof=primary requests"`
+	ContactsOnly  bool   `form:"contacts_only"`
+	ContactsFirst bool   `form:"contacts_first"`
+}
+
 // ListConversations returns user's conversations
 func (server *Server) listConversations(ctx *gin.Context) {
 	// Get user info from auth middleware
@@ -18,11 +29,23 @@ func (server *Server) listConversations(ctx *gin.Context) {
 	limit := ctx.DefaultQuery("limit", "20")
 	offset := ctx.DefaultQuery("offset", "0")
 
+	var req listConversationsQuery
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+	if req.Inbox == "" {
+		req.Inbox = util.InboxPrimary
+	}
+
 	conversations, err := server.store.GetUserConversationsWithLastMessage(
 		ctx, db.GetUserConversationsWithLastMessageParams{
-			UserID: parsedUser.UserID,
-			Limit:  parseInt32(limit, 20),
-			Offset: parseInt32(offset, 0),
+			UserID:        parsedUser.UserID,
+			Inbox:         req.Inbox,
+			ContactsOnly:  req.ContactsOnly,
+			ContactsFirst: req.ContactsFirst,
+			Limit:         parseInt32(limit, 20),
+			Offset:        parseInt32(offset, 0),
 		})
 
 	if err != nil {
@@ -30,8 +53,29 @@ func (server *Server) listConversations(ctx *gin.Context) {
 		return
 	}
 
+	otherUserIDs := make([]int64, 0, len(conversations))
+	for _, conversation := range conversations {
+		otherUserIDs = append(otherUserIDs, conversation.OtherUserID)
+	}
+
+	view, err := server.newPrivacyView(ctx, parsedUser.UserID, otherUserIDs)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	for i := range conversations {
+		if !view.showOnline(conversations[i].OtherUserID) {
+			conversations[i].OtherUserOnline = false
+		}
+		if !view.showLastSeen(conversations[i].OtherUserID) {
+			conversations[i].OtherUserLastSeen = pgtype.Timestamptz{}
+		}
+	}
+
 	ctx.JSON(http.StatusOK, gin.H{
 		"conversations": conversations,
+		"inbox":         req.Inbox,
 		"count":         len(conversations),
 		"message":       "Conversations retrieved successfully",
 	})
@@ -71,11 +115,75 @@ func (server *Server) GetOrCreateDirectConversation(ctx *gin.Context) {
 		return
 	}
 
+	// the policy decides who may start a conversation,
+	// an existing one can always be reopened
+	allowed, err := server.canStartDirectMessage(ctx, parsedUser.UserID, otherUser.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	if !allowed {
+		_, err = server.store.FindDirectConversation(ctx, db.FindDirectConversationParams{
+			UserID:   parsedUser.UserID,
+			UserID_2: otherUser.ID,
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				ctx.JSON(http.StatusForbidden, errResponse(errDirectMessagesClosed))
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, errResponse(err))
+			return
+		}
+	}
+
+	// first contact from a non-contact is a message request
+	contacts, err := server.contactsAmong(ctx, otherUser.ID,
+		[]int64{parsedUser.UserID})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
 	// Get or create conversation between current user and other user
 	result, err := server.store.GetOrCreateDirectConversationTx(
 		ctx, db.GetOrCreateDirectConversationTxParams{
 			User1ID: parsedUser.UserID, // Always use current authenticated user
 			User2ID: otherUser.ID,
+			Request: !contacts[parsedUser.UserID],
+		})
+	if err != nil {
+		// a block looks the same as a closed inbox
+		if errors.Is(err, db.ErrUserBlocked) {
+			ctx.JSON(http.StatusForbidden, errResponse(errDirectMessagesClosed))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	if result.IsNew {
+		conversationID := pgtype.Int8{
+			Int64: result.Conversation.ConversationsID,
+			Valid: true,
+		}
+		for _, userID := range []int64{parsedUser.UserID, otherUser.ID} {
+			joined := gin.H{
+				"conversation_id": result.Conversation.ConversationsID,
+				"user_id":         userID,
+			}
+			server.publishEvent(ctx, events.TypeMemberJoined,
+				result.Conversation.ConversationsID, joined)
+			server.publishWebhookEvent(ctx, util.WebhookEventMemberJoined,
+				conversationID, joined)
+		}
+	}
+
+	// a declined request still looks pending to the sender
+	recipient, err := server.store.GetConversationParticipant(ctx,
+		db.GetConversationParticipantParams{
+			ConversationID: result.Conversation.ConversationsID,
+			UserID:         otherUser.ID,
 		})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errResponse(err))
@@ -85,6 +193,7 @@ func (server *Server) GetOrCreateDirectConversation(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{
 		"conversation_id": result.Conversation.ConversationsID,
 		"is_new":          result.IsNew,
+		"is_request":      recipient.Inbox != util.InboxPrimary,
 		"message":         "Conversation ready",
 	})
 }
@@ -130,6 +239,26 @@ func (server *Server) getConversation(ctx *gin.Context) {
 		return
 	}
 
+	participantIDs := make([]int64, 0, len(participants))
+	for _, participant := range participants {
+		participantIDs = append(participantIDs, participant.ParticipantID)
+	}
+
+	view, err := server.newPrivacyView(ctx, payload.UserID, participantIDs)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	for i := range participants {
+		if !view.showOnline(participants[i].ParticipantID) {
+			participants[i].ParticipantOnline = false
+		}
+		if !view.showReadReceipts(participants[i].ParticipantID) {
+			participants[i].LastReadAt = pgtype.Timestamptz{}
+		}
+	}
+
 	ctx.JSON(http.StatusOK, gin.H{
 		"conversation_id": req.ConversationID,
 		"participants":    participants,
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/storage"
+	"github.com/kratos069/message-app/token"
+	"github.com/kratos069/message-app/util"
+	"github.com/kratos069/message-app/worker"
+	"github.com/rs/zerolog/log"
+)
+
+const dataExportPathPrefix = "/exports/"
+
+var (
+	errDataExportPending  = errors.New("a data export is already being prepared")
+	errDataExportNotFound = errors.New("data export not found or expired")
+)
+
+type dataExportResponse struct {
+	ExportID    int64      `json:"export_id"`
+	Status      string     `json:"status"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+func newDataExportResponse(export db.DataExport) dataExportResponse {
+	return dataExportResponse{
+		ExportID:    export.ExportID,
+		Status:      export.Status,
+		ExpiresAt:   timestamptzPtr(export.ExpiresAt),
+		CreatedAt:   export.CreatedAt,
+		CompletedAt: timestamptzPtr(export.CompletedAt),
+	}
+}
+
+// RequestDataExport queues an archive of the user's data,
+// a download link is emailed once it is ready
+func (server *Server) requestDataExport(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	// one pending export per user, see the unique index
+	export, err := server.store.CreateDataExport(ctx, authPayload.UserID)
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			ctx.JSON(http.StatusConflict, errResponse(errDataExportPending))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	opts := []asynq.Option{
+		asynq.MaxRetry(5),
+		asynq.Queue(worker.QueueDefault),
+	}
+
+	err = server.taskDistributor.DistributeTaskExportUserData(ctx,
+		&worker.PayloadExportUserData{ExportID: export.ExportID}, opts...)
+	if err != nil {
+		// otherwise the pending export blocks new requests
+		if failErr := server.store.FailDataExport(ctx, export.ExportID); failErr != nil {
+			log.Error().Err(failErr).Int64("export_id", export.ExportID).
+				Msg("Failed to mark data export failed")
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, newDataExportResponse(export))
+}
+
+// ListDataExports returns the user's data exports, newest first
+func (server *Server) listDataExports(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	exports, err := server.store.ListDataExportsByUser(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	resp := make([]dataExportResponse, 0, len(exports))
+	for _, export := range exports {
+		resp = append(resp, newDataExportResponse(export))
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"exports": resp,
+		"count":   len(resp),
+	})
+}
+
+type dataExportTokenRequest struct {
+	Token string `uri:"token" binding:"required"`
+}
+
+// DownloadDataExport sends the archive, the token
+// from the emailed link is the only credential
+func (server *Server) downloadDataExport(ctx *gin.Context) {
+	var uri dataExportTokenRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	export, ok := server.dataExport(ctx, uri.Token)
+	if !ok {
+		return
+	}
+
+	file, err := server.files.Open(ctx, export.FileKey.String)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			ctx.JSON(http.StatusNotFound, errResponse(errDataExportNotFound))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	defer file.Close()
+
+	// archives can take longer than the server's write timeout
+	err = http.NewResponseController(ctx.Writer).SetWriteDeadline(time.Time{})
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to lift write deadline for data export")
+	}
+
+	ctx.Header("Content-Type", "application/zip")
+	ctx.Header("Content-Disposition", fmt.Sprintf(
+		`attachment; filename="message-app-export-%d.zip"`, export.ExportID))
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Status(http.StatusOK)
+
+	_, err = io.Copy(ctx.Writer, file)
+	if err != nil {
+		log.Error().Err(err).Int64("export_id", export.ExportID).
+			Msg("Failed to send data export")
+	}
+}
+
+// ==========================================================================
+// ================================Helper====================================
+// ==========================================================================
+
+// returns the ready, unexpired export the token belongs to, any problem
+// with the token is answered with the same 404 to not help guessing
+func (server *Server) dataExport(ctx *gin.Context,
+	exportToken string) (db.DataExport, bool) {
+	prefix, ok := util.ParseDataExportToken(exportToken)
+	if !ok {
+		ctx.JSON(http.StatusNotFound, errResponse(errDataExportNotFound))
+		return db.DataExport{}, false
+	}
+
+	export, err := server.store.GetDataExportByPrefix(ctx,
+		pgtype.Text{String: prefix, Valid: true})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errResponse(errDataExportNotFound))
+			return export, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return export, false
+	}
+
+	hash := util.HashSecret(exportToken)
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(export.TokenHash.String)) != 1 ||
+		export.Status != worker.DataExportReady ||
+		!export.ExpiresAt.Valid || time.Now().After(export.ExpiresAt.Time) {
+		ctx.JSON(http.StatusNotFound, errResponse(errDataExportNotFound))
+		return export, false
+	}
+
+	return export, true
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/storage"
+	"github.com/kratos069/message-app/util"
+	"github.com/kratos069/message-app/worker"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDataExportStore keeps data exports in memory
+type fakeDataExportStore struct {
+	db.Store
+	exports map[string]db.DataExport
+}
+
+func (store *fakeDataExportStore) GetDataExportByPrefix(ctx context.Context,
+	prefix pgtype.Text) (db.DataExport, error) {
+	export, ok := store.exports[prefix.String]
+	if !ok {
+		return export, pgx.ErrNoRows
+	}
+	return export, nil
+}
+
+func newTestDataExport(id int64, status string,
+	expiresAt time.Time) (string, db.DataExport) {
+	exportToken, prefix := util.GenerateDataExportToken()
+
+	return exportToken, db.DataExport{
+		ExportID:  id,
+		UserID:    1,
+		Status:    status,
+		FileKey:   pgtype.Text{String: worker.DataExportFileKey(1, id), Valid: true},
+		Prefix:    pgtype.Text{String: prefix, Valid: true},
+		TokenHash: pgtype.Text{String: util.HashSecret(exportToken), Valid: true},
+		ExpiresAt: pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	}
+}
+
+func TestDownloadDataExport(t *testing.T) {
+	readyToken, ready := newTestDataExport(1, worker.DataExportReady,
+		time.Now().Add(time.Hour))
+	expiredToken, expired := newTestDataExport(2, worker.DataExportReady,
+		time.Now().Add(-time.Minute))
+	pendingToken, pending := newTestDataExport(3, worker.DataExportPending,
+		time.Now().Add(time.Hour))
+	missingToken, missing := newTestDataExport(4, worker.DataExportReady,
+		time.Now().Add(time.Hour))
+
+	testCases := []struct {
+		name          string
+		token         string
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			token: readyToken,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "application/zip", recorder.Header().Get("Content-Type"))
+				require.Contains(t, recorder.Header().Get("Content-Disposition"),
+					"message-app-export-1.zip")
+				require.Equal(t, "archive", recorder.Body.String())
+			},
+		},
+		{
+			name:  "Expired",
+			token: expiredToken,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:  "NotReady",
+			token: pendingToken,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:  "WrongSecret",
+			token: readyToken[:len(readyToken)-3] + "abc",
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:  "MalformedToken",
+			token: "nope",
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:  "FileGone",
+			token: missingToken,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			store := &fakeDataExportStore{
+				exports: map[string]db.DataExport{
+					ready.Prefix.String:   ready,
+					expired.Prefix.String: expired,
+					pending.Prefix.String: pending,
+					missing.Prefix.String: missing,
+				},
+			}
+			server := newTestServer(t, store, nil)
+			server.files = storage.NewLocalFileStore(t.TempDir())
+
+			for _, export := range []db.DataExport{ready, expired, pending} {
+				err := server.files.Put(context.Background(),
+					export.FileKey.String, strings.NewReader("archive"))
+				require.NoError(t, err)
+			}
+
+			request, err := http.NewRequest(http.MethodGet,
+				dataExportPathPrefix+tc.token, nil)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -57,6 +58,14 @@ func (server *Server) VerifyEmail(ctx *gin.Context) {
 			return
 		}
 
+		if errors.Is(err, db.ErrVerifyEmailOutdated) {
+			ctx.JSON(http.StatusBadRequest, VerifyEmailResponse{
+				Success: false,
+				Message: "This verification link is for a previous email address.",
+			})
+			return
+		}
+
 		if err.Error() == "verification code already used" {
 			ctx.JSON(http.StatusBadRequest, VerifyEmailResponse{
 				Success: false,
@@ -72,6 +81,9 @@ func (server *Server) VerifyEmail(ctx *gin.Context) {
 		return
 	}
 
+	// lift read-only mode right away
+	server.sessionGuard.forgetUser(result.User.ID)
+
 	// Success response
 	ctx.JSON(http.StatusOK, VerifyEmailResponse{
 		Success:    true,
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+
+	"github.com/kratos069/message-app/events"
+	"github.com/kratos069/message-app/util"
+	"github.com/rs/zerolog/log"
+)
+
+// ==========================================================================
+// ================================Helper====================================
+// ==========================================================================
+
+// sends an event to the conversation's subscribers on every instance.
+// Failures are only logged, the request that caused the event already
+// succeeded.
+func (server *Server) publishEvent(ctx context.Context, eventType string,
+	conversationID int64, data any) {
+	server.publishEventTo(ctx, eventType, conversationID, nil, data)
+}
+
+// like publishEvent, but only recipients may see the event,
+// nobody does when there are none
+func (server *Server) publishEventTo(ctx context.Context, eventType string,
+	conversationID int64, recipients []int64, data any) {
+	if recipients != nil && len(recipients) == 0 {
+		return
+	}
+
+	event, err := events.NewEvent(eventType, conversationID, data)
+	if err == nil {
+		event.Recipients = recipients
+		err = server.events.Publish(ctx, event)
+	}
+	if err != nil {
+		log.Error().Err(err).Str("event", eventType).
+			Int64("conversation_id", conversationID).
+			Msg("Failed to publish event")
+	}
+}
+
+// the people in the conversation who may learn what actorID does there.
+// Nobody does while actorID hasn't accepted the conversation's request,
+// and blocks either way hide it. Read receipts also follow privacyView,
+// so they need both sides to show them and no pending request.
+func (server *Server) eventRecipients(ctx context.Context, conversationID,
+	actorID int64, readReceipts bool) ([]int64, error) {
+	people, err := server.store.ListConversationPeople(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	others := make([]int64, 0, len(people))
+	for _, person := range people {
+		if person.UserID != actorID {
+			others = append(others, person.UserID)
+			continue
+		}
+		if person.Inbox != util.InboxPrimary {
+			return []int64{}, nil
+		}
+	}
+	if len(others) == 0 {
+		return []int64{}, nil
+	}
+
+	view, err := server.newPrivacyView(ctx, actorID, others)
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := make([]int64, 0, len(others))
+	for _, userID := range others {
+		if view.blocked[userID] {
+			continue
+		}
+		if readReceipts && !view.showReadReceipts(userID) {
+			continue
+		}
+		recipients = append(recipients, userID)
+	}
+
+	return recipients, nil
+}
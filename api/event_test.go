@@ -0,0 +1,70 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/events"
+	"github.com/kratos069/message-app/util"
+	"github.com/stretchr/testify/require"
+)
+
+func (store *fakePrivacyStore) MarkMessagesAsReadTx(ctx context.Context,
+	arg db.MarkMessagesAsReadTxParams) error {
+	return nil
+}
+
+func TestMarkMessagesRead(t *testing.T) {
+	store := newFakePrivacyStore()
+	server := newTestServer(t, store, nil)
+	store.conversations[conversationKey(1, 2)] = 7
+
+	subscription, err := server.events.Subscribe(context.Background(), 7)
+	require.NoError(t, err)
+	defer subscription.Close()
+
+	read := func(userID int64) int {
+		return sendAs(t, userID, http.MethodPost,
+			"/messages/:conversation_id/read", "/messages/7/read",
+			server.markMessagesRead).Code
+	}
+
+	require.Equal(t, http.StatusOK, read(1))
+	event := <-subscription.Events()
+	require.Equal(t, events.TypeMessagesRead, event.Type)
+	require.Equal(t, int64(7), event.ConversationID)
+	require.Equal(t, []int64{2}, event.Recipients)
+	var data struct {
+		UserID int64 `json:"user_id"`
+	}
+	require.NoError(t, json.Unmarshal(event.Data, &data))
+	require.Equal(t, int64(1), data.UserID)
+
+	// read receipts go both ways, hiding them hides everyone's
+	settings := defaultPrivacySettings(2)
+	settings.ReadReceipts = false
+	store.settings[2] = settings
+	require.Equal(t, http.StatusOK, read(2))
+	require.Equal(t, http.StatusOK, read(1))
+	require.Empty(t, subscription.Events())
+	delete(store.settings, 2)
+
+	// neither side learns anything through a block
+	store.blocks[[2]int64{2, 1}] = time.Now()
+	require.Equal(t, http.StatusOK, read(1))
+	require.Equal(t, http.StatusOK, read(2))
+	require.Empty(t, subscription.Events())
+	delete(store.blocks, [2]int64{2, 1})
+
+	// nor while 2 hasn't accepted 1's message request
+	store.inboxes[[2]int64{7, 2}] = util.InboxRequests
+	require.Equal(t, http.StatusOK, read(1))
+	require.Equal(t, http.StatusOK, read(2))
+	require.Empty(t, subscription.Events())
+
+	require.Equal(t, http.StatusForbidden, read(3))
+}
@@ -0,0 +1,457 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/events"
+	"github.com/kratos069/message-app/limiter"
+	"github.com/kratos069/message-app/token"
+	"github.com/kratos069/message-app/util"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	defaultIncomingWebhookRateLimit = 30
+	incomingWebhookPathPrefix       = "/hooks/"
+)
+
+var (
+	errIncomingWebhookNotFound = errors.New("incoming webhook not found")
+	errIncomingWebhookRevoked  = errors.New("incoming webhook is already revoked")
+	errIncomingWebhookLimited  = errors.New("too many messages, slow down")
+	errIncomingWebhookDisabled = errors.New("incoming webhook is disabled")
+)
+
+type incomingWebhookResponse struct {
+	IncomingWebhookID int64      `json:"incoming_webhook_id"`
+	ConversationID    int64      `json:"conversation_id"`
+	BotUserID         int64      `json:"bot_user_id"`
+	Name              string     `json:"name"`
+	Prefix            string     `json:"prefix"`
+	LastUsedAt        *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt         *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+func newIncomingWebhookResponse(hook db.IncomingWebhook) incomingWebhookResponse {
+	return incomingWebhookResponse{
+		IncomingWebhookID: hook.IncomingWebhookID,
+		ConversationID:    hook.ConversationID,
+		BotUserID:         hook.BotUserID,
+		Name:              hook.Name,
+		Prefix:            hook.Prefix,
+		LastUsedAt:        timestamptzPtr(hook.LastUsedAt),
+		RevokedAt:         timestamptzPtr(hook.RevokedAt),
+		CreatedAt:         hook.CreatedAt,
+	}
+}
+
+// messages per minute a single incoming webhook may post
+func incomingWebhookRate(config util.Config) limiter.Rate {
+	limit := config.IncomingHookLimit
+	if limit == 0 {
+		limit = defaultIncomingWebhookRateLimit
+	}
+
+	return limiter.Rate{Limit: limit, Window: time.Minute}
+}
+
+type createIncomingWebhookRequest struct {
+	Name string `json:"name" binding:"required,min=1,max=100"`
+}
+
+// CreateIncomingWebhook creates a URL integrations can post messages to,
+// the messages come from a bot of its own which joins the conversation
+func (server *Server) createIncomingWebhook(ctx *gin.Context) {
+	var uri conversationIDStruct
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	var req createIncomingWebhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	if !server.canManageConversation(ctx, uri.ConversationID) ||
+		!server.isDirectConversationOpen(ctx, uri.ConversationID) {
+		return
+	}
+
+	// the bot can't log in, nobody knows this password
+	hashedPassword, err := bcrypt.GenerateFromPassword(
+		[]byte(util.RandomString(32)), bcrypt.DefaultCost)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	hookToken, prefix := util.GenerateIncomingWebhookToken()
+	username := "webhook" + prefix
+
+	result, err := server.store.CreateIncomingWebhookTx(ctx, db.CreateIncomingWebhookTxParams{
+		Bot: db.CreateBotParams{
+			Username:     username,
+			Email:        username + "@" + botEmailDomain,
+			PasswordHash: string(hashedPassword),
+			Role:         util.CustomerRole,
+			BotOwnerID:   pgtype.Int8{Int64: authPayload.UserID, Valid: true},
+		},
+		ConversationID: uri.ConversationID,
+		Name:           req.Name,
+		Prefix:         prefix,
+		TokenHash:      util.HashSecret(hookToken),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"incoming_webhook": newIncomingWebhookResponse(result.IncomingWebhook),
+		// shown only once, anyone with the URL can post
+		"url": incomingWebhookPathPrefix + hookToken,
+	})
+}
+
+// ListIncomingWebhooks returns the incoming webhooks of a conversation
+func (server *Server) listIncomingWebhooks(ctx *gin.Context) {
+	var uri conversationIDStruct
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	if !server.canManageConversation(ctx, uri.ConversationID) {
+		return
+	}
+
+	hooks, err := server.store.ListIncomingWebhooks(ctx, uri.ConversationID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	resp := make([]incomingWebhookResponse, 0, len(hooks))
+	for _, hook := range hooks {
+		resp = append(resp, newIncomingWebhookResponse(hook))
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"incoming_webhooks": resp,
+		"count":             len(resp),
+	})
+}
+
+type incomingWebhookIDRequest struct {
+	ConversationID    int64 `uri:"conversation_id" binding:"required,min=1"`
+	IncomingWebhookID int64 `uri:"hook_id" binding:"required,min=1"`
+}
+
+// RevokeIncomingWebhook makes the URL stop working right away,
+// messages already posted stay
+func (server *Server) revokeIncomingWebhook(ctx *gin.Context) {
+	var uri incomingWebhookIDRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	if !server.canManageConversation(ctx, uri.ConversationID) {
+		return
+	}
+
+	hook, err := server.store.GetIncomingWebhook(ctx, uri.IncomingWebhookID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errResponse(errIncomingWebhookNotFound))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	if hook.ConversationID != uri.ConversationID {
+		ctx.JSON(http.StatusNotFound, errResponse(errIncomingWebhookNotFound))
+		return
+	}
+
+	hook, err = server.store.RevokeIncomingWebhook(ctx, hook.IncomingWebhookID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.JSON(http.StatusConflict, errResponse(errIncomingWebhookRevoked))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newIncomingWebhookResponse(hook))
+}
+
+type incomingWebhookTokenRequest struct {
+	Token string `uri:"token" binding:"required"`
+}
+
+type postIncomingWebhookRequest struct {
+	// not end-to-end encrypted, the integration only has the URL
+	Text string `json:"text" binding:"required,max=4000"`
+}
+
+// PostIncomingWebhook creates a message from the webhook's bot,
+// the token in the URL is the only credential
+func (server *Server) postIncomingWebhook(ctx *gin.Context) {
+	var uri incomingWebhookTokenRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	hook, ok := server.incomingWebhook(ctx, uri.Token)
+	if !ok {
+		return
+	}
+
+	wait, err := server.incomingWebhookLimiter.Allow(ctx,
+		"incoming_webhook:"+strconv.FormatInt(hook.IncomingWebhookID, 10))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	if wait > 0 {
+		tooManyRequests(ctx, errIncomingWebhookLimited, wait)
+		return
+	}
+
+	var req postIncomingWebhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	if !server.canIncomingWebhookPost(ctx, hook) {
+		return
+	}
+
+	// e.g. an admin banned the bot
+	bot, err := server.sessionGuard.user(ctx, hook.BotUserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	if err := server.accountPolicy.checkSend(bot); err != nil {
+		abortWithPolicyError(ctx, bot, err)
+		return
+	}
+
+	clientMessageID := uuid.NewString()
+	result, err := server.store.SendMessageTx(ctx, db.SendMessageTxParams{
+		ConversationID:   hook.ConversationID,
+		SenderID:         bot.ID,
+		EncryptedContent: req.Text,
+		ClientMessageID:  &clientMessageID,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrUserBlocked) {
+			ctx.JSON(http.StatusForbidden, errResponse(errDirectMessagesClosed))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to send message"})
+		return
+	}
+
+	// at most once a minute, see the query
+	err = server.store.TouchIncomingWebhook(ctx, hook.IncomingWebhookID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to update incoming webhook usage")
+	}
+
+	sent := gin.H{
+		"message_id":        result.Message.MessagesID,
+		"conversation_id":   result.Message.ConversationID,
+		"sender_id":         result.Message.SenderID,
+		"encrypted_content": result.Message.EncryptedContent,
+		"sent_at":           result.Message.SentAt,
+	}
+	server.publishEvent(ctx, events.TypeMessageSent,
+		result.Message.ConversationID, sent)
+	server.publishWebhookEvent(ctx, util.WebhookEventMessageSent,
+		pgtype.Int8{Int64: result.Message.ConversationID, Valid: true}, sent)
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"message_id": result.Message.MessagesID,
+		"sent_at":    result.Message.SentAt,
+		"status":     "sent",
+	})
+}
+
+// ==========================================================================
+// ================================Helper====================================
+// ==========================================================================
+
+// returns the live webhook the token belongs to, any problem with
+// the token is answered with the same 404 to not help guessing
+func (server *Server) incomingWebhook(ctx *gin.Context,
+	hookToken string) (db.IncomingWebhook, bool) {
+	prefix, ok := util.ParseIncomingWebhookToken(hookToken)
+	if !ok {
+		ctx.JSON(http.StatusNotFound, errResponse(errIncomingWebhookNotFound))
+		return db.IncomingWebhook{}, false
+	}
+
+	hook, err := server.store.GetIncomingWebhookByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errResponse(errIncomingWebhookNotFound))
+			return hook, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return hook, false
+	}
+
+	hash := util.HashSecret(hookToken)
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(hook.TokenHash)) != 1 ||
+		hook.RevokedAt.Valid {
+		ctx.JSON(http.StatusNotFound, errResponse(errIncomingWebhookNotFound))
+		return hook, false
+	}
+
+	return hook, true
+}
+
+// a webhook speaks for its creator, so it only works while the creator
+// could post themselves. Hooks of creators who left or were purged are
+// revoked, a banned creator's hook waits for the ban to be lifted.
+// Writes the response and returns false otherwise.
+func (server *Server) canIncomingWebhookPost(ctx *gin.Context,
+	hook db.IncomingWebhook) bool {
+	if !hook.CreatedBy.Valid {
+		server.revokeOrphanedIncomingWebhook(ctx, hook)
+		return false
+	}
+
+	creator, err := server.sessionGuard.user(ctx, hook.CreatedBy.Int64)
+	if err != nil {
+		if errors.Is(err, token.ErrInvalidToken) {
+			server.revokeOrphanedIncomingWebhook(ctx, hook)
+			return false
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return false
+	}
+	// the creator's ban reason is none of the integration's business
+	if err := server.accountPolicy.checkSend(creator); err != nil {
+		ctx.JSON(http.StatusForbidden, errResponse(errIncomingWebhookDisabled))
+		return false
+	}
+
+	if creator.Role == util.AdminRole {
+		return true
+	}
+
+	isParticipant, err := server.store.IsUserInConversation(ctx,
+		db.IsUserInConversationParams{
+			ConversationID: hook.ConversationID,
+			UserID:         creator.ID,
+		})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return false
+	}
+	if !isParticipant {
+		server.revokeOrphanedIncomingWebhook(ctx, hook)
+		return false
+	}
+
+	return true
+}
+
+// revokes a webhook whose creator is gone and answers
+// like for any other dead token
+func (server *Server) revokeOrphanedIncomingWebhook(ctx *gin.Context,
+	hook db.IncomingWebhook) {
+	_, err := server.store.RevokeIncomingWebhook(ctx, hook.IncomingWebhookID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		log.Error().Err(err).Int64("incoming_webhook_id", hook.IncomingWebhookID).
+			Msg("Failed to revoke orphaned incoming webhook")
+	}
+
+	ctx.JSON(http.StatusNotFound, errResponse(errIncomingWebhookNotFound))
+}
+
+// a webhook in a direct conversation must not become a way around
+// a block or an unanswered message request.
+// Writes the response and returns false otherwise.
+func (server *Server) isDirectConversationOpen(ctx *gin.Context,
+	conversationID int64) bool {
+	people, err := server.store.ListConversationPeople(ctx, conversationID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return false
+	}
+	if len(people) != 2 {
+		return true
+	}
+
+	for _, person := range people {
+		if person.Inbox != util.InboxPrimary {
+			ctx.JSON(http.StatusForbidden, errResponse(errDirectMessagesClosed))
+			return false
+		}
+	}
+
+	blocked, err := server.store.IsBlockedEitherWay(ctx, db.IsBlockedEitherWayParams{
+		UserID:      people[0].UserID,
+		OtherUserID: people[1].UserID,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return false
+	}
+	if blocked {
+		ctx.JSON(http.StatusForbidden, errResponse(errDirectMessagesClosed))
+		return false
+	}
+
+	return true
+}
+
+// conversations have no admins of their own, so every participant
+// manages a conversation's integrations, site admins manage all of them.
+// Writes the response and returns false otherwise.
+func (server *Server) canManageConversation(ctx *gin.Context,
+	conversationID int64) bool {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if authPayload.Role == util.AdminRole {
+		return true
+	}
+
+	isParticipant, err := server.store.IsUserInConversation(ctx,
+		db.IsUserInConversationParams{
+			ConversationID: conversationID,
+			UserID:         authPayload.UserID,
+		})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return false
+	}
+	if !isParticipant {
+		ctx.JSON(http.StatusForbidden,
+			gin.H{"error": "you are not a participant in this conversation"})
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,385 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/limiter"
+	"github.com/kratos069/message-app/util"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeIncomingWebhookStore keeps incoming webhooks and the messages
+// posted through them in memory
+type fakeIncomingWebhookStore struct {
+	db.Store
+	hooks        map[string]db.IncomingWebhook
+	users        map[int64]db.User
+	participants map[int64]bool
+	people       []db.ConversationParticipant
+	blocked      bool
+	revoked      []int64
+	created      []db.CreateIncomingWebhookTxParams
+	messages     []db.SendMessageTxParams
+}
+
+func (store *fakeIncomingWebhookStore) GetIncomingWebhookByPrefix(
+	ctx context.Context, prefix string) (db.IncomingWebhook, error) {
+	hook, ok := store.hooks[prefix]
+	if !ok {
+		return hook, pgx.ErrNoRows
+	}
+	return hook, nil
+}
+
+func (store *fakeIncomingWebhookStore) GetUserByID(ctx context.Context,
+	id int64) (db.User, error) {
+	user, ok := store.users[id]
+	if !ok {
+		return user, pgx.ErrNoRows
+	}
+	return user, nil
+}
+
+func (store *fakeIncomingWebhookStore) IsUserInConversation(ctx context.Context,
+	arg db.IsUserInConversationParams) (bool, error) {
+	return store.participants[arg.UserID], nil
+}
+
+func (store *fakeIncomingWebhookStore) ListConversationPeople(ctx context.Context,
+	conversationID int64) ([]db.ConversationParticipant, error) {
+	return store.people, nil
+}
+
+func (store *fakeIncomingWebhookStore) IsBlockedEitherWay(ctx context.Context,
+	arg db.IsBlockedEitherWayParams) (bool, error) {
+	return store.blocked, nil
+}
+
+func (store *fakeIncomingWebhookStore) CreateIncomingWebhookTx(ctx context.Context,
+	arg db.CreateIncomingWebhookTxParams) (db.CreateIncomingWebhookTxResults, error) {
+	store.created = append(store.created, arg)
+	return db.CreateIncomingWebhookTxResults{
+		IncomingWebhook: db.IncomingWebhook{
+			IncomingWebhookID: int64(len(store.created)),
+			ConversationID:    arg.ConversationID,
+			Name:              arg.Name,
+			Prefix:            arg.Prefix,
+		},
+	}, nil
+}
+
+func (store *fakeIncomingWebhookStore) RevokeIncomingWebhook(ctx context.Context,
+	incomingWebhookID int64) (db.IncomingWebhook, error) {
+	store.revoked = append(store.revoked, incomingWebhookID)
+	return db.IncomingWebhook{IncomingWebhookID: incomingWebhookID}, nil
+}
+
+func (store *fakeIncomingWebhookStore) SendMessageTx(ctx context.Context,
+	arg db.SendMessageTxParams) (db.SendMessageTxResult, error) {
+	store.messages = append(store.messages, arg)
+
+	return db.SendMessageTxResult{
+		Message: db.Message{
+			MessagesID:       int64(len(store.messages)),
+			ConversationID:   arg.ConversationID,
+			SenderID:         arg.SenderID,
+			EncryptedContent: arg.EncryptedContent,
+			SentAt:           time.Now(),
+		},
+	}, nil
+}
+
+func (store *fakeIncomingWebhookStore) TouchIncomingWebhook(ctx context.Context,
+	incomingWebhookID int64) error {
+	return nil
+}
+
+func (store *fakeIncomingWebhookStore) ListWebhooksForEvent(ctx context.Context,
+	arg db.ListWebhooksForEventParams) ([]db.Webhook, error) {
+	return nil, nil
+}
+
+func newTestIncomingWebhook(id, botID int64) (string, db.IncomingWebhook) {
+	hookToken, prefix := util.GenerateIncomingWebhookToken()
+
+	return hookToken, db.IncomingWebhook{
+		IncomingWebhookID: id,
+		ConversationID:    7,
+		BotUserID:         botID,
+		Prefix:            prefix,
+		TokenHash:         util.HashSecret(hookToken),
+		CreatedBy:         pgtype.Int8{Int64: 500, Valid: true},
+	}
+}
+
+func TestCreateIncomingWebhookDirectConversation(t *testing.T) {
+	direct := func(senderInbox, recipientInbox string) []db.ConversationParticipant {
+		return []db.ConversationParticipant{
+			{ConversationID: 7, UserID: 500, Inbox: senderInbox},
+			{ConversationID: 7, UserID: 600, Inbox: recipientInbox},
+		}
+	}
+
+	testCases := []struct {
+		name         string
+		people       []db.ConversationParticipant
+		blocked      bool
+		expectedCode int
+	}{
+		{
+			name:         "OK",
+			people:       direct(util.InboxPrimary, util.InboxPrimary),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "PendingRequest",
+			people:       direct(util.InboxPrimary, util.InboxRequests),
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "Blocked",
+			people:       direct(util.InboxPrimary, util.InboxPrimary),
+			blocked:      true,
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			// blocks only close direct conversations
+			name: "Group",
+			people: append(direct(util.InboxPrimary, util.InboxPrimary),
+				db.ConversationParticipant{ConversationID: 7, UserID: 700,
+					Inbox: util.InboxPrimary}),
+			blocked:      true,
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			store := &fakeIncomingWebhookStore{
+				participants: map[int64]bool{500: true},
+				people:       tc.people,
+				blocked:      tc.blocked,
+			}
+			server := newTestServer(t, store, nil)
+
+			router := newProfileTestRouter(newPrivacyTestPayload(500),
+				http.MethodPost, "/incoming_webhooks/:conversation_id",
+				server.createIncomingWebhook)
+			request, err := http.NewRequest(http.MethodPost, "/incoming_webhooks/7",
+				bytes.NewReader([]byte(`{"name":"ci"}`)))
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			require.Equal(t, tc.expectedCode, recorder.Code)
+			if tc.expectedCode == http.StatusOK {
+				require.Len(t, store.created, 1)
+			} else {
+				require.Empty(t, store.created)
+			}
+		})
+	}
+}
+
+func TestPostIncomingWebhook(t *testing.T) {
+	hookToken, hook := newTestIncomingWebhook(1, 300)
+	revokedToken, revokedHook := newTestIncomingWebhook(2, 300)
+	revokedHook.RevokedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	bannedToken, bannedHook := newTestIncomingWebhook(3, 400)
+	leftToken, leftHook := newTestIncomingWebhook(4, 300)
+	leftHook.CreatedBy = pgtype.Int8{Int64: 600, Valid: true}
+	purgedToken, purgedHook := newTestIncomingWebhook(5, 300)
+	purgedHook.CreatedBy = pgtype.Int8{}
+	bannedCreatorToken, bannedCreatorHook := newTestIncomingWebhook(6, 300)
+	bannedCreatorHook.CreatedBy = pgtype.Int8{Int64: 700, Valid: true}
+
+	testCases := []struct {
+		name          string
+		token         string
+		body          any
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder,
+			store *fakeIncomingWebhookStore)
+	}{
+		{
+			name:  "OK",
+			token: hookToken,
+			body:  gin.H{"text": "disk usage above 90%"},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder,
+				store *fakeIncomingWebhookStore) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+				require.Len(t, store.messages, 1)
+				require.Equal(t, int64(7), store.messages[0].ConversationID)
+				require.Equal(t, int64(300), store.messages[0].SenderID)
+				require.Equal(t, "disk usage above 90%", store.messages[0].EncryptedContent)
+			},
+		},
+		{
+			name:  "MissingText",
+			token: hookToken,
+			body:  gin.H{},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder,
+				store *fakeIncomingWebhookStore) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Empty(t, store.messages)
+			},
+		},
+		{
+			name:  "RevokedToken",
+			token: revokedToken,
+			body:  gin.H{"text": "hello"},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder,
+				store *fakeIncomingWebhookStore) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				require.Empty(t, store.messages)
+			},
+		},
+		{
+			name:  "WrongSecret",
+			token: hookToken[:len(hookToken)-3] + "abc",
+			body:  gin.H{"text": "hello"},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder,
+				store *fakeIncomingWebhookStore) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				require.Empty(t, store.messages)
+			},
+		},
+		{
+			name:  "MalformedToken",
+			token: "nope",
+			body:  gin.H{"text": "hello"},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder,
+				store *fakeIncomingWebhookStore) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:  "BannedBot",
+			token: bannedToken,
+			body:  gin.H{"text": "hello"},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder,
+				store *fakeIncomingWebhookStore) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Empty(t, store.messages)
+			},
+		},
+		{
+			name:  "CreatorLeft",
+			token: leftToken,
+			body:  gin.H{"text": "hello"},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder,
+				store *fakeIncomingWebhookStore) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				require.Empty(t, store.messages)
+				require.Equal(t, []int64{4}, store.revoked)
+			},
+		},
+		{
+			name:  "CreatorPurged",
+			token: purgedToken,
+			body:  gin.H{"text": "hello"},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder,
+				store *fakeIncomingWebhookStore) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				require.Empty(t, store.messages)
+				require.Equal(t, []int64{5}, store.revoked)
+			},
+		},
+		{
+			name:  "CreatorBanned",
+			token: bannedCreatorToken,
+			body:  gin.H{"text": "hello"},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder,
+				store *fakeIncomingWebhookStore) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.NotContains(t, recorder.Body.String(), "spam")
+				require.Empty(t, store.messages)
+				// lifting the ban brings the webhook back
+				require.Empty(t, store.revoked)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			store := &fakeIncomingWebhookStore{
+				hooks: map[string]db.IncomingWebhook{
+					hook.Prefix:              hook,
+					revokedHook.Prefix:       revokedHook,
+					bannedHook.Prefix:        bannedHook,
+					leftHook.Prefix:          leftHook,
+					purgedHook.Prefix:        purgedHook,
+					bannedCreatorHook.Prefix: bannedCreatorHook,
+				},
+				users: map[int64]db.User{
+					300: {ID: 300, IsBot: true, IsEmailVerified: true, Role: "customer"},
+					400: {ID: 400, IsBot: true, IsEmailVerified: true, Role: "customer",
+						IsBanned: true},
+					500: {ID: 500, IsEmailVerified: true, Role: "customer"},
+					600: {ID: 600, IsEmailVerified: true, Role: "customer"},
+					700: {ID: 700, IsEmailVerified: true, Role: "customer",
+						IsBanned: true, BannedReason: pgtype.Text{String: "spam", Valid: true}},
+				},
+				participants: map[int64]bool{500: true, 700: true},
+			}
+			server := newTestServer(t, store, nil)
+
+			recorder := postToIncomingWebhook(t, server, tc.token, tc.body)
+			tc.checkResponse(t, recorder, store)
+		})
+	}
+}
+
+func TestPostIncomingWebhookRateLimit(t *testing.T) {
+	hookToken, hook := newTestIncomingWebhook(1, 300)
+
+	store := &fakeIncomingWebhookStore{
+		hooks: map[string]db.IncomingWebhook{hook.Prefix: hook},
+		users: map[int64]db.User{
+			300: {ID: 300, IsBot: true, IsEmailVerified: true, Role: "customer"},
+			500: {ID: 500, IsEmailVerified: true, Role: "customer"},
+		},
+		participants: map[int64]bool{500: true},
+	}
+	server := newTestServer(t, store, nil)
+	server.incomingWebhookLimiter = limiter.NewMemoryRateLimiter(
+		limiter.Rate{Limit: 2, Window: time.Minute})
+
+	for range 2 {
+		recorder := postToIncomingWebhook(t, server, hookToken, gin.H{"text": "hi"})
+		require.Equal(t, http.StatusCreated, recorder.Code)
+	}
+
+	recorder := postToIncomingWebhook(t, server, hookToken, gin.H{"text": "hi"})
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.NotEmpty(t, recorder.Header().Get("Retry-After"))
+	require.Len(t, store.messages, 2)
+}
+
+func postToIncomingWebhook(t *testing.T, server *Server, hookToken string,
+	body any) *httptest.ResponseRecorder {
+	data, err := json.Marshal(body)
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodPost,
+		incomingWebhookPathPrefix+hookToken, bytes.NewReader(data))
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+
+	return recorder
+}
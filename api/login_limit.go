@@ -0,0 +1,129 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hibiken/asynq"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/limiter"
+	"github.com/kratos069/message-app/util"
+	"github.com/kratos069/message-app/worker"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	errInvalidCredentials = errors.New("invalid email or password")
+	errTooManyAttempts    = errors.New("too many failed login attempts, try again later")
+)
+
+// bcrypt hash of a random password, compared against when the email
+// is unknown so both cases take about as long
+const dummyPasswordHash = "$2a$10$VDjGHc0RvTlhaHZ6q1q7B.T4ZUq/6mDFZgjNt.sLGFXmeSR2QMaHO"
+
+// attempts are counted per email whether or not an account exists,
+// so lockouts don't reveal which emails are registered
+func loginAccountKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// per-account limits, lockout settings come from the config
+func limiterAccountPolicy(config util.Config) limiter.Policy {
+	return limiter.DefaultAccountPolicy(config.LoginLockoutAfter,
+		config.LoginLockoutDuration)
+}
+
+// refuses the attempt while the account or IP is backing off or locked,
+// returns false if a response was written
+func (server *Server) allowLoginAttempt(ctx *gin.Context, account string) bool {
+	wait, err := server.loginLimiter.Check(ctx, account, ctx.ClientIP())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return false
+	}
+
+	if wait > 0 {
+		tooManyRequests(ctx, errTooManyAttempts, wait)
+		return false
+	}
+
+	return true
+}
+
+// writes a 429 telling the client how long to wait
+func tooManyRequests(ctx *gin.Context, err error, wait time.Duration) {
+	seconds := int64(math.Ceil(wait.Seconds()))
+	ctx.Header("Retry-After", strconv.FormatInt(seconds, 10))
+	ctx.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       err.Error(),
+		"retry_after": seconds,
+	})
+}
+
+// counts a failed attempt and emails the user when it locks their account,
+// user is nil when no account exists for the email
+func (server *Server) recordLoginFailure(ctx *gin.Context,
+	account string, user *db.User) {
+	locked, err := server.loginLimiter.RecordFailure(ctx, account, ctx.ClientIP())
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to record login failure")
+		return
+	}
+
+	if !locked || user == nil {
+		return
+	}
+
+	log.Warn().Str("username", user.Username).Str("ip", ctx.ClientIP()).
+		Msg("Account locked after failed logins")
+
+	lockedUntil := time.Now().Add(
+		limiterAccountPolicy(server.config).LockoutDuration)
+	err = server.taskDistributor.DistributeTaskSendAccountLocked(ctx,
+		&worker.PayloadSendAccountLocked{
+			Username:    user.Username,
+			LockedUntil: lockedUntil.Unix(),
+		}, asynq.MaxRetry(10), asynq.Queue(worker.QueueCritical))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to distribute account locked task")
+	}
+}
+
+// clears the failures after a complete login
+func (server *Server) resetLoginFailures(ctx *gin.Context, account string) {
+	err := server.loginLimiter.Reset(ctx, account)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to reset login failures")
+	}
+}
+
+// UnlockUser lifts a login lockout (admin only)
+func (server *Server) unlockUser(ctx *gin.Context) {
+	var reqUser userIDStruct
+	if err := ctx.ShouldBindUri(&reqUser); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	user, err := server.store.GetUserByID(ctx, reqUser.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, "User not found")
+		return
+	}
+
+	err = server.loginLimiter.Unlock(ctx, loginAccountKey(user.Email))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError,
+			"Failed to unlock user")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "User unlocked successfully",
+	})
+}
@@ -1,12 +1,17 @@
 package api
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/events"
 	"github.com/kratos069/message-app/token"
+	"github.com/kratos069/message-app/util"
 )
 
 type conversationIDStruct struct {
@@ -92,6 +97,17 @@ func (server *Server) sendMessage(ctx *gin.Context) {
 		return
 	}
 
+	// Banned and unverified users can't send messages
+	sender, err := server.sessionGuard.user(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get sender"})
+		return
+	}
+	if err := server.accountPolicy.checkSend(sender); err != nil {
+		abortWithPolicyError(ctx, sender, err)
+		return
+	}
+
 	// Verify that the user is a participant in this conversation
 	isParticipant, err := server.store.IsUserInConversation(ctx, db.IsUserInConversationParams{
 		ConversationID: uri.ConversationID,
@@ -117,10 +133,31 @@ func (server *Server) sendMessage(ctx *gin.Context) {
 		ClientMessageID:  &clientMessageID,
 	})
 	if err != nil {
+		// a block looks the same as a closed inbox
+		if errors.Is(err, db.ErrUserBlocked) {
+			ctx.JSON(http.StatusForbidden, errResponse(errDirectMessagesClosed))
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to send message"})
 		return
 	}
 
+	// the message is what they were typing
+	_ = server.typing.Stop(ctx, result.Message.ConversationID,
+		authPayload.UserID)
+
+	sent := gin.H{
+		"message_id":        result.Message.MessagesID,
+		"conversation_id":   result.Message.ConversationID,
+		"sender_id":         result.Message.SenderID,
+		"encrypted_content": result.Message.EncryptedContent,
+		"sent_at":           result.Message.SentAt,
+	}
+	server.publishEvent(ctx, events.TypeMessageSent,
+		result.Message.ConversationID, sent)
+	server.publishWebhookEvent(ctx, util.WebhookEventMessageSent,
+		pgtype.Int8{Int64: result.Message.ConversationID, Valid: true}, sent)
+
 	// Respond with message metadata
 	ctx.JSON(http.StatusCreated, gin.H{
 		"message_id": result.Message.MessagesID,
@@ -135,3 +172,56 @@ func (server *Server) sendMessage(ctx *gin.Context) {
 		"message": "Message sent successfully",
 	})
 }
+
+// MarkMessagesRead marks every message in the conversation as read by
+// the caller and lets the other participants know, unless the caller
+// turned read receipts off
+func (server *Server) markMessagesRead(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	var req conversationIDStruct
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	isParticipant, err := server.store.IsUserInConversation(
+		ctx, db.IsUserInConversationParams{
+			ConversationID: req.ConversationID,
+			UserID:         authPayload.UserID,
+		})
+	if err != nil || !isParticipant {
+		ctx.JSON(http.StatusForbidden,
+			gin.H{"error": "access denied"})
+		return
+	}
+
+	err = server.store.MarkMessagesAsReadTx(ctx, db.MarkMessagesAsReadTxParams{
+		ConversationID: req.ConversationID,
+		UserID:         authPayload.UserID,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	readAt := time.Now().UTC()
+
+	recipients, err := server.eventRecipients(ctx, req.ConversationID,
+		authPayload.UserID, true)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	server.publishEventTo(ctx, events.TypeMessagesRead, req.ConversationID,
+		recipients, gin.H{
+			"conversation_id": req.ConversationID,
+			"user_id":         authPayload.UserID,
+			"read_at":         readAt,
+		})
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"conversation_id": req.ConversationID,
+		"read_at":         readAt,
+		"message":         "Messages marked as read",
+	})
+}
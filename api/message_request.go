@@ -0,0 +1,176 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/token"
+	"github.com/kratos069/message-app/util"
+)
+
+var errMessageRequestNotFound = errors.New("message request not found")
+
+// AcceptMessageRequest moves a conversation from the requests inbox to the
+// primary one, from then on the sender sees presence and read receipts
+func (server *Server) acceptMessageRequest(ctx *gin.Context) {
+	server.answerMessageRequest(ctx, util.InboxPrimary, "Message request accepted")
+}
+
+// DeclineMessageRequest hides the conversation, the sender isn't told
+func (server *Server) declineMessageRequest(ctx *gin.Context) {
+	server.answerMessageRequest(ctx, util.InboxDeclined, "Message request declined")
+}
+
+// BlockMessageRequest declines the request and blocks the sender
+func (server *Server) blockMessageRequest(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	var req conversationIDStruct
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	request, ok := server.messageRequest(ctx, req.ConversationID,
+		authPayload.UserID)
+	if !ok {
+		return
+	}
+
+	senderID, err := server.messageRequestSender(ctx, request)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	// both steps are idempotent, a failed block can just be retried
+	err = server.store.BlockUser(ctx, db.BlockUserParams{
+		BlockerID: authPayload.UserID,
+		BlockedID: senderID,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	request, err = server.store.UpdateParticipantInbox(ctx,
+		db.UpdateParticipantInboxParams{
+			ConversationID: request.ConversationID,
+			UserID:         authPayload.UserID,
+			Inbox:          util.InboxDeclined,
+		})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"conversation_id": request.ConversationID,
+		"inbox":           request.Inbox,
+		"blocked_user_id": senderID,
+		"message":         "Message request declined and sender blocked",
+	})
+}
+
+// ==========================================================================
+// ================================Helper====================================
+// ==========================================================================
+
+// moves the caller's pending request to inbox
+func (server *Server) answerMessageRequest(ctx *gin.Context, inbox,
+	message string) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	var req conversationIDStruct
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	request, ok := server.messageRequest(ctx, req.ConversationID,
+		authPayload.UserID)
+	if !ok {
+		return
+	}
+
+	request, err := server.store.UpdateParticipantInbox(ctx,
+		db.UpdateParticipantInboxParams{
+			ConversationID: request.ConversationID,
+			UserID:         authPayload.UserID,
+			Inbox:          inbox,
+		})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"conversation_id": request.ConversationID,
+		"inbox":           request.Inbox,
+		"message":         message,
+	})
+}
+
+// loads the caller's side of a message request, a declined one can still
+// be accepted. Writes the response and returns false if there is none.
+func (server *Server) messageRequest(ctx *gin.Context, conversationID,
+	userID int64) (db.ConversationParticipant, bool) {
+	request, err := server.store.GetConversationParticipant(ctx,
+		db.GetConversationParticipantParams{
+			ConversationID: conversationID,
+			UserID:         userID,
+		})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errResponse(errMessageRequestNotFound))
+			return request, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return request, false
+	}
+
+	if request.Inbox == util.InboxPrimary {
+		ctx.JSON(http.StatusNotFound, errResponse(errMessageRequestNotFound))
+		return request, false
+	}
+
+	return request, true
+}
+
+// the other person in the request's conversation,
+// a webhook's bot may have joined since but never sent the request
+func (server *Server) messageRequestSender(ctx context.Context,
+	request db.ConversationParticipant) (int64, error) {
+	people, err := server.store.ListConversationPeople(ctx,
+		request.ConversationID)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, person := range people {
+		if person.UserID != request.UserID {
+			return person.UserID, nil
+		}
+	}
+
+	return 0, pgx.ErrNoRows
+}
+
+// returns which of userIDs haven't accepted a message request from userID
+func (server *Server) pendingRequestsAmong(ctx context.Context, userID int64,
+	userIDs []int64) (map[int64]bool, error) {
+	pendingIDs, err := server.store.ListPendingRequestUserIDs(ctx,
+		db.ListPendingRequestUserIDsParams{
+			UserID:  userID,
+			UserIds: userIDs,
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return idSet(pendingIDs), nil
+}
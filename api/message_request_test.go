@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/util"
+	"github.com/stretchr/testify/require"
+)
+
+func (store *fakePrivacyStore) GetUserConversationsWithLastMessage(
+	ctx context.Context, arg db.GetUserConversationsWithLastMessageParams) (
+	[]db.GetUserConversationsWithLastMessageRow, error) {
+	rows := []db.GetUserConversationsWithLastMessageRow{}
+	for key, conversationID := range store.conversations {
+		if key[0] != arg.UserID && key[1] != arg.UserID {
+			continue
+		}
+		if store.inbox(conversationID, arg.UserID) != arg.Inbox {
+			continue
+		}
+		otherUserID := key[0]
+		if otherUserID == arg.UserID {
+			otherUserID = key[1]
+		}
+		rows = append(rows, db.GetUserConversationsWithLastMessageRow{
+			ConversationsID: conversationID,
+			OtherUserID:     otherUserID,
+		})
+	}
+	return rows, nil
+}
+
+// sends a request as userID to the handler routed at route
+func sendAs(t *testing.T, userID int64, method, route, path string,
+	handler gin.HandlerFunc) *httptest.ResponseRecorder {
+	router := newProfileTestRouter(newPrivacyTestPayload(userID), method,
+		route, handler)
+	request, err := http.NewRequest(method, path, nil)
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestMessageRequests(t *testing.T) {
+	store := newFakePrivacyStore()
+	server := newTestServer(t, store, nil)
+
+	start := func(senderID, recipientID int64) map[string]any {
+		recorder := sendAs(t, senderID, http.MethodPost,
+			"/conversations/:other_user_id",
+			fmt.Sprintf("/conversations/%d", recipientID),
+			server.GetOrCreateDirectConversation)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		var resp map[string]any
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+		return resp
+	}
+	answer := func(userID int64, action string,
+		conversationID int64) *httptest.ResponseRecorder {
+		handlers := map[string]gin.HandlerFunc{
+			"accept":  server.acceptMessageRequest,
+			"decline": server.declineMessageRequest,
+			"block":   server.blockMessageRequest,
+		}
+		return sendAs(t, userID, http.MethodPost,
+			"/message_requests/:conversation_id/"+action,
+			fmt.Sprintf("/message_requests/%d/%s", conversationID, action),
+			handlers[action])
+	}
+	list := func(userID int64, inbox string) *httptest.ResponseRecorder {
+		return sendAs(t, userID, http.MethodGet, "/conversations",
+			"/conversations?inbox="+inbox, server.listConversations)
+	}
+	showsPresence := func(viewerID, userID int64) bool {
+		view, err := server.newPrivacyView(context.Background(), viewerID,
+			[]int64{userID})
+		require.NoError(t, err)
+		return view.showOnline(userID) && view.showReadReceipts(userID)
+	}
+
+	// first contact lands in the recipient's requests inbox
+	resp := start(1, 2)
+	require.Equal(t, true, resp["is_request"])
+	conversationID := int64(resp["conversation_id"].(float64))
+	require.Equal(t, util.InboxRequests, store.inbox(conversationID, 2))
+	require.Equal(t, util.InboxPrimary, store.inbox(conversationID, 1))
+
+	recorder := list(2, util.InboxRequests)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Body.String(), `"count":1`)
+	require.Contains(t, list(2, util.InboxPrimary).Body.String(), `"count":0`)
+	require.Contains(t, list(1, util.InboxPrimary).Body.String(), `"count":1`)
+	require.Equal(t, http.StatusBadRequest, list(2, util.InboxDeclined).Code)
+
+	// the sender sees nothing until the request is accepted
+	require.False(t, showsPresence(1, 2))
+	require.True(t, showsPresence(2, 1))
+
+	// a declined request still looks pending to the sender
+	require.Equal(t, http.StatusOK, answer(2, "decline", conversationID).Code)
+	require.Equal(t, util.InboxDeclined, store.inbox(conversationID, 2))
+	require.Equal(t, true, start(1, 2)["is_request"])
+	require.Contains(t, list(2, util.InboxRequests).Body.String(), `"count":0`)
+	require.False(t, showsPresence(1, 2))
+
+	// but can still be accepted
+	require.Equal(t, http.StatusOK, answer(2, "accept", conversationID).Code)
+	require.Equal(t, util.InboxPrimary, store.inbox(conversationID, 2))
+	require.Equal(t, false, start(1, 2)["is_request"])
+	require.True(t, showsPresence(1, 2))
+
+	// nothing left to answer, and only participants can
+	require.Equal(t, http.StatusNotFound, answer(2, "accept", conversationID).Code)
+	require.Equal(t, http.StatusNotFound, answer(3, "accept", conversationID).Code)
+
+	// contacts of the recipient skip the requests inbox
+	store.contacts[[2]int64{4, 3}] = ""
+	require.Equal(t, false, start(3, 4)["is_request"])
+	require.Equal(t, true, start(4, 5)["is_request"])
+}
+
+func TestBlockMessageRequest(t *testing.T) {
+	store := newFakePrivacyStore()
+	server := newTestServer(t, store, nil)
+
+	recorder := sendAs(t, 1, http.MethodPost, "/conversations/:other_user_id",
+		"/conversations/2", server.GetOrCreateDirectConversation)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	conversationID := store.conversations[conversationKey(1, 2)]
+
+	recorder = sendAs(t, 2, http.MethodPost,
+		"/message_requests/:conversation_id/block",
+		fmt.Sprintf("/message_requests/%d/block", conversationID),
+		server.blockMessageRequest)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	require.Equal(t, util.InboxDeclined, store.inbox(conversationID, 2))
+	require.Contains(t, store.blocks, [2]int64{2, 1})
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/kratos069/message-app/token"
+	"github.com/kratos069/message-app/util"
 	"github.com/rs/zerolog/log"
 )
 
@@ -19,7 +20,8 @@ const (
 )
 
 // returns auth middlware function
-func authMiddleware(tokenMaker token.Maker, accessibleRoles []string) gin.HandlerFunc {
+func authMiddleware(tokenMaker token.Maker, guard *sessionGuard,
+	policy accountPolicy, accessibleRoles []string) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authorizationHeader := ctx.GetHeader(authorizationHeaderKey)
 		if len(authorizationHeader) == 0 {
@@ -46,12 +48,34 @@ func authMiddleware(tokenMaker token.Maker, accessibleRoles []string) gin.Handle
 
 		// second value of header is token
 		accessToken := fields[1]
+
+		// bots authenticate with API keys instead
+		if strings.HasPrefix(accessToken, util.APIKeyPrefix) {
+			if authenticateAPIKey(ctx, guard, policy, accessToken, accessibleRoles) {
+				ctx.Next()
+			}
+			return
+		}
 		payload, err := tokenMaker.VerifyToken(accessToken)
 		if err != nil {
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errResponse(err))
 			return
 		}
 
+		// token must still belong to a live session
+		user, err := guard.check(ctx, payload)
+		if err != nil {
+			ctx.AbortWithStatusJSON(sessionErrorStatus(err), errResponse(err))
+			return
+		}
+
+		// banned and unverified accounts are limited by the policy
+		err = policy.checkAccess(user, isReadOnlyRequest(ctx))
+		if err != nil {
+			abortWithPolicyError(ctx, user, err)
+			return
+		}
+
 		// store payload in key
 		ctx.Set(authorizationPayloadKey, payload)
 
@@ -67,6 +91,19 @@ func authMiddleware(tokenMaker token.Maker, accessibleRoles []string) gin.Handle
 	}
 }
 
+// maps session guard errors to response codes
+func sessionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errTokenRevoked),
+		errors.Is(err, errSessionBlocked),
+		errors.Is(err, errRoleChanged),
+		errors.Is(err, token.ErrInvalidToken):
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 func hasPermissions(userRole string, accessibleRoles []string) bool {
 	for _, role := range accessibleRoles {
 		if userRole == role {
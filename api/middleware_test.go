@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -8,6 +9,9 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
 	"github.com/kratos069/message-app/token"
 	"github.com/kratos069/message-app/util"
 	"github.com/stretchr/testify/require"
@@ -21,9 +25,11 @@ func addAuthorization(
 	username string,
 	userID int64,
 	role string,
+	sessionID uuid.UUID,
 	duration time.Duration,
 ) {
-	token, payload, err := tokenMaker.CreateToken(username, userID, role, duration)
+	token, payload, err := tokenMaker.CreateToken(username, userID, role,
+		sessionID, duration)
 	require.NoError(t, err)
 	require.NotEmpty(t, payload)
 
@@ -31,7 +37,62 @@ func addAuthorization(
 	request.Header.Set(authorizationHeaderKey, authorizationHeader)
 }
 
+// fakeSessionStore serves sessions and users from memory
+type fakeSessionStore struct {
+	sessions map[uuid.UUID]db.Session
+	users    map[int64]db.User
+	apiKeys  map[string]db.APIKey
+}
+
+func (store *fakeSessionStore) GetSessionByID(ctx context.Context,
+	id uuid.UUID) (db.Session, error) {
+	session, ok := store.sessions[id]
+	if !ok {
+		return session, pgx.ErrNoRows
+	}
+	return session, nil
+}
+
+func (store *fakeSessionStore) GetUserByID(ctx context.Context,
+	id int64) (db.User, error) {
+	user, ok := store.users[id]
+	if !ok {
+		return user, pgx.ErrNoRows
+	}
+	return user, nil
+}
+
+func (store *fakeSessionStore) GetAPIKeyByPrefix(ctx context.Context,
+	prefix string) (db.APIKey, error) {
+	apiKey, ok := store.apiKeys[prefix]
+	if !ok {
+		return apiKey, pgx.ErrNoRows
+	}
+	return apiKey, nil
+}
+
+func (store *fakeSessionStore) TouchAPIKey(ctx context.Context,
+	apiKeyID int64) error {
+	return nil
+}
+
 func TestAuthMiddleware(t *testing.T) {
+	sessionID := uuid.New()
+	blockedSessionID := uuid.New()
+	revokedSessionID := uuid.New()
+
+	store := &fakeSessionStore{
+		sessions: map[uuid.UUID]db.Session{
+			sessionID:        {ID: sessionID, Username: "user"},
+			revokedSessionID: {ID: revokedSessionID, Username: "user"},
+			blockedSessionID: {ID: blockedSessionID, Username: "user", IsBlocked: true},
+		},
+		users: map[int64]db.User{
+			100: {ID: 100, Username: "user", Role: "customer", IsEmailVerified: true},
+			200: {ID: 200, Username: "user", Role: "customer", IsBanned: true},
+		},
+	}
+
 	testCases := []struct {
 		name          string
 		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
@@ -41,7 +102,7 @@ func TestAuthMiddleware(t *testing.T) {
 			name: "OK",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 				addAuthorization(t, request, tokenMaker,
-					authorizationTypeBearer, "user", 100, "customer", time.Minute)
+					authorizationTypeBearer, "user", 100, "customer", sessionID, time.Minute)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
@@ -59,7 +120,7 @@ func TestAuthMiddleware(t *testing.T) {
 			name: "UnsupportedAuthorization",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 				addAuthorization(t, request, tokenMaker,
-					"unsupported", "user", 100, "customer", time.Minute)
+					"unsupported", "user", 100, "customer", sessionID, time.Minute)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
@@ -69,7 +130,7 @@ func TestAuthMiddleware(t *testing.T) {
 			name: "InvalidAuthorizationFormat",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 				addAuthorization(t, request, tokenMaker,
-					"", "user", 100, "customer", time.Minute)
+					"", "user", 100, "customer", sessionID, time.Minute)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
@@ -79,12 +140,62 @@ func TestAuthMiddleware(t *testing.T) {
 			name: "ExpiredToken",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 				addAuthorization(t, request, tokenMaker,
-					authorizationTypeBearer, "user", 100, "customer", -time.Minute)
+					authorizationTypeBearer, "user", 100, "customer", sessionID, -time.Minute)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
 			},
 		},
+		{
+			name: "UnknownSession",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker,
+					authorizationTypeBearer, "user", 100, "customer", uuid.New(), time.Minute)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "BlockedSession",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker,
+					authorizationTypeBearer, "user", 100, "customer", blockedSessionID, time.Minute)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "RevokedSession",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker,
+					authorizationTypeBearer, "user", 100, "customer", revokedSessionID, time.Minute)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ChangedRole",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker,
+					authorizationTypeBearer, "user", 100, "admin", sessionID, time.Minute)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "BannedUser",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker,
+					authorizationTypeBearer, "user", 200, "customer", sessionID, time.Minute)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
 	}
 
 	for i := range testCases {
@@ -93,10 +204,15 @@ func TestAuthMiddleware(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			server := newTestServer(t, nil, nil)
 
+			guard := newSessionGuard(store, token.NewMemoryRevocationList(), time.Minute)
+			err := guard.revokeSession(context.Background(), revokedSessionID, time.Minute)
+			require.NoError(t, err)
+
 			authPath := "/auth"
 			server.router.GET(
 				authPath,
-				authMiddleware(server.tokenMaker, []string{util.AdminRole, util.CustomerRole}),
+				authMiddleware(server.tokenMaker, guard, server.accountPolicy,
+					[]string{util.AdminRole, util.CustomerRole}),
 				func(ctx *gin.Context) {
 					ctx.JSON(http.StatusOK, gin.H{})
 				},
@@ -0,0 +1,355 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/util"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/oauth2"
+)
+
+const (
+	oidcStateCookie = "oidc_state"
+	// attempts at finding a free username for a new account
+	oidcUsernameAttempts = 5
+)
+
+var (
+	errOIDCNotConfigured   = errors.New("single sign-on is not configured")
+	errOIDCFlowExpired     = errors.New("login has expired or was already used, please try again")
+	errOIDCEmailUnverified = errors.New("the provider has not verified this email address")
+	// prevents taking over an account someone registered with an email
+	// they don't own, before its owner signs in through the provider
+	errOIDCAccountUnverified = errors.New("an account with this email exists but is not verified, log in with its password first")
+)
+
+// oidcClient talks to the configured OpenID Connect provider.
+// Discovery happens on first use, so the server also starts
+// while the provider is unreachable.
+type oidcClient struct {
+	issuerURL    string
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	httpClient   *http.Client
+
+	mu       sync.Mutex
+	oauth2   *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// returns nil when no provider is configured
+func newOIDCClient(config util.Config) *oidcClient {
+	if config.OIDCIssuerURL == "" {
+		return nil
+	}
+
+	return &oidcClient{
+		issuerURL:    config.OIDCIssuerURL,
+		clientID:     config.OIDCClientID,
+		clientSecret: config.OIDCClientSecret,
+		redirectURL:  config.OIDCRedirectURL,
+		httpClient:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// requests to the provider don't depend on the request which
+// triggered them, the keys it fetches are cached across requests
+func (client *oidcClient) context() context.Context {
+	return oidc.ClientContext(context.Background(), client.httpClient)
+}
+
+func (client *oidcClient) init() (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	if client.oauth2 != nil {
+		return client.oauth2, client.verifier, nil
+	}
+
+	provider, err := oidc.NewProvider(client.context(), client.issuerURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to discover provider: %w", err)
+	}
+
+	client.oauth2 = &oauth2.Config{
+		ClientID:     client.clientID,
+		ClientSecret: client.clientSecret,
+		RedirectURL:  client.redirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       []string{oidc.ScopeOpenID, "email", "profile"},
+	}
+	// checks signature (provider's JWKS), issuer, audience and expiry
+	client.verifier = provider.Verifier(&oidc.Config{
+		ClientID: client.clientID,
+	})
+
+	return client.oauth2, client.verifier, nil
+}
+
+// OIDCLogin redirects to the provider, the flow is protected by
+// PKCE, a nonce in the ID token and a state bound to a cookie
+func (server *Server) oidcLogin(ctx *gin.Context) {
+	if server.oidc == nil {
+		ctx.JSON(http.StatusNotFound, errResponse(errOIDCNotConfigured))
+		return
+	}
+
+	oauthConfig, _, err := server.oidc.init()
+	if err != nil {
+		log.Error().Err(err).Msg("OIDC provider unavailable")
+		ctx.JSON(http.StatusServiceUnavailable, errResponse(err))
+		return
+	}
+
+	state := util.RandomString(32)
+	flow := oidcFlow{
+		CodeVerifier: oauth2.GenerateVerifier(),
+		Nonce:        util.RandomString(32),
+	}
+
+	err = server.oidcFlows.save(ctx, state, flow)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(oidcStateCookie, state, int(oidcFlowDuration.Seconds()),
+		"/oauth", "", ctx.Request.TLS != nil, true)
+
+	ctx.Redirect(http.StatusFound, oauthConfig.AuthCodeURL(state,
+		oauth2.S256ChallengeOption(flow.CodeVerifier),
+		oidc.Nonce(flow.Nonce)))
+}
+
+type oidcCallbackRequest struct {
+	Code  string `form:"code" binding:"required"`
+	State string `form:"state" binding:"required"`
+}
+
+// claims we read from the ID token
+type oidcClaims struct {
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+// OIDCCallback finishes the login the provider redirected back from,
+// signing in the linked user or creating a new one
+func (server *Server) oidcCallback(ctx *gin.Context) {
+	if server.oidc == nil {
+		ctx.JSON(http.StatusNotFound, errResponse(errOIDCNotConfigured))
+		return
+	}
+
+	if providerErr := ctx.Query("error"); providerErr != "" {
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error":       "login was refused by the provider",
+			"description": ctx.Query("error_description"),
+		})
+		return
+	}
+
+	var req oidcCallbackRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	// the browser finishing the login must be the one which started it
+	cookieState, err := ctx.Cookie(oidcStateCookie)
+	if err != nil || cookieState != req.State {
+		ctx.JSON(http.StatusBadRequest, errResponse(errOIDCFlowExpired))
+		return
+	}
+	ctx.SetCookie(oidcStateCookie, "", -1, "/oauth", "",
+		ctx.Request.TLS != nil, true)
+
+	flow, ok, err := server.oidcFlows.take(ctx, req.State)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, errResponse(errOIDCFlowExpired))
+		return
+	}
+
+	oauthConfig, verifier, err := server.oidc.init()
+	if err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, errResponse(err))
+		return
+	}
+
+	oauthToken, err := oauthConfig.Exchange(server.oidc.context(), req.Code,
+		oauth2.VerifierOption(flow.CodeVerifier))
+	if err != nil {
+		log.Error().Err(err).Msg("OIDC code exchange failed")
+		ctx.JSON(http.StatusUnauthorized,
+			gin.H{"error": "failed to exchange authorization code"})
+		return
+	}
+
+	rawIDToken, ok := oauthToken.Extra("id_token").(string)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized,
+			gin.H{"error": "provider did not return an ID token"})
+		return
+	}
+
+	idToken, err := verifier.Verify(server.oidc.context(), rawIDToken)
+	if err != nil {
+		log.Error().Err(err).Msg("OIDC ID token rejected")
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid ID token"})
+		return
+	}
+	if idToken.Nonce != flow.Nonce {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid ID token"})
+		return
+	}
+
+	var claims oidcClaims
+	err = idToken.Claims(&claims)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid ID token"})
+		return
+	}
+	if claims.Email == "" || !claims.EmailVerified {
+		ctx.JSON(http.StatusForbidden, errResponse(errOIDCEmailUnverified))
+		return
+	}
+
+	user, err := server.oidcUser(ctx, idToken.Issuer, idToken.Subject, claims)
+	if err != nil {
+		if errors.Is(err, errOIDCAccountUnverified) {
+			ctx.JSON(http.StatusConflict, errResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	server.completeLogin(ctx, user)
+}
+
+// finds the user linked to the provider identity, links an existing
+// account with the same verified email, or creates a new account
+func (server *Server) oidcUser(ctx *gin.Context, issuer, subject string,
+	claims oidcClaims) (db.User, error) {
+	identity, err := server.store.GetUserIdentity(ctx, db.GetUserIdentityParams{
+		Issuer:  issuer,
+		Subject: subject,
+	})
+	if err == nil {
+		return server.store.GetUserByID(ctx, identity.UserID)
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return db.User{}, err
+	}
+
+	user, err := server.store.GetUserByEmail(ctx, claims.Email)
+	if err == nil {
+		if !user.IsEmailVerified {
+			return db.User{}, errOIDCAccountUnverified
+		}
+
+		_, err = server.store.CreateUserIdentity(ctx, db.CreateUserIdentityParams{
+			UserID:  user.ID,
+			Issuer:  issuer,
+			Subject: subject,
+			Email:   claims.Email,
+		})
+		if err != nil {
+			return db.User{}, err
+		}
+
+		log.Info().Str("username", user.Username).Str("issuer", issuer).
+			Msg("Linked external identity")
+		return user, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return db.User{}, err
+	}
+
+	return server.createOIDCUser(ctx, issuer, subject, claims)
+}
+
+func (server *Server) createOIDCUser(ctx *gin.Context, issuer, subject string,
+	claims oidcClaims) (db.User, error) {
+	// nobody knows the password, a real one can be set
+	// through the password reset flow
+	hashedPassword, err := util.HashPassword(util.RandomString(32))
+	if err != nil {
+		return db.User{}, err
+	}
+
+	base := oidcUsername(claims)
+	for attempt := 0; attempt < oidcUsernameAttempts; attempt++ {
+		username := base
+		if attempt > 0 {
+			username = fmt.Sprintf("%s%d", base, util.RandomInt(1000, 9999))
+		}
+
+		result, err := server.store.CreateUserTx(ctx, db.CreateUserTxParams{
+			CreateUserParams: db.CreateUserParams{
+				Username:     username,
+				Email:        claims.Email,
+				PasswordHash: hashedPassword,
+				Role:         util.CustomerRole,
+			},
+			Identity: &db.CreateUserIdentityParams{
+				Issuer:  issuer,
+				Subject: subject,
+				Email:   claims.Email,
+			},
+		})
+		if err == nil {
+			log.Info().Str("username", username).Str("issuer", issuer).
+				Msg("Created user from external identity")
+			return result.User, nil
+		}
+		if !isDuplicateKeyError(err) {
+			return db.User{}, err
+		}
+	}
+
+	return db.User{}, errors.New("could not find a free username")
+}
+
+// derives a username allowed at registration (3-50 alphanumerics)
+// from the provider's preferred username or the email
+func oidcUsername(claims oidcClaims) string {
+	source := claims.PreferredUsername
+	if source == "" {
+		source, _, _ = strings.Cut(claims.Email, "@")
+	}
+
+	var username strings.Builder
+	for _, r := range source {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			username.WriteRune(r)
+		}
+	}
+
+	name := username.String()
+	if len(name) > 45 {
+		name = name[:45]
+	}
+	for len(name) < 3 {
+		name += "user"
+	}
+
+	return name
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kratos069/message-app/util"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	oidcFlowDuration  = 10 * time.Minute
+	oidcFlowKeyPrefix = "oidc:flow:"
+)
+
+// secrets of one login between the redirect to the
+// provider and its callback, looked up by the state parameter
+type oidcFlow struct {
+	CodeVerifier string `json:"code_verifier"`
+	Nonce        string `json:"nonce"`
+}
+
+// oidcFlowStore keeps flows until the callback,
+// each flow can be taken only once
+type oidcFlowStore interface {
+	save(ctx context.Context, state string, flow oidcFlow) error
+	take(ctx context.Context, state string) (oidcFlow, bool, error)
+}
+
+// for a single instance, the callback must reach the same server
+type memoryOIDCFlowStore struct {
+	flows *util.TTLCache[string, oidcFlow]
+}
+
+func newMemoryOIDCFlowStore() oidcFlowStore {
+	return &memoryOIDCFlowStore{
+		flows: util.NewTTLCache[string, oidcFlow](oidcFlowDuration),
+	}
+}
+
+func (store *memoryOIDCFlowStore) save(ctx context.Context,
+	state string, flow oidcFlow) error {
+	store.flows.Set(state, flow)
+	return nil
+}
+
+func (store *memoryOIDCFlowStore) take(ctx context.Context,
+	state string) (oidcFlow, bool, error) {
+	flow, ok := store.flows.Take(state)
+	return flow, ok, nil
+}
+
+// shared by every instance
+type redisOIDCFlowStore struct {
+	client *redis.Client
+}
+
+func newRedisOIDCFlowStore(client *redis.Client) oidcFlowStore {
+	return &redisOIDCFlowStore{
+		client: client,
+	}
+}
+
+func (store *redisOIDCFlowStore) save(ctx context.Context,
+	state string, flow oidcFlow) error {
+	value, err := json.Marshal(flow)
+	if err != nil {
+		return err
+	}
+
+	err = store.client.Set(ctx, oidcFlowKeyPrefix+state, value,
+		oidcFlowDuration).Err()
+	if err != nil {
+		return fmt.Errorf("failed to save login flow: %w", err)
+	}
+
+	return nil
+}
+
+func (store *redisOIDCFlowStore) take(ctx context.Context,
+	state string) (oidcFlow, bool, error) {
+	value, err := store.client.GetDel(ctx, oidcFlowKeyPrefix+state).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return oidcFlow{}, false, nil
+		}
+		return oidcFlow{}, false, fmt.Errorf("failed to get login flow: %w", err)
+	}
+
+	var flow oidcFlow
+	err = json.Unmarshal(value, &flow)
+	if err != nil {
+		return oidcFlow{}, false, fmt.Errorf("invalid login flow: %w", err)
+	}
+
+	return flow, true, nil
+}
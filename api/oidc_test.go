@@ -0,0 +1,375 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/jackc/pgx/v5"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/util"
+	"github.com/stretchr/testify/require"
+)
+
+const mockClientID = "message-app"
+
+// mockIdP is a minimal OpenID Connect provider: discovery, JWKS and
+// a token endpoint checking PKCE. Authorization is done by the test.
+type mockIdP struct {
+	server *httptest.Server
+	signer jose.Signer
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]mockGrant
+}
+
+type mockGrant struct {
+	challenge string
+	nonce     string
+	claims    map[string]any
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key},
+		(&jose.SignerOptions{}).WithHeader("kid", "mock"))
+	require.NoError(t, err)
+
+	idp := &mockIdP{
+		signer: signer,
+		key:    key,
+		codes:  make(map[string]mockGrant),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                idp.server.URL,
+			"authorization_endpoint":                idp.server.URL + "/authorize",
+			"token_endpoint":                        idp.server.URL + "/token",
+			"jwks_uri":                              idp.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{
+			Key:       &key.PublicKey,
+			KeyID:     "mock",
+			Algorithm: string(jose.RS256),
+			Use:       "sig",
+		}}})
+	})
+	mux.HandleFunc("/token", idp.token)
+
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+
+	return idp
+}
+
+// plays the user logging in at the provider, returns the code
+// the provider would redirect back with
+func (idp *mockIdP) authorize(t *testing.T, authURL string,
+	claims map[string]any) string {
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	query := u.Query()
+
+	require.Equal(t, mockClientID, query.Get("client_id"))
+	require.Equal(t, "S256", query.Get("code_challenge_method"))
+
+	code := util.RandomString(16)
+
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.codes[code] = mockGrant{
+		challenge: query.Get("code_challenge"),
+		nonce:     query.Get("nonce"),
+		claims:    claims,
+	}
+
+	return code
+}
+
+func (idp *mockIdP) token(w http.ResponseWriter, r *http.Request) {
+	idp.mu.Lock()
+	grant, ok := idp.codes[r.FormValue("code")]
+	delete(idp.codes, r.FormValue("code"))
+	idp.mu.Unlock()
+
+	verifier := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(verifier[:]) != grant.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := map[string]any{
+		"iss":   idp.server.URL,
+		"aud":   mockClientID,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"nonce": grant.nonce,
+	}
+	for k, v := range grant.claims {
+		claims[k] = v
+	}
+
+	payload, _ := json.Marshal(claims)
+	signed, _ := idp.signer.Sign(payload)
+	idToken, _ := signed.CompactSerialize()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"access_token": "mock-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   60,
+		"id_token":     idToken,
+	})
+}
+
+// fakeOIDCStore keeps just what the login needs in memory
+type fakeOIDCStore struct {
+	db.Store
+	users      map[int64]db.User
+	identities map[string]db.UserIdentity
+}
+
+func newFakeOIDCStore() *fakeOIDCStore {
+	return &fakeOIDCStore{
+		users:      make(map[int64]db.User),
+		identities: make(map[string]db.UserIdentity),
+	}
+}
+
+func (store *fakeOIDCStore) GetUserIdentity(ctx context.Context,
+	arg db.GetUserIdentityParams) (db.UserIdentity, error) {
+	identity, ok := store.identities[arg.Issuer+"|"+arg.Subject]
+	if !ok {
+		return identity, pgx.ErrNoRows
+	}
+	return identity, nil
+}
+
+func (store *fakeOIDCStore) CreateUserIdentity(ctx context.Context,
+	arg db.CreateUserIdentityParams) (db.UserIdentity, error) {
+	identity := db.UserIdentity{
+		UserID:  arg.UserID,
+		Issuer:  arg.Issuer,
+		Subject: arg.Subject,
+		Email:   arg.Email,
+	}
+	store.identities[arg.Issuer+"|"+arg.Subject] = identity
+	return identity, nil
+}
+
+func (store *fakeOIDCStore) GetUserByID(ctx context.Context, id int64) (db.User, error) {
+	user, ok := store.users[id]
+	if !ok {
+		return user, pgx.ErrNoRows
+	}
+	return user, nil
+}
+
+func (store *fakeOIDCStore) GetUserByEmail(ctx context.Context, email string) (db.User, error) {
+	for _, user := range store.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return db.User{}, pgx.ErrNoRows
+}
+
+func (store *fakeOIDCStore) CreateUserTx(ctx context.Context,
+	arg db.CreateUserTxParams) (db.CreateUserTxResults, error) {
+	user := db.User{
+		ID:              int64(len(store.users) + 1),
+		Username:        arg.Username,
+		Email:           arg.Email,
+		PasswordHash:    arg.PasswordHash,
+		Role:            arg.Role,
+		IsEmailVerified: arg.Identity != nil,
+	}
+	store.users[user.ID] = user
+
+	if arg.Identity != nil {
+		identity := *arg.Identity
+		identity.UserID = user.ID
+		_, _ = store.CreateUserIdentity(ctx, identity)
+	}
+
+	return db.CreateUserTxResults{User: user}, nil
+}
+
+func (store *fakeOIDCStore) GetTwoFactorAuth(ctx context.Context,
+	userID int64) (db.TwoFactorAuth, error) {
+	return db.TwoFactorAuth{}, pgx.ErrNoRows
+}
+
+func (store *fakeOIDCStore) CreateSession(ctx context.Context,
+	arg db.CreateSessionParams) (db.Session, error) {
+	return db.Session{ID: arg.ID, Username: arg.Username}, nil
+}
+
+func (store *fakeOIDCStore) MarkUserOnline(ctx context.Context,
+	id int64) error {
+	return nil
+}
+
+// runs a whole login through the mock provider
+func oidcLogin(t *testing.T, server *Server, idp *mockIdP,
+	claims map[string]any) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/oauth/login", nil)
+	require.NoError(t, err)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusFound, recorder.Code)
+
+	authURL := recorder.Header().Get("Location")
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	state := u.Query().Get("state")
+	cookies := recorder.Result().Cookies()
+
+	code := idp.authorize(t, authURL, claims)
+
+	callback := "/oauth/callback?" + url.Values{
+		"code":  {code},
+		"state": {state},
+	}.Encode()
+
+	recorder = httptest.NewRecorder()
+	request, err = http.NewRequest(http.MethodGet, callback, nil)
+	require.NoError(t, err)
+	for _, cookie := range cookies {
+		request.AddCookie(cookie)
+	}
+	server.router.ServeHTTP(recorder, request)
+
+	return recorder
+}
+
+func newOIDCTestServer(t *testing.T, store db.Store, idp *mockIdP) *Server {
+	config := util.Config{
+		TokenSymmetricKey:   util.RandomString(32),
+		AccessTokenDuration: time.Minute,
+		OIDCIssuerURL:       idp.server.URL,
+		OIDCClientID:        mockClientID,
+		OIDCClientSecret:    "secret",
+		OIDCRedirectURL:     "http://localhost:8080/oauth/callback",
+	}
+
+	server, err := NewServer(config, store, nil)
+	require.NoError(t, err)
+
+	return server
+}
+
+func TestOIDCLogin(t *testing.T) {
+	idp := newMockIdP(t)
+
+	t.Run("CreatesAccount", func(t *testing.T) {
+		store := newFakeOIDCStore()
+		server := newOIDCTestServer(t, store, idp)
+
+		recorder := oidcLogin(t, server, idp, map[string]any{
+			"sub":                "alice-sub",
+			"email":              "alice@example.com",
+			"email_verified":     true,
+			"preferred_username": "alice.smith",
+		})
+		require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+
+		var resp loginUserResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+		require.Equal(t, "alicesmith", resp.User.Username)
+		require.NotEmpty(t, resp.AccessToken)
+
+		require.Len(t, store.users, 1)
+		require.True(t, store.users[1].IsEmailVerified)
+
+		// the second login finds the linked identity
+		recorder = oidcLogin(t, server, idp, map[string]any{
+			"sub":            "alice-sub",
+			"email":          "alice@example.com",
+			"email_verified": true,
+		})
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.Len(t, store.users, 1)
+	})
+
+	t.Run("LinksVerifiedAccount", func(t *testing.T) {
+		store := newFakeOIDCStore()
+		store.users[7] = db.User{ID: 7, Username: "bob", Email: "bob@example.com",
+			Role: util.CustomerRole, IsEmailVerified: true}
+		server := newOIDCTestServer(t, store, idp)
+
+		recorder := oidcLogin(t, server, idp, map[string]any{
+			"sub":            "bob-sub",
+			"email":          "bob@example.com",
+			"email_verified": true,
+		})
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.Len(t, store.users, 1)
+		require.Equal(t, int64(7), store.identities[idp.server.URL+"|bob-sub"].UserID)
+	})
+
+	t.Run("RefusesUnverifiedAccount", func(t *testing.T) {
+		store := newFakeOIDCStore()
+		store.users[7] = db.User{ID: 7, Username: "carol", Email: "carol@example.com",
+			Role: util.CustomerRole}
+		server := newOIDCTestServer(t, store, idp)
+
+		recorder := oidcLogin(t, server, idp, map[string]any{
+			"sub":            "carol-sub",
+			"email":          "carol@example.com",
+			"email_verified": true,
+		})
+		require.Equal(t, http.StatusConflict, recorder.Code)
+		require.Empty(t, store.identities)
+	})
+
+	t.Run("RefusesUnverifiedEmail", func(t *testing.T) {
+		store := newFakeOIDCStore()
+		server := newOIDCTestServer(t, store, idp)
+
+		recorder := oidcLogin(t, server, idp, map[string]any{
+			"sub":            "dave-sub",
+			"email":          "dave@example.com",
+			"email_verified": false,
+		})
+		require.Equal(t, http.StatusForbidden, recorder.Code)
+		require.Empty(t, store.users)
+	})
+
+	t.Run("StateMismatch", func(t *testing.T) {
+		server := newOIDCTestServer(t, newFakeOIDCStore(), idp)
+
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet,
+			"/oauth/callback?code=abc&state=forged", nil)
+		require.NoError(t, err)
+		server.router.ServeHTTP(recorder, request)
+		require.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+}
+
+func TestOIDCUsername(t *testing.T) {
+	require.Equal(t, "alicesmith", oidcUsername(oidcClaims{PreferredUsername: "alice.smith"}))
+	require.Equal(t, "bob", oidcUsername(oidcClaims{Email: "bob@example.com"}))
+	require.Equal(t, "xuser", oidcUsername(oidcClaims{Email: "x@example.com"}))
+	require.Equal(t, "user", oidcUsername(oidcClaims{PreferredUsername: "üü"}))
+}
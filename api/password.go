@@ -0,0 +1,106 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+	db "github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/util"
+	"github.com/kratos069/message-app/worker"
+	"github.com/rs/zerolog/log"
+)
+
+// both endpoints answer the same way whether or not
+// the account exists, so they can't be used to probe for emails
+const (
+	forgotPasswordMessage = "If an account exists for this email, a reset code has been sent."
+	resetPasswordFailed   = "Invalid or expired reset code."
+)
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ForgotPassword emails a single-use reset code
+func (server *Server) forgotPassword(ctx *gin.Context) {
+	var req forgotPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	user, err := server.store.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			log.Error().Err(err).Msg("Failed to get user for password reset")
+		}
+		ctx.JSON(http.StatusOK, gin.H{"message": forgotPasswordMessage})
+		return
+	}
+
+	opts := []asynq.Option{
+		asynq.MaxRetry(10),
+		asynq.Queue(worker.QueueCritical),
+	}
+
+	err = server.taskDistributor.DistributeTaskSendResetPassword(ctx,
+		&worker.PayloadSendResetPassword{
+			Username: user.Username,
+		}, opts...)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to distribute reset password task")
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": forgotPasswordMessage})
+}
+
+type resetPasswordRequest struct {
+	Email       string `json:"email" binding:"required,email"`
+	SecretCode  string `json:"secret_code" binding:"required,len=10"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// ResetPassword sets a new password using an emailed reset code
+// and logs the user out everywhere
+func (server *Server) resetPassword(ctx *gin.Context) {
+	var req resetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	hashedPassword, err := util.HashPassword(req.NewPassword)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	result, err := server.store.ResetPasswordTx(ctx, db.ResetPasswordTxParams{
+		Email:        req.Email,
+		SecretCode:   req.SecretCode,
+		PasswordHash: hashedPassword,
+	})
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			log.Error().Err(err).Msg("Failed to reset password")
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": resetPasswordFailed})
+		return
+	}
+
+	// sessions were blocked in the transaction, reject their tokens too
+	err = server.sessionGuard.revokeUser(ctx, result.User.ID,
+		server.revocationTTL())
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to revoke tokens after password reset")
+	}
+
+	log.Info().Str("username", result.User.Username).Msg("Password reset")
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Password has been reset. Please log in again.",
+	})
+}
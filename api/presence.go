@@ -0,0 +1,189 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/token"
+	"github.com/rs/zerolog/log"
+)
+
+const defaultPresenceTTL = time.Minute
+
+type presenceResponse struct {
+	UserID     int64      `json:"user_id"`
+	IsOnline   bool       `json:"is_online"`
+	LastSeenAt *time.Time `json:"last_seen_at"`
+}
+
+// Heartbeat keeps the caller online for the presence TTL. Clients send one
+// well within expires_in for as long as they are connected.
+func (server *Server) heartbeat(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err := server.markOnline(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"is_online":  true,
+		"expires_in": int64(server.presenceTTL().Seconds()),
+	})
+}
+
+type queryPresenceRequest struct {
+	UserIDs []int64 `json:"user_ids" binding:"required,min=1,max=100,dive,min=1"`
+}
+
+// QueryPresence returns who of up to 100 users is online and when the
+// others were last seen, as far as their privacy settings allow.
+// Unknown ids are left out.
+func (server *Server) queryPresence(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	var req queryPresenceRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	profiles, err := server.store.ListPublicProfiles(ctx, req.UserIDs)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	userIDs := make([]int64, 0, len(profiles))
+	for _, profile := range profiles {
+		userIDs = append(userIDs, profile.ID)
+	}
+
+	online, err := server.presence.Online(ctx, userIDs)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	view, err := server.newPrivacyView(ctx, authPayload.UserID, userIDs)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	resp := make([]presenceResponse, 0, len(profiles))
+	for _, profile := range profiles {
+		item := presenceResponse{
+			UserID:   profile.ID,
+			IsOnline: online[profile.ID] && view.showOnline(profile.ID),
+		}
+		if view.showLastSeen(profile.ID) {
+			item.LastSeenAt = timestamptzPtr(profile.LastSeenAt)
+		}
+		resp = append(resp, item)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"presence": resp,
+		"count":    len(resp),
+	})
+}
+
+// SweepPresence takes users offline once their heartbeats stop, writing
+// when they were last seen, until ctx is done
+func (server *Server) SweepPresence(ctx context.Context) {
+	server.reconcilePresence(ctx)
+
+	ticker := time.NewTicker(server.presenceTTL() / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			server.expirePresence(ctx)
+		}
+	}
+}
+
+// ==========================================================================
+// ================================Helper====================================
+// ==========================================================================
+
+func (server *Server) presenceTTL() time.Duration {
+	if server.config.PresenceTTL > 0 {
+		return server.config.PresenceTTL
+	}
+	return defaultPresenceTTL
+}
+
+// records a heartbeat, the database only hears about users coming online
+func (server *Server) markOnline(ctx context.Context, userID int64) error {
+	cameOnline, err := server.presence.Heartbeat(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !cameOnline {
+		return nil
+	}
+
+	return server.store.MarkUserOnline(ctx, userID)
+}
+
+func (server *Server) expirePresence(ctx context.Context) {
+	expiries, err := server.presence.Expire(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot expire presence")
+		return
+	}
+
+	for _, expiry := range expiries {
+		server.markOffline(ctx, expiry.UserID, expiry.LastSeenAt)
+	}
+}
+
+// users the database still has online from before this instance started,
+// like the ones the memory tracker forgot, never expire on their own
+func (server *Server) reconcilePresence(ctx context.Context) {
+	users, err := server.store.GetOnlineUsers(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot load online users")
+		return
+	}
+
+	userIDs := make([]int64, 0, len(users))
+	for _, user := range users {
+		userIDs = append(userIDs, user.ID)
+	}
+
+	online, err := server.presence.Online(ctx, userIDs)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot check presence")
+		return
+	}
+
+	now := time.Now()
+	for _, userID := range userIDs {
+		if !online[userID] {
+			server.markOffline(ctx, userID, now)
+		}
+	}
+}
+
+func (server *Server) markOffline(ctx context.Context, userID int64,
+	lastSeenAt time.Time) {
+	err := server.store.MarkUserOffline(ctx, db.MarkUserOfflineParams{
+		ID:         userID,
+		LastSeenAt: pgtype.Timestamptz{Time: lastSeenAt, Valid: true},
+	})
+	if err != nil {
+		log.Error().Err(err).Int64("user_id", userID).
+			Msg("cannot mark user offline")
+	}
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/presence"
+	"github.com/stretchr/testify/require"
+)
+
+func (store *fakeProfileStore) MarkUserOffline(ctx context.Context,
+	arg db.MarkUserOfflineParams) error {
+	user := store.users[arg.ID]
+	user.IsOnline = false
+	user.LastSeenAt = arg.LastSeenAt
+	store.users[arg.ID] = user
+	return nil
+}
+
+func (store *fakeProfileStore) GetOnlineUsers(ctx context.Context) (
+	[]db.GetOnlineUsersRow, error) {
+	users := []db.GetOnlineUsersRow{}
+	for _, user := range store.users {
+		if user.IsOnline {
+			users = append(users, db.GetOnlineUsersRow{ID: user.ID})
+		}
+	}
+	return users, nil
+}
+
+func TestPresence(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeProfileStore()
+	server := newTestServer(t, store, nil)
+	server.config.PresenceTTL = 50 * time.Millisecond
+	server.presence = presence.NewMemoryTracker(server.config.PresenceTTL)
+
+	query := func(userIDs ...int64) map[int64]presenceResponse {
+		data, err := json.Marshal(queryPresenceRequest{UserIDs: userIDs})
+		require.NoError(t, err)
+		router := newProfileTestRouter(newPrivacyTestPayload(1),
+			http.MethodPost, "/presence/query", server.queryPresence)
+		request, err := http.NewRequest(http.MethodPost, "/presence/query",
+			bytes.NewReader(data))
+		require.NoError(t, err)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var resp struct {
+			Presence []presenceResponse `json:"presence"`
+		}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+		states := map[int64]presenceResponse{}
+		for _, item := range resp.Presence {
+			states[item.UserID] = item
+		}
+		return states
+	}
+
+	// bob's flag is left over from before the tracker knew about him
+	server.reconcilePresence(ctx)
+	require.False(t, store.users[2].IsOnline)
+	require.True(t, store.users[2].LastSeenAt.Valid)
+
+	recorder := sendAs(t, 2, http.MethodPost, "/presence/heartbeat",
+		"/presence/heartbeat", server.heartbeat)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.True(t, store.users[2].IsOnline)
+
+	// deleted and unknown users are left out
+	states := query(2, 3, 99)
+	require.Len(t, states, 1)
+	require.True(t, states[2].IsOnline)
+
+	time.Sleep(60 * time.Millisecond)
+	require.False(t, query(2)[2].IsOnline)
+
+	// expiry writes the last heartbeat back
+	server.expirePresence(ctx)
+	require.False(t, store.users[2].IsOnline)
+	require.WithinDuration(t, time.Now(), store.users[2].LastSeenAt.Time,
+		time.Second)
+	require.NotNil(t, query(2)[2].LastSeenAt)
+}
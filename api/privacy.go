@@ -0,0 +1,264 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/token"
+	"github.com/kratos069/message-app/util"
+)
+
+var errDirectMessagesClosed = errors.New("this user doesn't accept messages from you")
+
+type privacySettingsResponse struct {
+	LastSeen     string     `json:"last_seen"`
+	Online       string     `json:"online"`
+	ReadReceipts bool       `json:"read_receipts"`
+	DMPolicy     string     `json:"dm_policy"`
+	UpdatedAt    *time.Time `json:"updated_at,omitempty"`
+}
+
+func newPrivacySettingsResponse(settings db.PrivacySetting) privacySettingsResponse {
+	resp := privacySettingsResponse{
+		LastSeen:     settings.LastSeen,
+		Online:       settings.Online,
+		ReadReceipts: settings.ReadReceipts,
+		DMPolicy:     settings.DmPolicy,
+	}
+	// defaults were never saved
+	if !settings.UpdatedAt.IsZero() {
+		resp.UpdatedAt = &settings.UpdatedAt
+	}
+
+	return resp
+}
+
+// GetPrivacySettings returns the caller's privacy settings
+func (server *Server) getPrivacySettings(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	settings, err := server.privacySettings(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newPrivacySettingsResponse(settings))
+}
+
+// fields left out keep their current value
+type updatePrivacySettingsRequest struct {
+	LastSeen     *string `json:"last_seen" binding:"omitempty,oneof=everyone contacts nobody"`
+	Online       *string `json:"online" binding:"omitempty,oneof=everyone contacts nobody"`
+	ReadReceipts *bool   `json:"read_receipts"`
+	DMPolicy     *string `json:"dm_policy" binding:"omitempty,oneof=everyone contacts nobody"`
+}
+
+// UpdatePrivacySettings changes some or all of the caller's privacy settings
+func (server *Server) updatePrivacySettings(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	var req updatePrivacySettingsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	settings, err := server.privacySettings(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	arg := db.UpsertPrivacySettingsParams{
+		UserID:       authPayload.UserID,
+		LastSeen:     settings.LastSeen,
+		Online:       settings.Online,
+		ReadReceipts: settings.ReadReceipts,
+		DmPolicy:     settings.DmPolicy,
+	}
+	if req.LastSeen != nil {
+		arg.LastSeen = *req.LastSeen
+	}
+	if req.Online != nil {
+		arg.Online = *req.Online
+	}
+	if req.ReadReceipts != nil {
+		arg.ReadReceipts = *req.ReadReceipts
+	}
+	if req.DMPolicy != nil {
+		arg.DmPolicy = *req.DMPolicy
+	}
+
+	settings, err = server.store.UpsertPrivacySettings(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newPrivacySettingsResponse(settings))
+}
+
+// ==========================================================================
+// ================================Helper====================================
+// ==========================================================================
+
+// what users get until they change anything
+func defaultPrivacySettings(userID int64) db.PrivacySetting {
+	return db.PrivacySetting{
+		UserID:       userID,
+		LastSeen:     util.AudienceEveryone,
+		Online:       util.AudienceEveryone,
+		ReadReceipts: true,
+		DmPolicy:     util.AudienceEveryone,
+	}
+}
+
+func (server *Server) privacySettings(ctx context.Context,
+	userID int64) (db.PrivacySetting, error) {
+	settings, err := server.store.GetPrivacySettings(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return defaultPrivacySettings(userID), nil
+		}
+		return settings, err
+	}
+
+	return settings, nil
+}
+
+// privacyView answers what one viewer may see about other users
+type privacyView struct {
+	viewer   db.PrivacySetting
+	settings map[int64]db.PrivacySetting
+	// have the viewer in their contacts
+	contacts map[int64]bool
+	// blocked by the viewer or blocking them, either way they see nothing
+	blocked map[int64]bool
+	// haven't accepted the viewer's message request yet
+	pending map[int64]bool
+}
+
+// loads the settings of the viewer and of userIDs, which of them
+// added the viewer as a contact, which are blocked and which still
+// have to accept a message request from the viewer
+func (server *Server) newPrivacyView(ctx context.Context, viewerID int64,
+	userIDs []int64) (privacyView, error) {
+	view := privacyView{
+		viewer:   defaultPrivacySettings(viewerID),
+		settings: make(map[int64]db.PrivacySetting, len(userIDs)),
+	}
+
+	rows, err := server.store.ListPrivacySettings(ctx,
+		slices.Concat(userIDs, []int64{viewerID}))
+	if err != nil {
+		return view, err
+	}
+	for _, settings := range rows {
+		if settings.UserID == viewerID {
+			view.viewer = settings
+		}
+		view.settings[settings.UserID] = settings
+	}
+
+	view.contacts, err = server.contactOwnersAmong(ctx, viewerID, userIDs)
+	if err != nil {
+		return view, err
+	}
+
+	view.blocked, err = server.blockedAmong(ctx, viewerID, userIDs)
+	if err != nil {
+		return view, err
+	}
+
+	view.pending, err = server.pendingRequestsAmong(ctx, viewerID, userIDs)
+	if err != nil {
+		return view, err
+	}
+
+	return view, nil
+}
+
+func (view privacyView) settingsOf(userID int64) db.PrivacySetting {
+	if settings, ok := view.settings[userID]; ok {
+		return settings
+	}
+	return defaultPrivacySettings(userID)
+}
+
+func (view privacyView) allows(userID int64, audience string) bool {
+	// users always see their own
+	if userID == view.viewer.UserID {
+		return true
+	}
+	if view.blocked[userID] || view.pending[userID] {
+		return false
+	}
+
+	switch audience {
+	case util.AudienceEveryone:
+		return true
+	case util.AudienceContacts:
+		return view.contacts[userID]
+	}
+
+	return false
+}
+
+// hides presence the viewer may not see in a profile
+func (view privacyView) applyToProfile(profile *publicProfileResponse) {
+	if !view.showOnline(profile.UserID) {
+		profile.IsOnline = false
+	}
+	if !view.showLastSeen(profile.UserID) {
+		profile.LastSeenAt = nil
+	}
+}
+
+func (view privacyView) showOnline(userID int64) bool {
+	return view.allows(userID, view.settingsOf(userID).Online)
+}
+
+func (view privacyView) showLastSeen(userID int64) bool {
+	return view.allows(userID, view.settingsOf(userID).LastSeen)
+}
+
+// read receipts go both ways, who hides theirs doesn't see anyone's
+func (view privacyView) showReadReceipts(userID int64) bool {
+	if userID == view.viewer.UserID {
+		return true
+	}
+	if view.blocked[userID] || view.pending[userID] {
+		return false
+	}
+	return view.viewer.ReadReceipts && view.settingsOf(userID).ReadReceipts
+}
+
+// reports whether senderID may start a direct conversation with recipientID
+func (server *Server) canStartDirectMessage(ctx context.Context,
+	senderID, recipientID int64) (bool, error) {
+	settings, err := server.privacySettings(ctx, recipientID)
+	if err != nil {
+		return false, err
+	}
+
+	switch settings.DmPolicy {
+	case util.AudienceEveryone:
+		return true, nil
+	case util.AudienceContacts:
+		// contacts of the recipient, not of the sender
+		contacts, err := server.contactsAmong(ctx, recipientID, []int64{senderID})
+		if err != nil {
+			return false, err
+		}
+		return contacts[senderID], nil
+	}
+
+	return false, nil
+}
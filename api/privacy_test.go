@@ -0,0 +1,376 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/token"
+	"github.com/kratos069/message-app/util"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePrivacyStore keeps privacy settings and conversations in memory
+type fakePrivacyStore struct {
+	db.Store
+	settings map[int64]db.PrivacySetting
+	// pairs of users that share a conversation, smaller id first
+	conversations map[[2]int64]int64
+	created       int
+	// blocker first
+	blocks map[[2]int64]time.Time
+	// conversation and participant, primary when missing
+	inboxes map[[2]int64]string
+	// owner first, with the nickname
+	contacts map[[2]int64]string
+}
+
+func newFakePrivacyStore() *fakePrivacyStore {
+	return &fakePrivacyStore{
+		settings:      map[int64]db.PrivacySetting{},
+		conversations: map[[2]int64]int64{},
+		blocks:        map[[2]int64]time.Time{},
+		inboxes:       map[[2]int64]string{},
+		contacts:      map[[2]int64]string{},
+	}
+}
+
+func conversationKey(a, b int64) [2]int64 {
+	return [2]int64{min(a, b), max(a, b)}
+}
+
+func (store *fakePrivacyStore) GetPrivacySettings(ctx context.Context,
+	userID int64) (db.PrivacySetting, error) {
+	settings, ok := store.settings[userID]
+	if !ok {
+		return settings, pgx.ErrNoRows
+	}
+	return settings, nil
+}
+
+func (store *fakePrivacyStore) ListPrivacySettings(ctx context.Context,
+	userIds []int64) ([]db.PrivacySetting, error) {
+	rows := []db.PrivacySetting{}
+	for _, userID := range userIds {
+		if settings, ok := store.settings[userID]; ok {
+			rows = append(rows, settings)
+		}
+	}
+	return rows, nil
+}
+
+func (store *fakePrivacyStore) UpsertPrivacySettings(ctx context.Context,
+	arg db.UpsertPrivacySettingsParams) (db.PrivacySetting, error) {
+	settings := db.PrivacySetting{
+		UserID:       arg.UserID,
+		LastSeen:     arg.LastSeen,
+		Online:       arg.Online,
+		ReadReceipts: arg.ReadReceipts,
+		DmPolicy:     arg.DmPolicy,
+		UpdatedAt:    time.Now(),
+	}
+	store.settings[arg.UserID] = settings
+	return settings, nil
+}
+
+func (store *fakePrivacyStore) inbox(conversationID, userID int64) string {
+	if inbox, ok := store.inboxes[[2]int64{conversationID, userID}]; ok {
+		return inbox
+	}
+	return util.InboxPrimary
+}
+
+func (store *fakePrivacyStore) ListContactIDs(ctx context.Context,
+	arg db.ListContactIDsParams) ([]int64, error) {
+	contactIDs := []int64{}
+	for _, userID := range arg.UserIds {
+		if _, ok := store.contacts[[2]int64{arg.OwnerID, userID}]; ok {
+			contactIDs = append(contactIDs, userID)
+		}
+	}
+	return contactIDs, nil
+}
+
+func (store *fakePrivacyStore) ListContactOwnerIDs(ctx context.Context,
+	arg db.ListContactOwnerIDsParams) ([]int64, error) {
+	ownerIDs := []int64{}
+	for _, userID := range arg.UserIds {
+		if _, ok := store.contacts[[2]int64{userID, arg.ContactID}]; ok {
+			ownerIDs = append(ownerIDs, userID)
+		}
+	}
+	return ownerIDs, nil
+}
+
+func (store *fakePrivacyStore) ListPendingRequestUserIDs(ctx context.Context,
+	arg db.ListPendingRequestUserIDsParams) ([]int64, error) {
+	pending := []int64{}
+	for _, userID := range arg.UserIds {
+		conversationID, ok := store.conversations[conversationKey(arg.UserID, userID)]
+		if ok && store.inbox(conversationID, arg.UserID) == util.InboxPrimary &&
+			store.inbox(conversationID, userID) != util.InboxPrimary {
+			pending = append(pending, userID)
+		}
+	}
+	return pending, nil
+}
+
+func (store *fakePrivacyStore) GetConversationParticipant(ctx context.Context,
+	arg db.GetConversationParticipantParams) (db.ConversationParticipant, error) {
+	for key, conversationID := range store.conversations {
+		if conversationID == arg.ConversationID &&
+			(key[0] == arg.UserID || key[1] == arg.UserID) {
+			return db.ConversationParticipant{
+				ConversationID: conversationID,
+				UserID:         arg.UserID,
+				Inbox:          store.inbox(conversationID, arg.UserID),
+			}, nil
+		}
+	}
+	return db.ConversationParticipant{}, pgx.ErrNoRows
+}
+
+func (store *fakePrivacyStore) ListConversationPeople(ctx context.Context,
+	conversationID int64) ([]db.ConversationParticipant, error) {
+	people := []db.ConversationParticipant{}
+	for key, id := range store.conversations {
+		if id == conversationID {
+			for _, userID := range key {
+				people = append(people, db.ConversationParticipant{
+					ConversationID: conversationID,
+					UserID:         userID,
+					Inbox:          store.inbox(conversationID, userID),
+				})
+			}
+		}
+	}
+	return people, nil
+}
+
+func (store *fakePrivacyStore) UpdateParticipantInbox(ctx context.Context,
+	arg db.UpdateParticipantInboxParams) (db.ConversationParticipant, error) {
+	store.inboxes[[2]int64{arg.ConversationID, arg.UserID}] = arg.Inbox
+	return db.ConversationParticipant{
+		ConversationID: arg.ConversationID,
+		UserID:         arg.UserID,
+		Inbox:          arg.Inbox,
+	}, nil
+}
+
+func (store *fakePrivacyStore) blockedEitherWay(a, b int64) bool {
+	_, ab := store.blocks[[2]int64{a, b}]
+	_, ba := store.blocks[[2]int64{b, a}]
+	return ab || ba
+}
+
+func (store *fakePrivacyStore) ListBlockedEitherWay(ctx context.Context,
+	arg db.ListBlockedEitherWayParams) ([]int64, error) {
+	blocked := []int64{}
+	for _, userID := range arg.UserIds {
+		if store.blockedEitherWay(arg.UserID, userID) {
+			blocked = append(blocked, userID)
+		}
+	}
+	return blocked, nil
+}
+
+func (store *fakePrivacyStore) GetUserByID(ctx context.Context,
+	id int64) (db.User, error) {
+	return db.User{ID: id, Role: util.CustomerRole}, nil
+}
+
+func (store *fakePrivacyStore) FindDirectConversation(ctx context.Context,
+	arg db.FindDirectConversationParams) (int64, error) {
+	conversationID, ok := store.conversations[conversationKey(arg.UserID, arg.UserID_2)]
+	if !ok {
+		return 0, pgx.ErrNoRows
+	}
+	return conversationID, nil
+}
+
+func (store *fakePrivacyStore) GetOrCreateDirectConversationTx(ctx context.Context,
+	arg db.GetOrCreateDirectConversationTxParams) (db.GetOrCreateDirectConversationTxResult, error) {
+	if store.blockedEitherWay(arg.User1ID, arg.User2ID) {
+		return db.GetOrCreateDirectConversationTxResult{}, db.ErrUserBlocked
+	}
+
+	key := conversationKey(arg.User1ID, arg.User2ID)
+	if conversationID, ok := store.conversations[key]; ok {
+		return db.GetOrCreateDirectConversationTxResult{
+			Conversation: db.Conversation{ConversationsID: conversationID},
+		}, nil
+	}
+
+	store.created++
+	conversationID := int64(100 + store.created)
+	store.conversations[key] = conversationID
+	if arg.Request {
+		store.inboxes[[2]int64{conversationID, arg.User2ID}] = util.InboxRequests
+	}
+	return db.GetOrCreateDirectConversationTxResult{
+		Conversation: db.Conversation{ConversationsID: conversationID},
+		IsNew:        true,
+	}, nil
+}
+
+func (store *fakePrivacyStore) ListWebhooksForEvent(ctx context.Context,
+	arg db.ListWebhooksForEventParams) ([]db.Webhook, error) {
+	return []db.Webhook{}, nil
+}
+
+func newPrivacyTestPayload(userID int64) *token.Payload {
+	return &token.Payload{ID: uuid.New(), Username: "user", UserID: userID,
+		Role: util.CustomerRole, SessionID: uuid.New()}
+}
+
+func TestUpdatePrivacySettings(t *testing.T) {
+	store := newFakePrivacyStore()
+	server := newTestServer(t, store, nil)
+	payload := newPrivacyTestPayload(1)
+
+	send := func(method string, body any) *httptest.ResponseRecorder {
+		router := newProfileTestRouter(payload, method, "/account/privacy",
+			server.getPrivacySettings)
+		if method == http.MethodPatch {
+			router = newProfileTestRouter(payload, method, "/account/privacy",
+				server.updatePrivacySettings)
+		}
+
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+		request, err := http.NewRequest(method, "/account/privacy",
+			bytes.NewReader(data))
+		require.NoError(t, err)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	// defaults until something is saved
+	recorder := send(http.MethodGet, nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var resp privacySettingsResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	require.Equal(t, util.AudienceEveryone, resp.LastSeen)
+	require.Equal(t, util.AudienceEveryone, resp.DMPolicy)
+	require.True(t, resp.ReadReceipts)
+	require.Nil(t, resp.UpdatedAt)
+
+	// only the given fields change
+	recorder = send(http.MethodPatch, gin.H{"last_seen": "contacts",
+		"read_receipts": false})
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, util.AudienceContacts, store.settings[1].LastSeen)
+	require.Equal(t, util.AudienceEveryone, store.settings[1].Online)
+	require.False(t, store.settings[1].ReadReceipts)
+
+	recorder = send(http.MethodPatch, gin.H{"dm_policy": "nobody"})
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, util.AudienceNobody, store.settings[1].DmPolicy)
+	require.Equal(t, util.AudienceContacts, store.settings[1].LastSeen)
+
+	recorder = send(http.MethodPatch, gin.H{"online": "friends"})
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestPrivacyView(t *testing.T) {
+	store := newFakePrivacyStore()
+	server := newTestServer(t, store, nil)
+
+	// 2 added the viewer as a contact, 3 didn't
+	store.contacts[[2]int64{2, 1}] = ""
+	store.contacts[[2]int64{1, 3}] = ""
+	store.settings[2] = db.PrivacySetting{UserID: 2,
+		LastSeen: util.AudienceContacts, Online: util.AudienceNobody,
+		ReadReceipts: true, DmPolicy: util.AudienceEveryone}
+	store.settings[3] = db.PrivacySetting{UserID: 3,
+		LastSeen: util.AudienceContacts, Online: util.AudienceEveryone,
+		ReadReceipts: false, DmPolicy: util.AudienceEveryone}
+
+	view, err := server.newPrivacyView(context.Background(), 1,
+		[]int64{2, 3, 4})
+	require.NoError(t, err)
+
+	require.True(t, view.showLastSeen(2))
+	require.False(t, view.showOnline(2))
+	require.True(t, view.showReadReceipts(2))
+
+	require.False(t, view.showLastSeen(3))
+	require.True(t, view.showOnline(3))
+	require.False(t, view.showReadReceipts(3))
+
+	// no settings saved means the defaults
+	require.True(t, view.showLastSeen(4))
+	require.True(t, view.showOnline(4))
+	require.True(t, view.showReadReceipts(4))
+
+	// who hides their own read receipts doesn't see anyone's
+	store.settings[1] = db.PrivacySetting{UserID: 1,
+		LastSeen: util.AudienceNobody, Online: util.AudienceNobody,
+		ReadReceipts: false, DmPolicy: util.AudienceEveryone}
+	view, err = server.newPrivacyView(context.Background(), 1,
+		[]int64{1, 2})
+	require.NoError(t, err)
+	require.False(t, view.showReadReceipts(2))
+
+	// but always sees their own presence
+	require.True(t, view.showOnline(1))
+	require.True(t, view.showLastSeen(1))
+	require.True(t, view.showReadReceipts(1))
+}
+
+func TestDirectMessagePolicy(t *testing.T) {
+	store := newFakePrivacyStore()
+	server := newTestServer(t, store, nil)
+
+	start := func(senderID int64, path string) *httptest.ResponseRecorder {
+		router := newProfileTestRouter(newPrivacyTestPayload(senderID),
+			http.MethodPost, "/conversations/:other_user_id",
+			server.GetOrCreateDirectConversation)
+		request, err := http.NewRequest(http.MethodPost, path, nil)
+		require.NoError(t, err)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	// 2 takes messages from contacts only, 3 from nobody
+	store.settings[2] = db.PrivacySetting{UserID: 2,
+		LastSeen: util.AudienceEveryone, Online: util.AudienceEveryone,
+		ReadReceipts: true, DmPolicy: util.AudienceContacts}
+	store.settings[3] = db.PrivacySetting{UserID: 3,
+		LastSeen: util.AudienceEveryone, Online: util.AudienceEveryone,
+		ReadReceipts: true, DmPolicy: util.AudienceNobody}
+
+	// everyone by default
+	require.Equal(t, http.StatusOK, start(1, "/conversations/4").Code)
+	require.Equal(t, 1, store.created)
+
+	require.Equal(t, http.StatusForbidden, start(1, "/conversations/2").Code)
+	require.Equal(t, http.StatusForbidden, start(1, "/conversations/3").Code)
+	require.Equal(t, 1, store.created)
+
+	// until 2 adds 1 as a contact
+	store.contacts[[2]int64{2, 1}] = ""
+	recorder := start(1, "/conversations/2")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, 2, store.created)
+
+	// an existing conversation can be reopened whatever the policy
+	store.conversations[conversationKey(1, 3)] = 51
+	recorder = start(1, "/conversations/3")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var resp map[string]any
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	require.Equal(t, float64(51), resp["conversation_id"])
+	require.Equal(t, false, resp["is_new"])
+	require.Equal(t, 2, store.created)
+}
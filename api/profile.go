@@ -0,0 +1,441 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/storage"
+	"github.com/kratos069/message-app/token"
+	"github.com/kratos069/message-app/util"
+	"github.com/kratos069/message-app/worker"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultUsernameCooldown = 30 * 24 * time.Hour
+	avatarUploadField       = "avatar"
+	avatarUploadIDLength    = 16
+)
+
+var (
+	errUsernameTaken    = errors.New("username is already taken")
+	errUsernameCooldown = errors.New("username was changed recently, try again later")
+	errAvatarNotFound   = errors.New("avatar not found")
+)
+
+var errProfileNotFound = errors.New("user not found")
+
+// what every user may see about any other user
+type publicProfileResponse struct {
+	UserID            int64      `json:"user_id"`
+	Username          string     `json:"username"`
+	ProfilePictureUrl *string    `json:"profile_picture_url"`
+	IsOnline          bool       `json:"is_online"`
+	LastSeenAt        *time.Time `json:"last_seen_at"`
+	IsBot             bool       `json:"is_bot"`
+}
+
+func newPublicProfileResponse(
+	profile db.ListPublicProfilesRow) publicProfileResponse {
+	return publicProfileResponse{
+		UserID:            profile.ID,
+		Username:          profile.Username,
+		ProfilePictureUrl: textPtr(profile.ProfilePictureUrl),
+		IsOnline:          profile.IsOnline,
+		LastSeenAt:        timestamptzPtr(profile.LastSeenAt),
+		IsBot:             profile.IsBot,
+	}
+}
+
+// everything about the caller's own account, secrets excluded
+type meResponse struct {
+	UserID              int64      `json:"user_id"`
+	Username            string     `json:"username"`
+	Email               string     `json:"email"`
+	IsEmailVerified     bool       `json:"is_email_verified"`
+	Role                string     `json:"role"`
+	ProfilePictureUrl   *string    `json:"profile_picture_url"`
+	IsOnline            bool       `json:"is_online"`
+	LastSeenAt          *time.Time `json:"last_seen_at"`
+	UsernameChangedAt   *time.Time `json:"username_changed_at"`
+	DeletionRequestedAt *time.Time `json:"deletion_requested_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+}
+
+func newMeResponse(user db.User) meResponse {
+	return meResponse{
+		UserID:              user.ID,
+		Username:            user.Username,
+		Email:               user.Email,
+		IsEmailVerified:     user.IsEmailVerified,
+		Role:                user.Role,
+		ProfilePictureUrl:   textPtr(user.ProfilePictureUrl),
+		IsOnline:            user.IsOnline,
+		LastSeenAt:          timestamptzPtr(user.LastSeenAt),
+		UsernameChangedAt:   timestamptzPtr(user.UsernameChangedAt),
+		DeletionRequestedAt: timestamptzPtr(user.DeletionRequestedAt),
+		CreatedAt:           user.CreatedAt,
+	}
+}
+
+// GetMe returns the full details of the caller's account
+func (server *Server) getMe(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	user, err := server.store.GetUserByID(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newMeResponse(user))
+}
+
+type inputUserID struct {
+	UserID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// GetUserByID returns the public profile of any user
+func (server *Server) getUserByID(ctx *gin.Context) {
+	var input inputUserID
+	if err := ctx.ShouldBindUri(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	profiles, err := server.store.ListPublicProfiles(ctx, []int64{input.UserID})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	if len(profiles) == 0 {
+		ctx.JSON(http.StatusNotFound, errResponse(errProfileNotFound))
+		return
+	}
+
+	resp, err := server.publicProfiles(ctx, profiles)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp[0])
+}
+
+type lookupUsersRequest struct {
+	UserIDs []int64 `json:"user_ids" binding:"required,min=1,max=100,dive,min=1"`
+}
+
+// LookupUsers returns the public profiles of up to 100 users at once,
+// unknown ids are left out
+func (server *Server) lookupUsers(ctx *gin.Context) {
+	var req lookupUsersRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	profiles, err := server.store.ListPublicProfiles(ctx, req.UserIDs)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	resp, err := server.publicProfiles(ctx, profiles)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"users": resp,
+		"count": len(resp),
+	})
+}
+
+type updateProfileRequest struct {
+	Username string `json:"username" binding:"required,min=3,max=50,alphanum"`
+}
+
+// UpdateProfile changes the username. Tokens carry the username, so
+// every session is logged out and the caller gets a new one.
+func (server *Server) updateProfile(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	var req updateProfileRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	user, err := server.store.GetUserByID(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	if req.Username == user.Username {
+		ctx.JSON(http.StatusBadRequest,
+			gin.H{"error": "new username is the same as the current one"})
+		return
+	}
+
+	if user.UsernameChangedAt.Valid {
+		wait := time.Until(user.UsernameChangedAt.Time.Add(server.usernameCooldown()))
+		if wait > 0 {
+			tooManyRequests(ctx, errUsernameCooldown, wait)
+			return
+		}
+	}
+
+	_, err = server.store.GetUserByUsername(ctx, req.Username)
+	if err == nil {
+		ctx.JSON(http.StatusConflict, errResponse(errUsernameTaken))
+		return
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	user, err = server.store.UpdateUserProfile(ctx, db.UpdateUserProfileParams{
+		ID:       user.ID,
+		Username: pgtype.Text{String: req.Username, Valid: true},
+	})
+	if err != nil {
+		// taken in the meantime
+		if isDuplicateKeyError(err) {
+			ctx.JSON(http.StatusConflict, errResponse(errUsernameTaken))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	// sessions follow the rename, the tokens issued for them don't
+	err = server.store.BlockUserSessions(ctx, user.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	err = server.sessionGuard.revokeUser(ctx, user.ID, server.revocationTTL())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	resp, err := server.startSession(ctx, user)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	log.Info().Int64("user_id", user.ID).Str("username", user.Username).
+		Msg("Username changed")
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// UploadAvatar accepts a JPEG, PNG or GIF image, the thumbnails are made
+// in the background and replace the profile picture when ready
+func (server *Server) uploadAvatar(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	// room for the multipart headers around the file
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body,
+		worker.MaxAvatarSize+64<<10)
+
+	fileHeader, err := ctx.FormFile(avatarUploadField)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			ctx.JSON(http.StatusRequestEntityTooLarge, errResponse(worker.ErrAvatarTooBig))
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+	if fileHeader.Size > worker.MaxAvatarSize {
+		ctx.JSON(http.StatusRequestEntityTooLarge, errResponse(worker.ErrAvatarTooBig))
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+	defer file.Close()
+
+	// the content decides, not the file name or content type
+	err = worker.ValidateAvatar(file)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, worker.ErrAvatarTooBig) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		ctx.JSON(status, errResponse(err))
+		return
+	}
+
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	uploadID := util.RandomString(avatarUploadIDLength)
+	originalKey := worker.AvatarOriginalKey(authPayload.UserID, uploadID)
+
+	err = server.files.Put(ctx, originalKey, file)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	// tasks can finish out of order, only the latest upload gets applied
+	err = server.store.SetAvatarUploadID(ctx, db.SetAvatarUploadIDParams{
+		UploadID: pgtype.Text{String: uploadID, Valid: true},
+		ID:       authPayload.UserID,
+	})
+	if err != nil {
+		server.deleteAvatarUpload(ctx, originalKey)
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	opts := []asynq.Option{
+		asynq.MaxRetry(3),
+		asynq.Queue(worker.QueueDefault),
+	}
+
+	err = server.taskDistributor.DistributeTaskProcessAvatar(ctx,
+		&worker.PayloadProcessAvatar{
+			UserID:   authPayload.UserID,
+			UploadID: uploadID,
+		}, opts...)
+	if err != nil {
+		server.deleteAvatarUpload(ctx, originalKey)
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{
+		"upload_id": uploadID,
+		"status":    "processing",
+	})
+}
+
+type avatarRequest struct {
+	UserID   int64  `uri:"user_id" binding:"required,min=1"`
+	UploadID string `uri:"upload_id" binding:"required,alphanum"`
+	File     string `uri:"file" binding:"required"`
+}
+
+// GetAvatar serves an avatar thumbnail, the upload id
+// in the path is random so they can be cached forever
+func (server *Server) getAvatar(ctx *gin.Context) {
+	var uri avatarRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusNotFound, errResponse(errAvatarNotFound))
+		return
+	}
+
+	key, ok := avatarThumbnailKey(uri.UserID, uri.UploadID, uri.File)
+	if !ok {
+		ctx.JSON(http.StatusNotFound, errResponse(errAvatarNotFound))
+		return
+	}
+
+	file, err := server.files.Open(ctx, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			ctx.JSON(http.StatusNotFound, errResponse(errAvatarNotFound))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	defer file.Close()
+
+	ctx.Header("Content-Type", "image/png")
+	ctx.Header("Cache-Control", "public, max-age=31536000, immutable")
+	ctx.Status(http.StatusOK)
+
+	_, err = io.Copy(ctx.Writer, file)
+	if err != nil {
+		log.Error().Err(err).Str("key", key).Msg("Failed to send avatar")
+	}
+}
+
+// ==========================================================================
+// ================================Helper====================================
+// ==========================================================================
+
+// the profiles as the caller may see them
+func (server *Server) publicProfiles(ctx *gin.Context,
+	profiles []db.ListPublicProfilesRow) ([]publicProfileResponse, error) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	userIDs := make([]int64, 0, len(profiles))
+	for _, profile := range profiles {
+		userIDs = append(userIDs, profile.ID)
+	}
+
+	view, err := server.newPrivacyView(ctx, authPayload.UserID, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]publicProfileResponse, 0, len(profiles))
+	for _, profile := range profiles {
+		profileResp := newPublicProfileResponse(profile)
+		view.applyToProfile(&profileResp)
+		resp = append(resp, profileResp)
+	}
+
+	return resp, nil
+}
+
+func textPtr(value pgtype.Text) *string {
+	if !value.Valid {
+		return nil
+	}
+	return &value.String
+}
+
+func (server *Server) usernameCooldown() time.Duration {
+	if server.config.UsernameCooldown == 0 {
+		return defaultUsernameCooldown
+	}
+	return server.config.UsernameCooldown
+}
+
+// removes an original the worker won't get to
+func (server *Server) deleteAvatarUpload(ctx context.Context, key string) {
+	err := server.files.Delete(ctx, key)
+	if err != nil {
+		log.Error().Err(err).Str("key", key).Msg("Failed to delete avatar upload")
+	}
+}
+
+// only thumbnails are served, never the original upload
+func avatarThumbnailKey(userID int64, uploadID, file string) (string, bool) {
+	for _, size := range worker.AvatarSizes {
+		if file == strconv.Itoa(size)+".png" {
+			return worker.AvatarThumbnailKey(userID, uploadID, size), true
+		}
+	}
+
+	return "", false
+}
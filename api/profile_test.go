@@ -0,0 +1,523 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/storage"
+	"github.com/kratos069/message-app/token"
+	"github.com/kratos069/message-app/util"
+	"github.com/kratos069/message-app/worker"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProfileStore keeps users in memory
+type fakeProfileStore struct {
+	db.Store
+	users    map[int64]db.User
+	sessions []db.CreateSessionParams
+	searches []db.SearchUsersParams
+}
+
+func (store *fakeProfileStore) GetUserByID(ctx context.Context,
+	id int64) (db.User, error) {
+	user, ok := store.users[id]
+	if !ok {
+		return user, pgx.ErrNoRows
+	}
+	return user, nil
+}
+
+func (store *fakeProfileStore) GetUserByUsername(ctx context.Context,
+	username string) (db.User, error) {
+	for _, user := range store.users {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+	return db.User{}, pgx.ErrNoRows
+}
+
+func (store *fakeProfileStore) UpdateUserProfile(ctx context.Context,
+	arg db.UpdateUserProfileParams) (db.User, error) {
+	user := store.users[arg.ID]
+	if arg.Username.Valid && arg.Username.String != user.Username {
+		user.Username = arg.Username.String
+		user.UsernameChangedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	}
+	store.users[arg.ID] = user
+	return user, nil
+}
+
+func (store *fakeProfileStore) ListPublicProfiles(ctx context.Context,
+	ids []int64) ([]db.ListPublicProfilesRow, error) {
+	profiles := []db.ListPublicProfilesRow{}
+	for _, id := range ids {
+		user, ok := store.users[id]
+		if !ok || user.DeletionRequestedAt.Valid {
+			continue
+		}
+		profiles = append(profiles, db.ListPublicProfilesRow{
+			ID:                user.ID,
+			Username:          user.Username,
+			ProfilePictureUrl: user.ProfilePictureUrl,
+			IsOnline:          user.IsOnline,
+			LastSeenAt:        user.LastSeenAt,
+			IsBot:             user.IsBot,
+		})
+	}
+	// like ORDER BY id
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].ID < profiles[j].ID })
+	return profiles, nil
+}
+
+// nobody changed their privacy settings
+func (store *fakeProfileStore) ListPrivacySettings(ctx context.Context,
+	userIds []int64) ([]db.PrivacySetting, error) {
+	return []db.PrivacySetting{}, nil
+}
+
+func (store *fakeProfileStore) ListContactOwnerIDs(ctx context.Context,
+	arg db.ListContactOwnerIDsParams) ([]int64, error) {
+	return []int64{}, nil
+}
+
+func (store *fakeProfileStore) ListBlockedEitherWay(ctx context.Context,
+	arg db.ListBlockedEitherWayParams) ([]int64, error) {
+	return []int64{}, nil
+}
+
+func (store *fakeProfileStore) ListPendingRequestUserIDs(ctx context.Context,
+	arg db.ListPendingRequestUserIDsParams) ([]int64, error) {
+	return []int64{}, nil
+}
+
+func (store *fakeProfileStore) BlockUserSessions(ctx context.Context,
+	username string) error {
+	return nil
+}
+
+func (store *fakeProfileStore) CreateSession(ctx context.Context,
+	arg db.CreateSessionParams) (db.Session, error) {
+	store.sessions = append(store.sessions, arg)
+	return db.Session{ID: arg.ID, Username: arg.Username}, nil
+}
+
+func (store *fakeProfileStore) MarkUserOnline(ctx context.Context,
+	id int64) error {
+	user := store.users[id]
+	user.IsOnline = true
+	store.users[id] = user
+	return nil
+}
+
+func (store *fakeProfileStore) SetAvatarUploadID(ctx context.Context,
+	arg db.SetAvatarUploadIDParams) error {
+	user := store.users[arg.ID]
+	user.AvatarUploadID = arg.UploadID
+	store.users[arg.ID] = user
+	return nil
+}
+
+// fakeAvatarDistributor records avatar tasks
+type fakeAvatarDistributor struct {
+	worker.TaskDistributor
+	avatars []worker.PayloadProcessAvatar
+}
+
+func (distributor *fakeAvatarDistributor) DistributeTaskProcessAvatar(
+	ctx context.Context, payload *worker.PayloadProcessAvatar,
+	opts ...asynq.Option) error {
+	distributor.avatars = append(distributor.avatars, *payload)
+	return nil
+}
+
+// routes the handler with the payload set, like authMiddleware does
+func newProfileTestRouter(payload *token.Payload, method, path string,
+	handler gin.HandlerFunc) *gin.Engine {
+	router := gin.New()
+	router.Handle(method, path, func(ctx *gin.Context) {
+		ctx.Set(authorizationPayloadKey, payload)
+		handler(ctx)
+	})
+	return router
+}
+
+func newFakeProfileStore() *fakeProfileStore {
+	return &fakeProfileStore{
+		users: map[int64]db.User{
+			1: {ID: 1, Username: "alice", Email: "alice@example.com",
+				Role: util.CustomerRole, PasswordHash: "hash"},
+			2: {ID: 2, Username: "bob", Email: "bob@example.com",
+				Role: util.CustomerRole, IsOnline: true},
+			3: {ID: 3, Username: "carol", Email: "carol@example.com",
+				Role:                util.CustomerRole,
+				DeletionRequestedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true}},
+		},
+	}
+}
+
+func TestGetUserByID(t *testing.T) {
+	server := newTestServer(t, newFakeProfileStore(), nil)
+
+	payload := &token.Payload{ID: uuid.New(), Username: "alice", UserID: 1,
+		Role: util.CustomerRole, SessionID: uuid.New()}
+	router := newProfileTestRouter(payload, http.MethodGet, "/users/:id",
+		server.getUserByID)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		request, err := http.NewRequest(http.MethodGet, path, nil)
+		require.NoError(t, err)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	// someone else's profile, without private fields
+	recorder := get("/users/2")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var profile map[string]any
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &profile))
+	require.Equal(t, "bob", profile["username"])
+	require.Equal(t, true, profile["is_online"])
+	require.NotContains(t, profile, "email")
+	require.NotContains(t, profile, "role")
+
+	// accounts waiting for deletion are gone already
+	require.Equal(t, http.StatusNotFound, get("/users/3").Code)
+	require.Equal(t, http.StatusNotFound, get("/users/99").Code)
+	require.Equal(t, http.StatusBadRequest, get("/users/abc").Code)
+}
+
+func TestGetMe(t *testing.T) {
+	server := newTestServer(t, newFakeProfileStore(), nil)
+
+	payload := &token.Payload{ID: uuid.New(), Username: "alice", UserID: 1,
+		Role: util.CustomerRole, SessionID: uuid.New()}
+	router := newProfileTestRouter(payload, http.MethodGet, "/users/me",
+		server.getMe)
+
+	request, err := http.NewRequest(http.MethodGet, "/users/me", nil)
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var me map[string]any
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &me))
+	require.Equal(t, "alice@example.com", me["email"])
+	require.Equal(t, util.CustomerRole, me["role"])
+	require.NotContains(t, me, "password_hash")
+}
+
+func TestLookupUsers(t *testing.T) {
+	testCases := []struct {
+		name          string
+		body          any
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"user_ids": []int64{2, 1, 3, 99}},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp struct {
+					Users []publicProfileResponse `json:"users"`
+					Count int                     `json:"count"`
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				require.Equal(t, 2, resp.Count)
+				require.Equal(t, "alice", resp.Users[0].Username)
+				require.Equal(t, "bob", resp.Users[1].Username)
+			},
+		},
+		{
+			name: "Empty",
+			body: gin.H{"user_ids": []int64{}},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "TooMany",
+			body: gin.H{"user_ids": make([]int64, 101)},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidID",
+			body: gin.H{"user_ids": []int64{1, 0}},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			server := newTestServer(t, newFakeProfileStore(), nil)
+
+			payload := &token.Payload{ID: uuid.New(), Username: "alice", UserID: 1,
+				Role: util.CustomerRole, SessionID: uuid.New()}
+			router := newProfileTestRouter(payload, http.MethodPost, "/users/lookup",
+				server.lookupUsers)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/lookup",
+				bytes.NewReader(data))
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+	recently := pgtype.Timestamptz{Time: time.Now().Add(-time.Hour), Valid: true}
+	longAgo := pgtype.Timestamptz{Time: time.Now().Add(-60 * 24 * time.Hour), Valid: true}
+
+	testCases := []struct {
+		name          string
+		changedAt     pgtype.Timestamptz
+		username      string
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder,
+			store *fakeProfileStore)
+	}{
+		{
+			name:     "OK",
+			username: "alice2",
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder,
+				store *fakeProfileStore) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "alice2", store.users[1].Username)
+
+				// a new session under the new name
+				var resp loginUserResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				require.NotEmpty(t, resp.AccessToken)
+				require.Equal(t, "alice2", resp.User.Username)
+				require.Len(t, store.sessions, 1)
+				require.Equal(t, "alice2", store.sessions[0].Username)
+			},
+		},
+		{
+			name:      "CooldownOver",
+			changedAt: longAgo,
+			username:  "alice2",
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder,
+				store *fakeProfileStore) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "alice2", store.users[1].Username)
+			},
+		},
+		{
+			name:      "Cooldown",
+			changedAt: recently,
+			username:  "alice2",
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder,
+				store *fakeProfileStore) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				require.NotEmpty(t, recorder.Header().Get("Retry-After"))
+				require.Equal(t, "alice", store.users[1].Username)
+			},
+		},
+		{
+			name:     "Taken",
+			username: "bob",
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder,
+				store *fakeProfileStore) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				require.Equal(t, "alice", store.users[1].Username)
+			},
+		},
+		{
+			name:     "SameUsername",
+			username: "alice",
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder,
+				store *fakeProfileStore) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "InvalidUsername",
+			username: "no spaces",
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder,
+				store *fakeProfileStore) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Equal(t, "alice", store.users[1].Username)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			store := &fakeProfileStore{
+				users: map[int64]db.User{
+					1: {ID: 1, Username: "alice", Role: util.CustomerRole,
+						UsernameChangedAt: tc.changedAt},
+					2: {ID: 2, Username: "bob", Role: util.CustomerRole},
+				},
+			}
+			server := newTestServer(t, store, nil)
+
+			payload := &token.Payload{ID: uuid.New(), Username: "alice", UserID: 1,
+				Role: util.CustomerRole, SessionID: uuid.New()}
+			router := newProfileTestRouter(payload, http.MethodPatch,
+				"/account/profile", server.updateProfile)
+
+			data, err := json.Marshal(gin.H{"username": tc.username})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPatch, "/account/profile",
+				bytes.NewReader(data))
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, store)
+		})
+	}
+}
+
+func TestUploadAvatar(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 10, 20))
+	img.Set(1, 1, color.RGBA{R: 255, A: 255})
+	var pngData bytes.Buffer
+	require.NoError(t, png.Encode(&pngData, img))
+
+	testCases := []struct {
+		name          string
+		content       []byte
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder,
+			store *fakeProfileStore, distributor *fakeAvatarDistributor,
+			files storage.FileStore)
+	}{
+		{
+			name:    "OK",
+			content: pngData.Bytes(),
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder,
+				store *fakeProfileStore, distributor *fakeAvatarDistributor,
+				files storage.FileStore) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+				require.Len(t, distributor.avatars, 1)
+				require.Equal(t, int64(1), distributor.avatars[0].UserID)
+				// older uploads still processing can't replace this one
+				require.Equal(t, distributor.avatars[0].UploadID,
+					store.users[1].AvatarUploadID.String)
+
+				// the original waits for the worker
+				file, err := files.Open(context.Background(),
+					worker.AvatarOriginalKey(1, distributor.avatars[0].UploadID))
+				require.NoError(t, err)
+				require.NoError(t, file.Close())
+			},
+		},
+		{
+			name:    "NotAnImage",
+			content: []byte("<?php echo 'hi'; ?>"),
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder,
+				store *fakeProfileStore, distributor *fakeAvatarDistributor,
+				files storage.FileStore) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Empty(t, distributor.avatars)
+			},
+		},
+		{
+			name:    "TooLarge",
+			content: append(pngData.Bytes(), make([]byte, worker.MaxAvatarSize)...),
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder,
+				store *fakeProfileStore, distributor *fakeAvatarDistributor,
+				files storage.FileStore) {
+				require.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+				require.Empty(t, distributor.avatars)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			store := newFakeProfileStore()
+			distributor := &fakeAvatarDistributor{}
+			server := newTestServer(t, store, distributor)
+			server.files = storage.NewLocalFileStore(t.TempDir())
+
+			payload := &token.Payload{ID: uuid.New(), Username: "alice", UserID: 1,
+				Role: util.CustomerRole, SessionID: uuid.New()}
+			router := newProfileTestRouter(payload, http.MethodPost,
+				"/account/avatar", server.uploadAvatar)
+
+			var body bytes.Buffer
+			writer := multipart.NewWriter(&body)
+			part, err := writer.CreateFormFile(avatarUploadField, "avatar.png")
+			require.NoError(t, err)
+			_, err = part.Write(tc.content)
+			require.NoError(t, err)
+			require.NoError(t, writer.Close())
+
+			request, err := http.NewRequest(http.MethodPost, "/account/avatar", &body)
+			require.NoError(t, err)
+			request.Header.Set("Content-Type", writer.FormDataContentType())
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, store, distributor, server.files)
+		})
+	}
+}
+
+func TestGetAvatar(t *testing.T) {
+	server := newTestServer(t, &fakeProfileStore{}, nil)
+	server.files = storage.NewLocalFileStore(t.TempDir())
+
+	ctx := context.Background()
+	require.NoError(t, server.files.Put(ctx, worker.AvatarThumbnailKey(1, "abc", 64),
+		bytes.NewReader([]byte("thumbnail"))))
+	require.NoError(t, server.files.Put(ctx, worker.AvatarOriginalKey(1, "abc"),
+		bytes.NewReader([]byte("original"))))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		request, err := http.NewRequest(http.MethodGet, path, nil)
+		require.NoError(t, err)
+		recorder := httptest.NewRecorder()
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := get("/" + worker.AvatarThumbnailKey(1, "abc", 64))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "thumbnail", recorder.Body.String())
+	require.Equal(t, "image/png", recorder.Header().Get("Content-Type"))
+
+	// the original upload isn't public
+	require.Equal(t, http.StatusNotFound, get("/avatars/1/abc/original").Code)
+	require.Equal(t, http.StatusNotFound, get("/avatars/1/abc/256.png").Code)
+	require.Equal(t, http.StatusNotFound, get("/avatars/1/..%2F..%2Fx/64.png").Code)
+}
@@ -5,13 +5,20 @@ import (
 	"fmt"
 	"net/http"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/events"
+	"github.com/kratos069/message-app/limiter"
+	"github.com/kratos069/message-app/presence"
+	"github.com/kratos069/message-app/storage"
 	"github.com/kratos069/message-app/token"
+	"github.com/kratos069/message-app/typing"
 	"github.com/kratos069/message-app/util"
 	"github.com/kratos069/message-app/worker"
+	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog/log"
 )
 
@@ -20,15 +27,31 @@ type Server struct {
 	config          util.Config
 	store           db.Store
 	tokenMaker      token.Maker
+	tokenKeys       tokenKeySource
 	router          *gin.Engine
 	server          *http.Server
 	taskDistributor worker.TaskDistributor
+	redisClient     *redis.Client
+	sessionGuard    *sessionGuard
+	accountPolicy   accountPolicy
+	loginLimiter    limiter.LoginLimiter
+	// messages posted through each incoming webhook
+	incomingWebhookLimiter limiter.RateLimiter
+	oidc                   *oidcClient
+	oidcFlows              oidcFlowStore
+	// data export archives and avatars
+	files storage.FileStore
+	// who is online, Users.is_online follows it
+	presence presence.Tracker
+	typing   typing.Store
+	// conversation events for whichever instance holds the recipients
+	events events.Bus
 }
 
 // Creates HTTP server and Setup Routing
 func NewServer(config util.Config, store db.Store,
 	taskDistributor worker.TaskDistributor) (*Server, error) {
-	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
+	tokenMaker, tokenKeys, err := newTokenMaker(config)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create token maker: %w", err)
 	}
@@ -50,11 +73,52 @@ func NewServer(config util.Config, store db.Store,
 		config:          config,
 		store:           store,
 		tokenMaker:      tokenMaker,
+		tokenKeys:       tokenKeys,
 		taskDistributor: taskDistributor,
+		accountPolicy:   newAccountPolicy(config.UnverifiedAccess),
+		oidc:            newOIDCClient(config),
+		files:           storage.NewLocalFileStore(config.FileStorageDir),
 	}
 
+	accountLimits := limiterAccountPolicy(config)
+	ipLimits := limiter.DefaultIPPolicy()
+
+	// revocations, login attempts, SSO flows, presence, typing and events
+	// are shared through redis when available, otherwise they only apply
+	// to this instance
+	var revocations token.RevocationList
+	if config.RedisAddress != "" {
+		server.redisClient = redis.NewClient(&redis.Options{
+			Addr: config.RedisAddress,
+		})
+		revocations = token.NewRedisRevocationList(server.redisClient)
+		server.loginLimiter = limiter.NewRedisLoginLimiter(server.redisClient,
+			accountLimits, ipLimits)
+		server.oidcFlows = newRedisOIDCFlowStore(server.redisClient)
+		server.incomingWebhookLimiter = limiter.NewRedisRateLimiter(
+			server.redisClient, incomingWebhookRate(config))
+		server.presence = presence.NewRedisTracker(server.redisClient,
+			server.presenceTTL())
+		server.typing = typing.NewRedisStore(server.redisClient, typingTTL)
+		server.events = events.NewRedisBus(server.redisClient)
+	} else {
+		revocations = token.NewMemoryRevocationList()
+		server.loginLimiter = limiter.NewMemoryLoginLimiter(accountLimits,
+			ipLimits)
+		server.oidcFlows = newMemoryOIDCFlowStore()
+		server.incomingWebhookLimiter = limiter.NewMemoryRateLimiter(
+			incomingWebhookRate(config))
+		server.presence = presence.NewMemoryTracker(server.presenceTTL())
+		server.typing = typing.NewMemoryStore(typingTTL)
+		server.events = events.NewMemoryBus()
+	}
+	server.sessionGuard = newSessionGuard(store, revocations, config.AuthCacheTTL)
+
 	// Routes
-	server.setupRoutes()
+	err = server.setupRoutes()
+	if err != nil {
+		return nil, fmt.Errorf("cannot set up routes: %w", err)
+	}
 
 	// HTTP server with timeouts
 	server.server = &http.Server{
@@ -69,9 +133,16 @@ func NewServer(config util.Config, store db.Store,
 	return server, nil
 }
 
-func (server *Server) setupRoutes() {
+func (server *Server) setupRoutes() error {
 	router := gin.Default()
 
+	// ClientIP, which login attempts are limited by, only believes
+	// forwarding headers from these proxies, from none unless configured
+	err := router.SetTrustedProxies(trustedProxies(server.config))
+	if err != nil {
+		return err
+	}
+
 	// Track active requests
 	router.Use(ActiveRequestsMiddleware())
 
@@ -86,40 +157,119 @@ func (server *Server) setupRoutes() {
 	// routes
 
 	// public
+	router.GET("/.well-known/token-keys", server.publicKeys)
 	router.POST("/register", server.register)
 	router.POST("/login", server.loginUser)
+	router.POST("/login/mfa", server.verifyMFA)
+
+	// Single sign-on through an OpenID Connect provider
+	router.GET("/oauth/login", server.oidcLogin)
+	router.GET("/oauth/callback", server.oidcCallback)
 
 	// Email verification (public - accessed via email link)
 	router.GET("/verify_email", server.VerifyEmail)
 	router.POST("/resend_verification", server.ResendVerificationEmail)
 
+	// Password reset (public - must not reveal whether an account exists)
+	router.POST("/forgot_password", server.forgotPassword)
+	router.POST("/reset_password", server.resetPassword)
+
+	// integrations post here, the token is the credential
+	router.POST(incomingWebhookPathPrefix+":token", server.postIncomingWebhook)
+
+	// Data export download (public - accessed via email link)
+	router.GET(dataExportPathPrefix+":token", server.downloadDataExport)
+
+	// avatars are linked from profiles, anyone can load them
+	router.GET(worker.AvatarURLPrefix+":user_id/:upload_id/:file", server.getAvatar)
+
 	// for both users and admins
 	authRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker,
+		server.sessionGuard, server.accountPolicy,
 		[]string{util.AdminRole, util.CustomerRole}))
 	authRoutes.POST("/logout", server.logoutUser)
+	authRoutes.POST("/account/password", server.changePassword)
+	authRoutes.POST("/account/email", server.changeEmail)
+	authRoutes.POST("/account/2fa/enroll", server.enrollTwoFactor)
+	authRoutes.POST("/account/2fa/confirm", server.confirmTwoFactor)
+	authRoutes.POST("/account/2fa/disable", server.disableTwoFactor)
+	authRoutes.POST("/account/delete", server.deleteAccount)
+	authRoutes.POST("/account/export", server.requestDataExport)
+	authRoutes.GET("/account/exports", server.listDataExports)
+	authRoutes.PATCH("/account/profile", server.updateProfile)
+	authRoutes.POST("/account/avatar", server.uploadAvatar)
+	authRoutes.GET("/account/privacy", server.getPrivacySettings)
+	authRoutes.PATCH("/account/privacy", server.updatePrivacySettings)
+	authRoutes.GET("/account/blocks", server.listBlockedUsers)
+	authRoutes.POST("/account/blocks/:user_id", server.blockUser)
+	authRoutes.DELETE("/account/blocks/:user_id", server.unblockUser)
+	authRoutes.GET("/users/me", server.getMe)
 	authRoutes.GET("/users/:id", server.getUserByID)
+	authRoutes.POST("/users/lookup", server.lookupUsers)
 	authRoutes.POST("/users/search", server.SearchUsers)
 
+	authRoutes.POST("/presence/heartbeat", server.heartbeat)
+	authRoutes.POST("/presence/query", server.queryPresence)
+
+	authRoutes.GET("/contacts", server.listContacts)
+	authRoutes.PUT("/contacts/:user_id", server.addContact)
+	authRoutes.DELETE("/contacts/:user_id", server.removeContact)
+
 	authRoutes.GET("/conversations", server.listConversations)
 	authRoutes.POST(
 		"/conversations/:other_user_id",
 		server.GetOrCreateDirectConversation)
 	authRoutes.GET("/conversations/:id", server.getConversation)
+	authRoutes.POST("/message_requests/:conversation_id/accept",
+		server.acceptMessageRequest)
+	authRoutes.POST("/message_requests/:conversation_id/decline",
+		server.declineMessageRequest)
+	authRoutes.POST("/message_requests/:conversation_id/block",
+		server.blockMessageRequest)
 	authRoutes.GET("/debug/:conversation_id", server.debugConversation)
 
 	authRoutes.GET("/messages/:conversation_id", server.getMessages)
 	authRoutes.POST("/messages/:conversation_id", server.sendMessage)
+	authRoutes.POST("/messages/:conversation_id/read", server.markMessagesRead)
+
+	authRoutes.GET("/typing/:conversation_id", server.getTypingUsers)
+	authRoutes.POST("/typing/:conversation_id", server.startTyping)
+	authRoutes.DELETE("/typing/:conversation_id", server.stopTyping)
+
+	// bots are managed by their owners and admins
+	authRoutes.POST("/bots", server.createBot)
+	authRoutes.GET("/bots", server.listBots)
+	authRoutes.POST("/bots/:bot_id/keys", server.createAPIKey)
+	authRoutes.GET("/bots/:bot_id/keys", server.listAPIKeys)
+	authRoutes.POST("/bots/:bot_id/keys/:key_id/rotate", server.rotateAPIKey)
+	authRoutes.DELETE("/bots/:bot_id/keys/:key_id", server.revokeAPIKey)
+
+	// webhooks are managed by their owners and admins
+	authRoutes.POST("/webhooks", server.createWebhook)
+	authRoutes.GET("/webhooks", server.listWebhooks)
+	authRoutes.POST("/webhooks/:webhook_id/test", server.testWebhook)
+	authRoutes.POST("/webhooks/:webhook_id/disable", server.disableWebhook)
+	authRoutes.GET("/webhooks/:webhook_id/deliveries", server.listWebhookDeliveries)
+
+	authRoutes.POST("/incoming_webhooks/:conversation_id", server.createIncomingWebhook)
+	authRoutes.GET("/incoming_webhooks/:conversation_id", server.listIncomingWebhooks)
+	authRoutes.DELETE("/incoming_webhooks/:conversation_id/:hook_id",
+		server.revokeIncomingWebhook)
 
 	// for only admins
 	adminRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker,
-		[]string{util.AdminRole}))
+		server.sessionGuard, server.accountPolicy, []string{util.AdminRole}))
 	adminRoutes.GET("/admin", server.listUsers)
 	adminRoutes.GET("/admin/:user_id", server.getUser)
 	adminRoutes.POST("/admin/ban/:user_id", server.banUser)
 	adminRoutes.POST("/admin/unban/:user_id", server.unbanUser)
+	adminRoutes.POST("/admin/unlock/:user_id", server.unlockUser)
+	adminRoutes.POST("/admin/role/:user_id", server.changeUserRole)
+	adminRoutes.GET("/admin/audit/roles", server.listRoleAuditLogs)
 	adminRoutes.GET("/admin/stats", server.getStats)
 
 	server.router = router
+	return nil
 }
 
 // Starts and runs HTTP server on a specific address
@@ -138,6 +288,13 @@ func (server *Server) Shutdown(ctx context.Context) error {
 	}
 
 	log.Info().Msg("All active connections closed")
+
+	if server.redisClient != nil {
+		if err := server.redisClient.Close(); err != nil {
+			return fmt.Errorf("redis client close failed: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -145,6 +302,24 @@ func errResponse(err error) gin.H {
 	return gin.H{"error": err.Error()}
 }
 
+// revocations must outlive every token they apply to
+func (server *Server) revocationTTL() time.Duration {
+	return max(server.config.AccessTokenDuration,
+		server.config.RefreshTokenDuration)
+}
+
+// TRUSTED_PROXIES is a comma separated list of addresses or CIDRs
+func trustedProxies(config util.Config) []string {
+	var proxies []string
+	for _, proxy := range strings.Split(config.TrustedProxies, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+
+	return proxies
+}
+
 func parseInt32(s string, defaultVal int32) int32 {
 	var val int32
 	if _, err := fmt.Sscanf(s, "%d", &val); err != nil {
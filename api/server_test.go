@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kratos069/message-app/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrustedProxies(t *testing.T) {
+	clientIP := func(trusted string) string {
+		server, err := NewServer(util.Config{
+			TokenSymmetricKey:   util.RandomString(32),
+			AccessTokenDuration: time.Minute,
+			TrustedProxies:      trusted,
+		}, nil, nil)
+		require.NoError(t, err)
+
+		server.router.GET("/ip", func(ctx *gin.Context) {
+			ctx.String(http.StatusOK, ctx.ClientIP())
+		})
+		request, err := http.NewRequest(http.MethodGet, "/ip", nil)
+		require.NoError(t, err)
+		request.RemoteAddr = "10.0.0.1:4000"
+		request.Header.Set("X-Forwarded-For", "203.0.113.7")
+
+		recorder := httptest.NewRecorder()
+		server.router.ServeHTTP(recorder, request)
+		return recorder.Body.String()
+	}
+
+	// anyone could claim any address otherwise
+	require.Equal(t, "10.0.0.1", clientIP(""))
+	require.Equal(t, "10.0.0.1", clientIP("192.168.0.0/16"))
+	require.Equal(t, "203.0.113.7", clientIP("192.168.0.0/16, 10.0.0.0/8"))
+
+	_, err := NewServer(util.Config{
+		TokenSymmetricKey:   util.RandomString(32),
+		AccessTokenDuration: time.Minute,
+		TrustedProxies:      "not a proxy",
+	}, nil, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/token"
+	"github.com/kratos069/message-app/util"
+)
+
+const defaultAuthCacheTTL = 30 * time.Second
+
+var (
+	errTokenRevoked   = errors.New("token has been revoked")
+	errSessionBlocked = errors.New("session is blocked")
+	errRoleChanged    = errors.New("role has changed, please log in again")
+)
+
+// sessionStateStore is the part of db.Store the guard reads from
+type sessionStateStore interface {
+	GetSessionByID(ctx context.Context, id uuid.UUID) (db.Session, error)
+	GetUserByID(ctx context.Context, id int64) (db.User, error)
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (db.APIKey, error)
+	TouchAPIKey(ctx context.Context, apiKeyID int64) error
+}
+
+// sessionGuard checks that a verified token still belongs to
+// a live session of a user who is not banned.
+// The revocation list makes logout and bans take effect on the
+// next request; the cache keeps the database out of the hot path.
+type sessionGuard struct {
+	store       sessionStateStore
+	revocations token.RevocationList
+	sessions    *util.TTLCache[uuid.UUID, db.Session]
+	users       *util.TTLCache[int64, db.User]
+}
+
+func newSessionGuard(store sessionStateStore,
+	revocations token.RevocationList, cacheTTL time.Duration) *sessionGuard {
+	if cacheTTL == 0 {
+		cacheTTL = defaultAuthCacheTTL
+	}
+
+	return &sessionGuard{
+		store:       store,
+		revocations: revocations,
+		sessions:    util.NewTTLCache[uuid.UUID, db.Session](cacheTTL),
+		users:       util.NewTTLCache[int64, db.User](cacheTTL),
+	}
+}
+
+// returns the token's user, or an error if the
+// token must no longer be accepted
+func (guard *sessionGuard) check(ctx context.Context,
+	payload *token.Payload) (db.User, error) {
+	// e.g. 2FA challenge tokens, which never belong to a session
+	if payload.SessionID == uuid.Nil {
+		return db.User{}, token.ErrInvalidToken
+	}
+
+	revoked, err := guard.revocations.IsRevoked(ctx, payload)
+	if err != nil {
+		return db.User{}, err
+	}
+	if revoked {
+		return db.User{}, errTokenRevoked
+	}
+
+	session, err := guard.session(ctx, payload.SessionID)
+	if err != nil {
+		return db.User{}, err
+	}
+	if session.IsBlocked {
+		return db.User{}, errSessionBlocked
+	}
+	if session.Username != payload.Username {
+		return db.User{}, token.ErrInvalidToken
+	}
+
+	user, err := guard.user(ctx, payload.UserID)
+	if err != nil {
+		return user, err
+	}
+
+	// a demoted admin's tokens must not keep admin access
+	if user.Role != payload.Role {
+		return db.User{}, errRoleChanged
+	}
+
+	return user, nil
+}
+
+func (guard *sessionGuard) session(ctx context.Context,
+	sessionID uuid.UUID) (db.Session, error) {
+	if session, ok := guard.sessions.Get(sessionID); ok {
+		return session, nil
+	}
+
+	session, err := guard.store.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return session, token.ErrInvalidToken
+		}
+		return session, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	guard.sessions.Set(sessionID, session)
+	return session, nil
+}
+
+func (guard *sessionGuard) user(ctx context.Context,
+	userID int64) (db.User, error) {
+	if user, ok := guard.users.Get(userID); ok {
+		return user, nil
+	}
+
+	user, err := guard.store.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return user, token.ErrInvalidToken
+		}
+		return user, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	guard.users.Set(userID, user)
+	return user, nil
+}
+
+// revokes a single session on every instance
+func (guard *sessionGuard) revokeSession(ctx context.Context,
+	sessionID uuid.UUID, ttl time.Duration) error {
+	guard.sessions.Delete(sessionID)
+	return guard.revocations.RevokeSession(ctx, sessionID, ttl)
+}
+
+// revokes every token issued to the user so far on every instance
+func (guard *sessionGuard) revokeUser(ctx context.Context,
+	userID int64, ttl time.Duration) error {
+	guard.users.Delete(userID)
+	return guard.revocations.RevokeUser(ctx, userID, ttl)
+}
+
+// drops cached state of a user, e.g. after an unban
+// or an email verification
+func (guard *sessionGuard) forgetUser(userID int64) {
+	guard.users.Delete(userID)
+}
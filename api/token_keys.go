@@ -0,0 +1,181 @@
+package api
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kratos069/message-app/token"
+	"github.com/kratos069/message-app/util"
+)
+
+// tokenKeySource reloads the keys of the configured token maker
+// and tells which public keys may be published
+type tokenKeySource interface {
+	reload(config util.Config) error
+	publicKeys() map[string]ed25519.PublicKey
+}
+
+// creates the maker chosen by TOKEN_TYPE together with its keys
+func newTokenMaker(config util.Config) (token.Maker, tokenKeySource, error) {
+	switch config.TokenType {
+	case "", util.TokenTypeLocal:
+		activeKeyID, keys, err := symmetricTokenKeys(config)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		keyring, err := token.NewKeyring(activeKeyID, keys)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return token.NewPasetoKeyringMaker(keyring),
+			symmetricKeySource{keyring}, nil
+	case util.TokenTypePublic:
+		keys, err := signingTokenKeys(config)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		keyring, err := token.NewKeyring(config.TokenActiveKeyID, keys)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return token.NewPasetoPublicMaker(keyring),
+			signingKeySource{keyring}, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown token type %q", config.TokenType)
+	}
+}
+
+// TOKEN_KEYS holds the rotated keys, TOKEN_SYMMETRIC_KEY is kept
+// for tokens issued before key IDs and is active if no ID is set
+func symmetricTokenKeys(config util.Config) (string, map[string][]byte, error) {
+	specs, err := token.ParseKeys(config.TokenKeys)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if config.TokenSymmetricKey != "" {
+		specs[token.LegacyKeyID] = config.TokenSymmetricKey
+	}
+
+	keys, err := token.SymmetricKeys(specs)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return config.TokenActiveKeyID, keys, nil
+}
+
+// TOKEN_SIGNING_KEYS holds base64 encoded Ed25519 seeds
+func signingTokenKeys(config util.Config) (map[string]ed25519.PrivateKey, error) {
+	specs, err := token.ParseKeys(config.TokenSigningKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	return token.Ed25519Keys(specs)
+}
+
+type symmetricKeySource struct {
+	keyring *token.Keyring[[]byte]
+}
+
+func (source symmetricKeySource) reload(config util.Config) error {
+	if config.TokenType != "" && config.TokenType != util.TokenTypeLocal {
+		return errors.New("token type can't be changed without a restart")
+	}
+
+	activeKeyID, keys, err := symmetricTokenKeys(config)
+	if err != nil {
+		return err
+	}
+
+	return source.keyring.Update(activeKeyID, keys)
+}
+
+// nothing of a shared secret may be published
+func (source symmetricKeySource) publicKeys() map[string]ed25519.PublicKey {
+	return nil
+}
+
+type signingKeySource struct {
+	keyring *token.Keyring[ed25519.PrivateKey]
+}
+
+func (source signingKeySource) reload(config util.Config) error {
+	if config.TokenType != util.TokenTypePublic {
+		return errors.New("token type can't be changed without a restart")
+	}
+
+	keys, err := signingTokenKeys(config)
+	if err != nil {
+		return err
+	}
+
+	return source.keyring.Update(config.TokenActiveKeyID, keys)
+}
+
+func (source signingKeySource) publicKeys() map[string]ed25519.PublicKey {
+	privateKeys := source.keyring.All()
+
+	keys := make(map[string]ed25519.PublicKey, len(privateKeys))
+	for id, privateKey := range privateKeys {
+		keys[id] = privateKey.Public().(ed25519.PublicKey)
+	}
+
+	return keys
+}
+
+// ReloadTokenKeys applies rotated token keys without a restart,
+// tokens of retired keys stop verifying right away
+func (server *Server) ReloadTokenKeys(config util.Config) error {
+	return server.tokenKeys.reload(config)
+}
+
+// one public key in JWK format (RFC 8037)
+type publicKeyResponse struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	X         string `json:"x"`
+}
+
+// PublicKeys publishes the keys v2.public tokens are verified with,
+// the token footer names the key ("kid") that signed it
+func (server *Server) publicKeys(ctx *gin.Context) {
+	keys := server.tokenKeys.publicKeys()
+	if keys == nil {
+		ctx.JSON(http.StatusNotFound,
+			gin.H{"error": "tokens are not signed with public keys"})
+		return
+	}
+
+	resp := make([]publicKeyResponse, 0, len(keys))
+	for id, key := range keys {
+		resp = append(resp, publicKeyResponse{
+			KeyType:   "OKP",
+			Curve:     "Ed25519",
+			KeyID:     id,
+			Use:       "sig",
+			Algorithm: "v2.public",
+			X:         base64.RawURLEncoding.EncodeToString(key),
+		})
+	}
+	sort.Slice(resp, func(i, j int) bool {
+		return resp[i].KeyID < resp[j].KeyID
+	})
+
+	// keys change rarely but must be picked up soon after a rotation
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, gin.H{"keys": resp})
+}
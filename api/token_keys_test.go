@@ -0,0 +1,52 @@
+package api
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kratos069/message-app/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublicKeys(t *testing.T) {
+	seed := []byte(util.RandomString(ed25519.SeedSize))
+	publicKey := ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey)
+
+	config := util.Config{
+		TokenType:           util.TokenTypePublic,
+		TokenSigningKeys:    "k1:" + base64.StdEncoding.EncodeToString(seed),
+		TokenActiveKeyID:    "k1",
+		AccessTokenDuration: time.Minute,
+	}
+
+	server, err := NewServer(config, nil, nil)
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/.well-known/token-keys", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var resp struct {
+		Keys []publicKeyResponse `json:"keys"`
+	}
+	err = json.Unmarshal(recorder.Body.Bytes(), &resp)
+	require.NoError(t, err)
+	require.Len(t, resp.Keys, 1)
+	require.Equal(t, "k1", resp.Keys[0].KeyID)
+	require.Equal(t, base64.RawURLEncoding.EncodeToString(publicKey), resp.Keys[0].X)
+
+	// shared secrets are never published
+	server = newTestServer(t, nil, nil)
+
+	recorder = httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+}
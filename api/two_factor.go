@@ -0,0 +1,320 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/token"
+	"github.com/kratos069/message-app/util"
+)
+
+const (
+	totpIssuer                  = "Message App"
+	recoveryCodeCount           = 10
+	defaultMFAChallengeDuration = 5 * time.Minute
+)
+
+var (
+	errInvalidMFACode      = errors.New("invalid two-factor code")
+	errTwoFactorEnabled    = errors.New("two-factor authentication is already enabled")
+	errTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+)
+
+type mfaChallengeResponse struct {
+	MFARequired       bool      `json:"mfa_required"`
+	MFAToken          string    `json:"mfa_token"`
+	MFATokenExpiresAt time.Time `json:"mfa_token_expires_at"`
+}
+
+// answers a correct password with a short-lived challenge token
+// instead of a session when the user has 2FA enabled
+func (server *Server) sendMFAChallenge(ctx *gin.Context, user db.User) {
+	duration := server.config.MFAChallengeDuration
+	if duration == 0 {
+		duration = defaultMFAChallengeDuration
+	}
+
+	mfaToken, payload, err := server.tokenMaker.CreateToken(
+		user.Username,
+		user.ID,
+		util.MFAChallengeRole,
+		uuid.Nil,
+		duration,
+	)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, mfaChallengeResponse{
+		MFARequired:       true,
+		MFAToken:          mfaToken,
+		MFATokenExpiresAt: payload.ExpiredAt,
+	})
+}
+
+type verifyMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// VerifyMFA completes a two-step login with a TOTP or recovery code
+func (server *Server) verifyMFA(ctx *gin.Context) {
+	var req verifyMFARequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	payload, err := server.tokenMaker.VerifyToken(req.MFAToken)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errResponse(err))
+		return
+	}
+	if payload.Role != util.MFAChallengeRole {
+		ctx.JSON(http.StatusUnauthorized, errResponse(token.ErrInvalidToken))
+		return
+	}
+
+	user, err := server.store.GetUserByID(ctx, payload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errResponse(token.ErrInvalidToken))
+		return
+	}
+
+	// user may have been banned since the password step
+	err = server.accountPolicy.checkLogin(user)
+	if err != nil {
+		abortWithPolicyError(ctx, user, err)
+		return
+	}
+
+	// codes are guessed against the same limits as passwords
+	account := loginAccountKey(user.Email)
+	if !server.allowLoginAttempt(ctx, account) {
+		return
+	}
+
+	err = server.verifySecondFactor(ctx, user.ID, req.Code)
+	if err != nil {
+		if errors.Is(err, errInvalidMFACode) {
+			server.recordLoginFailure(ctx, account, &user)
+		}
+		ctx.JSON(mfaErrorStatus(err), errResponse(err))
+		return
+	}
+
+	resp, err := server.startSession(ctx, user)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	server.resetLoginFailures(ctx, account)
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type enrollTwoFactorResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// EnrollTwoFactor creates a pending TOTP secret,
+// the provisioning URI is meant to be shown as a QR code
+func (server *Server) enrollTwoFactor(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	user, err := server.store.GetUserByID(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	secret, err := util.GenerateTOTPSecret()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	// doesn't overwrite a confirmed secret
+	twoFactor, err := server.store.UpsertTwoFactorSecret(ctx,
+		db.UpsertTwoFactorSecretParams{
+			UserID: user.ID,
+			Secret: secret,
+		})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.JSON(http.StatusConflict, errResponse(errTwoFactorEnabled))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, enrollTwoFactorResponse{
+		Secret:          twoFactor.Secret,
+		ProvisioningURI: util.TOTPProvisioningURI(totpIssuer, user.Email, twoFactor.Secret),
+	})
+}
+
+type confirmTwoFactorRequest struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+// ConfirmTwoFactor turns on 2FA once the user proves their
+// authenticator works, and returns the recovery codes (only once)
+func (server *Server) confirmTwoFactor(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	var req confirmTwoFactorRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	twoFactor, err := server.store.GetTwoFactorAuth(ctx, authPayload.UserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.JSON(http.StatusBadRequest,
+				gin.H{"error": "two-factor enrollment not started"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	if twoFactor.IsEnabled {
+		ctx.JSON(http.StatusConflict, errResponse(errTwoFactorEnabled))
+		return
+	}
+
+	step, ok := util.ValidateTOTP(twoFactor.Secret, req.Code, time.Now())
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, errResponse(errInvalidMFACode))
+		return
+	}
+
+	recoveryCodes := util.GenerateRecoveryCodes(recoveryCodeCount)
+	codeHashes := make([]string, len(recoveryCodes))
+	for i, code := range recoveryCodes {
+		codeHashes[i] = util.HashSecret(code)
+	}
+
+	_, err = server.store.EnableTwoFactorTx(ctx, db.EnableTwoFactorTxParams{
+		UserID:             authPayload.UserID,
+		Step:               step,
+		RecoveryCodeHashes: codeHashes,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"recovery_codes": recoveryCodes,
+		"message":        "Two-factor authentication enabled. Store the recovery codes somewhere safe.",
+	})
+}
+
+type disableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// DisableTwoFactor turns 2FA off, requires both factors
+func (server *Server) disableTwoFactor(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	var req disableTwoFactorRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	user, err := server.store.GetUserByID(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	err = util.CheckPassword(req.Password, user.PasswordHash)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errResponse(errWrongPassword))
+		return
+	}
+
+	err = server.verifySecondFactor(ctx, user.ID, req.Code)
+	if err != nil {
+		ctx.JSON(mfaErrorStatus(err), errResponse(err))
+		return
+	}
+
+	err = server.store.DisableTwoFactorTx(ctx, user.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// accepts a TOTP code (each one only once) or an unused recovery code
+func (server *Server) verifySecondFactor(ctx *gin.Context,
+	userID int64, code string) error {
+	twoFactor, err := server.store.GetTwoFactorAuth(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errTwoFactorNotEnabled
+		}
+		return err
+	}
+	if !twoFactor.IsEnabled {
+		return errTwoFactorNotEnabled
+	}
+
+	if step, ok := util.ValidateTOTP(twoFactor.Secret, code, time.Now()); ok {
+		used, err := server.store.UseTwoFactorStep(ctx, db.UseTwoFactorStepParams{
+			UserID:       userID,
+			LastUsedStep: step,
+		})
+		if err != nil {
+			return err
+		}
+		if used == 0 {
+			return errInvalidMFACode
+		}
+		return nil
+	}
+
+	used, err := server.store.UseRecoveryCode(ctx, db.UseRecoveryCodeParams{
+		UserID:   userID,
+		CodeHash: util.HashSecret(strings.ToLower(strings.TrimSpace(code))),
+	})
+	if err != nil {
+		return err
+	}
+	if used == 0 {
+		return errInvalidMFACode
+	}
+
+	return nil
+}
+
+func mfaErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errInvalidMFACode):
+		return http.StatusUnauthorized
+	case errors.Is(err, errTwoFactorNotEnabled):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
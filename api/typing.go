@@ -0,0 +1,178 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/events"
+	"github.com/kratos069/message-app/token"
+	"github.com/rs/zerolog/log"
+)
+
+// typing stops this long after the last update
+const typingTTL = 10 * time.Second
+
+type typingUserResponse struct {
+	UserID            int64     `json:"user_id"`
+	Username          string    `json:"username"`
+	ProfilePictureUrl *string   `json:"profile_picture_url"`
+	StartedAt         time.Time `json:"started_at"`
+}
+
+// StartTyping marks the caller as typing in the conversation. Clients
+// repeat it while the user keeps typing, it runs out after typingTTL.
+func (server *Server) startTyping(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	conversationID, ok := server.typingConversation(ctx, authPayload.UserID)
+	if !ok {
+		return
+	}
+
+	err := server.typing.Start(ctx, conversationID, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	server.publishTyping(ctx, events.TypeTypingStarted, conversationID,
+		authPayload.UserID)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"conversation_id": conversationID,
+		"expires_in":      int64(typingTTL.Seconds()),
+	})
+}
+
+// StopTyping clears the caller's typing state right away
+func (server *Server) stopTyping(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	conversationID, ok := server.typingConversation(ctx, authPayload.UserID)
+	if !ok {
+		return
+	}
+
+	err := server.typing.Stop(ctx, conversationID, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	server.publishTyping(ctx, events.TypeTypingStopped, conversationID,
+		authPayload.UserID)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"conversation_id": conversationID,
+		"message":         "Stopped typing",
+	})
+}
+
+// GetTypingUsers returns who is typing in the conversation, users
+// blocked by the caller or blocking them are left out
+func (server *Server) getTypingUsers(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	conversationID, ok := server.typingConversation(ctx, authPayload.UserID)
+	if !ok {
+		return
+	}
+
+	typists, err := server.typing.Typing(ctx, conversationID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	userIDs := make([]int64, 0, len(typists))
+	for _, typist := range typists {
+		userIDs = append(userIDs, typist.UserID)
+	}
+
+	resp := make([]typingUserResponse, 0, len(typists))
+	if len(userIDs) > 0 {
+		profiles, err := server.store.ListPublicProfiles(ctx, userIDs)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errResponse(err))
+			return
+		}
+
+		blocked, err := server.blockedAmong(ctx, authPayload.UserID, userIDs)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errResponse(err))
+			return
+		}
+
+		byID := make(map[int64]db.ListPublicProfilesRow, len(profiles))
+		for _, profile := range profiles {
+			byID[profile.ID] = profile
+		}
+
+		for _, typist := range typists {
+			profile, ok := byID[typist.UserID]
+			if !ok || blocked[typist.UserID] {
+				continue
+			}
+			resp = append(resp, typingUserResponse{
+				UserID:            profile.ID,
+				Username:          profile.Username,
+				ProfilePictureUrl: textPtr(profile.ProfilePictureUrl),
+				StartedAt:         typist.StartedAt,
+			})
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"typing_users": resp,
+		"count":        len(resp),
+	})
+}
+
+// ==========================================================================
+// ================================Helper====================================
+// ==========================================================================
+
+// tells the people in the conversation who may know about it that userID
+// started or stopped typing. The typing state is already saved, so
+// failures are only logged.
+func (server *Server) publishTyping(ctx context.Context, eventType string,
+	conversationID, userID int64) {
+	recipients, err := server.eventRecipients(ctx, conversationID, userID, false)
+	if err != nil {
+		log.Error().Err(err).Str("event", eventType).
+			Int64("conversation_id", conversationID).
+			Msg("Failed to find event recipients")
+		return
+	}
+
+	server.publishEventTo(ctx, eventType, conversationID, recipients,
+		gin.H{"user_id": userID})
+}
+
+// binds the conversation id, only participants get to see or change
+// typing state. Writes the response and returns false otherwise.
+func (server *Server) typingConversation(ctx *gin.Context,
+	userID int64) (int64, bool) {
+	var req conversationIDStruct
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return 0, false
+	}
+
+	isParticipant, err := server.store.IsUserInConversation(
+		ctx, db.IsUserInConversationParams{
+			ConversationID: req.ConversationID,
+			UserID:         userID,
+		})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return 0, false
+	}
+	if !isParticipant {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return 0, false
+	}
+
+	return req.ConversationID, true
+}
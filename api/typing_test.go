@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/events"
+	"github.com/stretchr/testify/require"
+)
+
+func (store *fakePrivacyStore) IsUserInConversation(ctx context.Context,
+	arg db.IsUserInConversationParams) (bool, error) {
+	_, err := store.GetConversationParticipant(ctx,
+		db.GetConversationParticipantParams(arg))
+	return err == nil, nil
+}
+
+func (store *fakePrivacyStore) ListPublicProfiles(ctx context.Context,
+	ids []int64) ([]db.ListPublicProfilesRow, error) {
+	profiles := []db.ListPublicProfilesRow{}
+	for _, id := range ids {
+		profiles = append(profiles, db.ListPublicProfilesRow{
+			ID:       id,
+			Username: fmt.Sprintf("user%d", id),
+		})
+	}
+	return profiles, nil
+}
+
+func TestTyping(t *testing.T) {
+	store := newFakePrivacyStore()
+	server := newTestServer(t, store, nil)
+	store.conversations[conversationKey(1, 2)] = 7
+
+	handlers := map[string]gin.HandlerFunc{
+		http.MethodGet:    server.getTypingUsers,
+		http.MethodPost:   server.startTyping,
+		http.MethodDelete: server.stopTyping,
+	}
+	send := func(userID int64, method string) *httptest.ResponseRecorder {
+		return sendAs(t, userID, method, "/typing/:conversation_id",
+			"/typing/7", handlers[method])
+	}
+	typing := func(userID int64) []typingUserResponse {
+		recorder := send(userID, http.MethodGet)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		var resp struct {
+			TypingUsers []typingUserResponse `json:"typing_users"`
+		}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+		return resp.TypingUsers
+	}
+
+	subscription, err := server.events.Subscribe(context.Background(), 7)
+	require.NoError(t, err)
+	defer subscription.Close()
+
+	require.Empty(t, typing(1))
+
+	require.Equal(t, http.StatusOK, send(2, http.MethodPost).Code)
+	event := <-subscription.Events()
+	require.Equal(t, events.TypeTypingStarted, event.Type)
+	require.Equal(t, []int64{1}, event.Recipients)
+	users := typing(1)
+	require.Len(t, users, 1)
+	require.Equal(t, int64(2), users[0].UserID)
+	require.Equal(t, "user2", users[0].Username)
+	require.WithinDuration(t, time.Now(), users[0].StartedAt, time.Second)
+
+	// blocks hide it, also from the event stream
+	store.blocks[[2]int64{1, 2}] = time.Now()
+	require.Empty(t, typing(1))
+	require.Equal(t, http.StatusOK, send(2, http.MethodPost).Code)
+	require.Empty(t, subscription.Events())
+	delete(store.blocks, [2]int64{1, 2})
+
+	require.Equal(t, http.StatusOK, send(2, http.MethodDelete).Code)
+	require.Equal(t, events.TypeTypingStopped, (<-subscription.Events()).Type)
+	require.Empty(t, typing(1))
+
+	// only participants see or change it
+	require.Equal(t, http.StatusForbidden, send(3, http.MethodPost).Code)
+	require.Equal(t, http.StatusForbidden, send(3, http.MethodGet).Code)
+}
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"strings"
@@ -11,6 +13,7 @@ import (
 	"github.com/hibiken/asynq"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/kratos069/message-app/db/sqlc/db-gen"
 	"github.com/kratos069/message-app/token"
 	"github.com/kratos069/message-app/util"
@@ -23,7 +26,6 @@ type RegisterRequest struct {
 	Username string `json:"username" binding:"required,min=3,max=50,alphanum"`
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=6"`
-	Role     string `json:"role"`
 }
 
 type userResponse struct {
@@ -64,7 +66,8 @@ func (server *Server) register(ctx *gin.Context) {
 			Username:     req.Username,
 			Email:        req.Email,
 			PasswordHash: string(hashedPassword),
-			Role:         req.Role,
+			// roles are only changed by admins, see changeUserRole
+			Role: util.CustomerRole,
 		},
 		AfterCreate: func(user db.User) error {
 			// send verification email -> that user created
@@ -121,10 +124,18 @@ func (server *Server) loginUser(ctx *gin.Context) {
 		return
 	}
 
+	account := loginAccountKey(input.Email)
+	if !server.allowLoginAttempt(ctx, account) {
+		return
+	}
+
 	user, err := server.store.GetUserByEmail(ctx, input.Email)
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errResponse(err))
+			// same work and same answer as a wrong password
+			_ = util.CheckPassword(input.Password, dummyPasswordHash)
+			server.recordLoginFailure(ctx, account, nil)
+			ctx.JSON(http.StatusUnauthorized, errResponse(errInvalidCredentials))
 			return
 		}
 
@@ -133,21 +144,82 @@ func (server *Server) loginUser(ctx *gin.Context) {
 	}
 
 	err = util.CheckPassword(input.Password, user.PasswordHash)
+	// bots only authenticate with API keys
+	if err == nil && user.IsBot {
+		err = errInvalidCredentials
+	}
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, errResponse(err))
+		server.recordLoginFailure(ctx, account, &user)
+		ctx.JSON(http.StatusUnauthorized, errResponse(errInvalidCredentials))
 		return
 	}
 
+	// cleared only once a session exists, with 2FA that is in verifyMFA
+	if server.completeLogin(ctx, user) {
+		server.resetLoginFailures(ctx, account)
+	}
+}
+
+// completes a login once the user proved who they are (password or
+// external provider), reports whether a session was started
+func (server *Server) completeLogin(ctx *gin.Context, user db.User) bool {
+	// banned or (depending on policy) unverified users can't log in
+	err := server.accountPolicy.checkLogin(user)
+	if err != nil {
+		abortWithPolicyError(ctx, user, err)
+		return false
+	}
+
+	// with 2FA enabled a session is only created once the code is verified
+	twoFactor, err := server.store.GetTwoFactorAuth(ctx, user.ID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return false
+	}
+	if err == nil && twoFactor.IsEnabled {
+		server.sendMFAChallenge(ctx, user)
+		return false
+	}
+
+	resp, err := server.startSession(ctx, user)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return false
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+	return true
+}
+
+// creates access & refresh tokens and the session they belong to
+func (server *Server) startSession(ctx *gin.Context,
+	user db.User) (loginUserResponse, error) {
+	// logging in during the grace period keeps the account
+	if user.DeletionRequestedAt.Valid {
+		err := server.store.CancelUserDeletion(ctx, user.ID)
+		if err != nil {
+			return loginUserResponse{}, err
+		}
+		user.DeletionRequestedAt = pgtype.Timestamptz{}
+		server.sessionGuard.forgetUser(user.ID)
+	}
+
+	// both tokens carry the session they belong to
+	sessionID, err := uuid.NewRandom()
+	if err != nil {
+		return loginUserResponse{}, err
+	}
+
 	// creating access token
 	accessToken, accessPayload, err := server.tokenMaker.CreateToken(
 		user.Username,
 		user.ID,
 		user.Role,
+		sessionID,
 		server.config.AccessTokenDuration,
 	)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
-		return
+		return loginUserResponse{}, err
 	}
 
 	// creating refresh token
@@ -155,16 +227,16 @@ func (server *Server) loginUser(ctx *gin.Context) {
 		user.Username,
 		user.ID,
 		user.Role,
+		sessionID,
 		server.config.RefreshTokenDuration,
 	)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
-		return
+		return loginUserResponse{}, err
 	}
 
 	// create session
 	session, err := server.store.CreateSession(ctx, db.CreateSessionParams{
-		ID:           refreshPayload.ID,
+		ID:           sessionID,
 		Username:     accessPayload.Username,
 		RefreshToken: refreshToken,
 		UserAgent:    ctx.Request.UserAgent(),
@@ -173,15 +245,11 @@ func (server *Server) loginUser(ctx *gin.Context) {
 		ExpiredAt:    refreshPayload.ExpiredAt,
 	})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
-		return
+		return loginUserResponse{}, err
 	}
 
-	// Update online status
-	_ = server.store.UpdateUserOnlineStatus(ctx, db.UpdateUserOnlineStatusParams{
-		ID:       user.ID,
-		IsOnline: true,
-	})
+	// logging in counts as a heartbeat, the client keeps it going
+	_ = server.markOnline(ctx, user.ID)
 
 	resp := loginUserResponse{
 		SessionID:             session.ID,
@@ -192,7 +260,7 @@ func (server *Server) loginUser(ctx *gin.Context) {
 		User:                  newUserResponse(user),
 	}
 
-	ctx.JSON(http.StatusOK, resp)
+	return resp, nil
 }
 
 // Logout logs out a user
@@ -207,7 +275,21 @@ func (server *Server) logoutUser(ctx *gin.Context) {
 		return
 	}
 
-	// Update user online status to offline
+	// tokens of the blocked sessions stop working on the next request
+	err = server.sessionGuard.revokeUser(ctx, authPayload.UserID,
+		server.revocationTTL())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	// offline right away instead of when the heartbeats run out
+	_, err = server.presence.Leave(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
 	err = server.store.UpdateUserOnlineStatus(ctx, db.UpdateUserOnlineStatusParams{
 		ID:       authPayload.UserID,
 		IsOnline: false,
@@ -222,57 +304,114 @@ func (server *Server) logoutUser(ctx *gin.Context) {
 	})
 }
 
-type inputUserID struct {
-	UserID int64 `uri:"id" binding:"required,min=1"`
+var errInvalidCursor = errors.New("invalid cursor")
+
+// the cursor comes from next_cursor of the previous page
+type searchUsersRequest struct {
+	Query        string `json:"query" binding:"required,max=50"`
+	ContactsOnly bool   `json:"contacts_only"`
+	Limit        int32  `json:"limit" binding:"omitempty,min=1,max=50"`
+	Cursor       string `json:"cursor"`
+}
+
+type searchUserResponse struct {
+	UserID            int64   `json:"user_id"`
+	Username          string  `json:"username"`
+	Nickname          *string `json:"nickname"`
+	ProfilePictureUrl *string `json:"profile_picture_url"`
+	IsOnline          bool    `json:"is_online"`
+	IsBot             bool    `json:"is_bot"`
+	IsContact         bool    `json:"is_contact"`
 }
 
-func (server *Server) getUserByID(ctx *gin.Context) {
-	var input inputUserID
+// SearchUsers searches users by username, and contacts by nickname too.
+// Exact matches come first, then prefix and then fuzzy ones. Banned,
+// deleted and blocked users are left out.
+func (server *Server) SearchUsers(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
-	if err := ctx.ShouldBindUri(&input); err != nil {
+	var req searchUsersRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, errResponse(err))
 		return
 	}
 
-	user, err := server.store.GetUserByID(ctx, input.UserID)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+	query := strings.TrimSpace(req.Query)
+	if query == "" {
+		ctx.JSON(http.StatusBadRequest, errResponse(errors.New("query is empty")))
 		return
 	}
+	if req.Limit == 0 {
+		req.Limit = 20
+	}
 
-	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
-	if user.ID != authPayload.UserID {
-		err := errors.New("account doesnot belong to the authenticated user")
-		ctx.JSON(http.StatusUnauthorized, errResponse(err))
-		return
+	cursor := searchCursor{Tier: -1}
+	if req.Cursor != "" {
+		var err error
+		cursor, err = decodeSearchCursor(req.Cursor)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, errResponse(err))
+			return
+		}
 	}
 
-	resp := newUserResponse(user)
+	// one extra row tells whether there is another page
+	pattern := escapeLike(query)
+	users, err := server.store.SearchUsers(ctx, db.SearchUsersParams{
+		Contains:      "%" + pattern + "%",
+		Query:         query,
+		ViewerID:      authPayload.UserID,
+		Prefix:        pattern + "%",
+		ContactsOnly:  req.ContactsOnly,
+		AfterTier:     cursor.Tier,
+		AfterUsername: cursor.Username,
+		Limit:         req.Limit + 1,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
 
-	ctx.JSON(http.StatusOK, resp)
-}
+	var nextCursor *string
+	if len(users) > int(req.Limit) {
+		users = users[:req.Limit]
+		last := users[len(users)-1]
+		next := encodeSearchCursor(searchCursor{
+			Tier:     last.Tier,
+			Username: last.Username,
+		})
+		nextCursor = &next
+	}
 
-// SearchUsers searches for users by username
-func (server *Server) SearchUsers(ctx *gin.Context) {
-	query := ctx.Query("username")
-	if query == "" {
-		ctx.JSON(http.StatusBadRequest, "user not found")
-		return
+	userIDs := make([]int64, 0, len(users))
+	for _, user := range users {
+		userIDs = append(userIDs, user.ID)
 	}
 
-	users, err := server.store.SearchUsersByUsername(ctx, db.SearchUsersByUsernameParams{
-		Username: "%" + query + "%",
-		Limit:    20,
-	})
+	view, err := server.newPrivacyView(ctx, authPayload.UserID, userIDs)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
 		return
 	}
 
+	resp := make([]searchUserResponse, 0, len(users))
+	for _, user := range users {
+		resp = append(resp, searchUserResponse{
+			UserID:            user.ID,
+			Username:          user.Username,
+			Nickname:          textPtr(user.Nickname),
+			ProfilePictureUrl: textPtr(user.ProfilePictureUrl),
+			IsOnline:          user.IsOnline && view.showOnline(user.ID),
+			IsBot:             user.IsBot,
+			IsContact:         user.IsContact,
+		})
+	}
+
 	ctx.JSON(http.StatusOK, gin.H{
-		"users":   users,
-		"count":   len(users),
-		"message": "Search completed successfully",
+		"users":       resp,
+		"count":       len(resp),
+		"next_cursor": nextCursor,
+		"message":     "Search completed successfully",
 	})
 }
 
@@ -298,3 +437,34 @@ func isDuplicateKeyError(err error) bool {
 		strings.Contains(errMsg, "unique constraint") ||
 		strings.Contains(errMsg, "SQLSTATE 23505")
 }
+
+// searchCursor is where the previous page of a user search ended
+type searchCursor struct {
+	Tier     int32  `json:"t"`
+	Username string `json:"u"`
+}
+
+// cursors are opaque to clients
+func encodeSearchCursor(cursor searchCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSearchCursor(encoded string) (searchCursor, error) {
+	var cursor searchCursor
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return cursor, errInvalidCursor
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return cursor, errInvalidCursor
+	}
+	return cursor, nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// escapes the LIKE wildcards so a query only matches itself
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
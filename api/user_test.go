@@ -0,0 +1,102 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/stretchr/testify/require"
+)
+
+// finds users by substring, all in one tier
+func (store *fakeProfileStore) SearchUsers(ctx context.Context,
+	arg db.SearchUsersParams) ([]db.SearchUsersRow, error) {
+	store.searches = append(store.searches, arg)
+	rows := []db.SearchUsersRow{}
+	for _, user := range store.users {
+		if user.ID == arg.ViewerID || !strings.Contains(user.Username, arg.Query) {
+			continue
+		}
+		if arg.AfterTier == 1 && user.Username <= arg.AfterUsername {
+			continue
+		}
+		rows = append(rows, db.SearchUsersRow{
+			ID:       user.ID,
+			Username: user.Username,
+			IsOnline: user.IsOnline,
+			Tier:     1,
+		})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Username < rows[j].Username })
+	if len(rows) > int(arg.Limit) {
+		rows = rows[:arg.Limit]
+	}
+	return rows, nil
+}
+
+func TestSearchUsers(t *testing.T) {
+	store := newFakeProfileStore()
+	store.users[3] = db.User{ID: 3, Username: "bobby", Email: "bobby@example.com"}
+	store.users[4] = db.User{ID: 4, Username: "bobcat", Email: "bobcat@example.com"}
+	server := newTestServer(t, store, nil)
+
+	search := func(body any) (*httptest.ResponseRecorder, map[string]any) {
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+		router := newProfileTestRouter(newPrivacyTestPayload(1),
+			http.MethodPost, "/users/search", server.SearchUsers)
+		request, err := http.NewRequest(http.MethodPost, "/users/search",
+			bytes.NewReader(data))
+		require.NoError(t, err)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		var resp map[string]any
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+		return recorder, resp
+	}
+	usernames := func(resp map[string]any) []string {
+		names := []string{}
+		for _, user := range resp["users"].([]any) {
+			names = append(names, user.(map[string]any)["username"].(string))
+		}
+		return names
+	}
+
+	recorder, resp := search(gin.H{"query": "bob", "limit": 2})
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, []string{"bob", "bobby"}, usernames(resp))
+	require.NotContains(t, recorder.Body.String(), "email")
+	cursor, ok := resp["next_cursor"].(string)
+	require.True(t, ok)
+
+	recorder, resp = search(gin.H{"query": "bob", "limit": 2, "cursor": cursor})
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, []string{"bobcat"}, usernames(resp))
+	require.Nil(t, resp["next_cursor"])
+
+	// wildcards in the query only match themselves
+	recorder, _ = search(gin.H{"query": " a_b% "})
+	require.Equal(t, http.StatusOK, recorder.Code)
+	last := store.searches[len(store.searches)-1]
+	require.Equal(t, "a_b%", last.Query)
+	require.Equal(t, `%a\_b\%%`, last.Contains)
+	require.Equal(t, `a\_b\%%`, last.Prefix)
+	require.Equal(t, int32(21), last.Limit)
+
+	recorder, _ = search(gin.H{"query": "   "})
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	recorder, _ = search(gin.H{"limit": 5})
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	recorder, _ = search(gin.H{"query": "bob", "limit": 500})
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	recorder, _ = search(gin.H{"query": "bob", "cursor": "not a cursor"})
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
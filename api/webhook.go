@@ -0,0 +1,392 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/token"
+	"github.com/kratos069/message-app/util"
+	"github.com/kratos069/message-app/worker"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	webhookSecretLength = 40
+	webhookMaxRetry     = 8
+)
+
+var (
+	errWebhookNotFound     = errors.New("webhook not found")
+	errWebhookDisabled     = errors.New("webhook is disabled")
+	errInvalidWebhookURL   = errors.New("webhook URL must be an absolute http(s) URL")
+	errInvalidWebhookEvent = errors.New("unknown webhook event")
+	errGlobalWebhook       = errors.New("only admins can subscribe to every conversation")
+	errBanEventScope       = errors.New("user.banned is only sent to webhooks of every conversation")
+)
+
+// never contains the secret, only createWebhook returns it, once
+type webhookResponse struct {
+	WebhookID      int64      `json:"webhook_id"`
+	OwnerID        int64      `json:"owner_id"`
+	ConversationID *int64     `json:"conversation_id,omitempty"`
+	URL            string     `json:"url"`
+	Events         []string   `json:"events"`
+	IsActive       bool       `json:"is_active"`
+	DisabledAt     *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func newWebhookResponse(webhook db.Webhook) webhookResponse {
+	resp := webhookResponse{
+		WebhookID:  webhook.WebhookID,
+		OwnerID:    webhook.OwnerID,
+		URL:        webhook.Url,
+		Events:     webhook.Events,
+		IsActive:   webhook.IsActive,
+		DisabledAt: timestamptzPtr(webhook.DisabledAt),
+		CreatedAt:  webhook.CreatedAt,
+	}
+	if webhook.ConversationID.Valid {
+		resp.ConversationID = &webhook.ConversationID.Int64
+	}
+
+	return resp
+}
+
+type webhookDeliveryResponse struct {
+	DeliveryID     int64           `json:"delivery_id"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int32           `json:"attempts"`
+	ResponseStatus *int32          `json:"response_status,omitempty"`
+	LastError      *string         `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+func newWebhookDeliveryResponse(delivery db.WebhookDelivery) webhookDeliveryResponse {
+	resp := webhookDeliveryResponse{
+		DeliveryID:  delivery.DeliveryID,
+		Event:       delivery.Event,
+		Payload:     delivery.Payload,
+		Status:      delivery.Status,
+		Attempts:    delivery.Attempts,
+		CreatedAt:   delivery.CreatedAt,
+		DeliveredAt: timestamptzPtr(delivery.DeliveredAt),
+	}
+	if delivery.ResponseStatus.Valid {
+		resp.ResponseStatus = &delivery.ResponseStatus.Int32
+	}
+	if delivery.LastError.Valid {
+		resp.LastError = &delivery.LastError.String
+	}
+
+	return resp
+}
+
+// body of every delivery, data depends on the event
+type webhookEnvelope struct {
+	Event      string    `json:"event"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Data       any       `json:"data"`
+}
+
+type createWebhookRequest struct {
+	URL    string   `json:"url" binding:"required,max=2048"`
+	Events []string `json:"events" binding:"required,min=1,dive,required"`
+	// without one, events of every conversation are sent (admins only)
+	ConversationID int64 `json:"conversation_id" binding:"omitempty,min=1"`
+}
+
+// CreateWebhook registers a webhook for a conversation of the caller
+func (server *Server) createWebhook(ctx *gin.Context) {
+	var req createWebhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	if !isWebhookURL(req.URL) {
+		ctx.JSON(http.StatusBadRequest, errResponse(errInvalidWebhookURL))
+		return
+	}
+	for _, event := range req.Events {
+		if !util.IsValidWebhookEvent(event) {
+			ctx.JSON(http.StatusBadRequest, errResponse(errInvalidWebhookEvent))
+			return
+		}
+		if event == util.WebhookEventUserBanned && req.ConversationID != 0 {
+			ctx.JSON(http.StatusBadRequest, errResponse(errBanEventScope))
+			return
+		}
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	var conversationID pgtype.Int8
+	if req.ConversationID == 0 {
+		if authPayload.Role != util.AdminRole {
+			ctx.JSON(http.StatusForbidden, errResponse(errGlobalWebhook))
+			return
+		}
+	} else {
+		isParticipant, err := server.store.IsUserInConversation(ctx,
+			db.IsUserInConversationParams{
+				ConversationID: req.ConversationID,
+				UserID:         authPayload.UserID,
+			})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errResponse(err))
+			return
+		}
+		if !isParticipant {
+			ctx.JSON(http.StatusForbidden,
+				gin.H{"error": "you are not a participant in this conversation"})
+			return
+		}
+		conversationID = pgtype.Int8{Int64: req.ConversationID, Valid: true}
+	}
+
+	secret := util.RandomString(webhookSecretLength)
+
+	webhook, err := server.store.CreateWebhook(ctx, db.CreateWebhookParams{
+		OwnerID:        authPayload.UserID,
+		ConversationID: conversationID,
+		Url:            req.URL,
+		Secret:         secret,
+		Events:         req.Events,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"webhook": newWebhookResponse(webhook),
+		// shown only once, deliveries are signed with it
+		"secret": secret,
+	})
+}
+
+// ListWebhooks returns the caller's webhooks
+func (server *Server) listWebhooks(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	webhooks, err := server.store.ListWebhooksByOwner(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	resp := make([]webhookResponse, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		resp = append(resp, newWebhookResponse(webhook))
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"webhooks": resp,
+		"count":    len(resp),
+	})
+}
+
+type webhookIDRequest struct {
+	WebhookID int64 `uri:"webhook_id" binding:"required,min=1"`
+}
+
+// TestWebhook sends a ping delivery to check the receiver
+func (server *Server) testWebhook(ctx *gin.Context) {
+	var uri webhookIDRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	webhook, ok := server.managedWebhook(ctx, uri.WebhookID)
+	if !ok {
+		return
+	}
+	if !webhook.IsActive {
+		ctx.JSON(http.StatusConflict, errResponse(errWebhookDisabled))
+		return
+	}
+
+	delivery, err := server.deliverWebhook(ctx, webhook, webhookEnvelope{
+		Event:      util.WebhookEventPing,
+		OccurredAt: time.Now().UTC(),
+		Data:       gin.H{"webhook_id": webhook.WebhookID},
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, newWebhookDeliveryResponse(delivery))
+}
+
+// DisableWebhook stops deliveries, pending ones are dropped
+func (server *Server) disableWebhook(ctx *gin.Context) {
+	var uri webhookIDRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	webhook, ok := server.managedWebhook(ctx, uri.WebhookID)
+	if !ok {
+		return
+	}
+
+	webhook, err := server.store.DisableWebhook(ctx, webhook.WebhookID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.JSON(http.StatusConflict, errResponse(errWebhookDisabled))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newWebhookResponse(webhook))
+}
+
+// ListWebhookDeliveries returns the delivery log of a webhook
+func (server *Server) listWebhookDeliveries(ctx *gin.Context) {
+	var uri webhookIDRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	webhook, ok := server.managedWebhook(ctx, uri.WebhookID)
+	if !ok {
+		return
+	}
+
+	limit := ctx.DefaultQuery("limit", "50")
+	offset := ctx.DefaultQuery("offset", "0")
+
+	deliveries, err := server.store.ListWebhookDeliveries(ctx,
+		db.ListWebhookDeliveriesParams{
+			WebhookID: webhook.WebhookID,
+			Limit:     parseInt32(limit, 50),
+			Offset:    parseInt32(offset, 0),
+		})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	resp := make([]webhookDeliveryResponse, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		resp = append(resp, newWebhookDeliveryResponse(delivery))
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"deliveries": resp,
+		"count":      len(resp),
+	})
+}
+
+// ==========================================================================
+// ================================Helper====================================
+// ==========================================================================
+
+// sends event to every webhook subscribed to it, conversationID is
+// invalid for events outside of conversations. Failures are only
+// logged, the request that caused the event already succeeded.
+func (server *Server) publishWebhookEvent(ctx *gin.Context, event string,
+	conversationID pgtype.Int8, data any) {
+	webhooks, err := server.store.ListWebhooksForEvent(ctx,
+		db.ListWebhooksForEventParams{
+			Event:          event,
+			AdminRole:      util.AdminRole,
+			ConversationID: conversationID,
+		})
+	if err != nil {
+		log.Error().Err(err).Str("event", event).Msg("Failed to list webhooks")
+		return
+	}
+
+	envelope := webhookEnvelope{
+		Event:      event,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	}
+	for _, webhook := range webhooks {
+		_, err := server.deliverWebhook(ctx, webhook, envelope)
+		if err != nil {
+			log.Error().Err(err).Str("event", event).
+				Int64("webhook_id", webhook.WebhookID).
+				Msg("Failed to queue webhook delivery")
+		}
+	}
+}
+
+// logs a delivery and queues the task that sends it
+func (server *Server) deliverWebhook(ctx *gin.Context, webhook db.Webhook,
+	envelope webhookEnvelope) (db.WebhookDelivery, error) {
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return db.WebhookDelivery{}, err
+	}
+
+	delivery, err := server.store.CreateWebhookDelivery(ctx,
+		db.CreateWebhookDeliveryParams{
+			WebhookID: webhook.WebhookID,
+			Event:     envelope.Event,
+			Payload:   payload,
+		})
+	if err != nil {
+		return delivery, err
+	}
+
+	opts := []asynq.Option{
+		asynq.MaxRetry(webhookMaxRetry),
+		asynq.Queue(worker.QueueDefault),
+	}
+
+	err = server.taskDistributor.DistributeTaskDeliverWebhook(ctx,
+		&worker.PayloadDeliverWebhook{DeliveryID: delivery.DeliveryID}, opts...)
+	return delivery, err
+}
+
+// returns the webhook if the caller owns it or is an admin,
+// otherwise it writes the response and reports false
+func (server *Server) managedWebhook(ctx *gin.Context,
+	webhookID int64) (db.Webhook, bool) {
+	webhook, err := server.store.GetWebhook(ctx, webhookID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errResponse(errWebhookNotFound))
+			return webhook, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return webhook, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if webhook.OwnerID != authPayload.UserID && authPayload.Role != util.AdminRole {
+		ctx.JSON(http.StatusNotFound, errResponse(errWebhookNotFound))
+		return webhook, false
+	}
+
+	return webhook, true
+}
+
+func isWebhookURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+
+	return (u.Scheme == "https" || u.Scheme == "http") && u.Host != "" && u.User == nil
+}
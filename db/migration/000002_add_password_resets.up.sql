@@ -0,0 +1,19 @@
+-- ============================================
+-- PASSWORD RESETS TABLE
+-- ============================================
+CREATE TABLE "Password_Resets" (
+  "reset_id" bigserial PRIMARY KEY,
+  "username" varchar(50) NOT NULL,
+  "email" varchar(255) NOT NULL,
+  "secret_code" varchar(100) NOT NULL,
+  "is_used" boolean NOT NULL DEFAULT false,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "expired_at" timestamptz NOT NULL DEFAULT (now() + interval '15 minutes')
+);
+
+-- Password_Resets indexes
+CREATE INDEX idx_password_resets_username ON "Password_Resets" ("username");
+CREATE INDEX idx_password_resets_email_code ON "Password_Resets" ("email", "secret_code");
+
+-- Password_Resets foreign key
+ALTER TABLE "Password_Resets" ADD FOREIGN KEY ("username") REFERENCES "Users" ("username") ON DELETE CASCADE;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "Recovery_Codes" CASCADE;
+DROP TABLE IF EXISTS "Two_Factor_Auth" CASCADE;
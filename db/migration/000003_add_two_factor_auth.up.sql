@@ -0,0 +1,43 @@
+-- ============================================
+-- TWO FACTOR AUTH TABLE (TOTP)
+-- ============================================
+CREATE TABLE "Two_Factor_Auth" (
+  "user_id" bigint PRIMARY KEY,
+  "secret" varchar(64) NOT NULL,
+  "is_enabled" boolean NOT NULL DEFAULT false,
+  "last_used_step" bigint NOT NULL DEFAULT 0,
+  "confirmed_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+-- Comments
+COMMENT ON COLUMN "Two_Factor_Auth"."last_used_step" IS 'Prevents reusing a TOTP code';
+
+-- Two_Factor_Auth foreign key
+ALTER TABLE "Two_Factor_Auth" 
+  ADD FOREIGN KEY ("user_id") 
+  REFERENCES "Users" ("id") 
+  ON DELETE CASCADE;
+
+-- ============================================
+-- RECOVERY CODES TABLE
+-- ============================================
+CREATE TABLE "Recovery_Codes" (
+  "recovery_codes_id" bigserial PRIMARY KEY,
+  "user_id" bigint NOT NULL,
+  "code_hash" varchar(64) NOT NULL,
+  "used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+-- Recovery_Codes indexes
+CREATE INDEX idx_recovery_codes_user_id ON "Recovery_Codes" ("user_id");
+
+-- Comments
+COMMENT ON COLUMN "Recovery_Codes"."code_hash" IS 'sha256 of the single-use code';
+
+-- Recovery_Codes foreign key
+ALTER TABLE "Recovery_Codes" 
+  ADD FOREIGN KEY ("user_id") 
+  REFERENCES "Users" ("id") 
+  ON DELETE CASCADE;
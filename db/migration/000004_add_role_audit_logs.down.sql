@@ -0,0 +1 @@
+DROP TABLE IF EXISTS "Role_Audit_Logs" CASCADE;
@@ -0,0 +1,31 @@
+-- ============================================
+-- ROLE AUDIT LOGS TABLE
+-- ============================================
+CREATE TABLE "Role_Audit_Logs" (
+  "role_audit_log_id" bigserial PRIMARY KEY,
+  "actor_id" bigint,
+  "target_id" bigint,
+  "target_username" varchar(50) NOT NULL,
+  "old_role" varchar(20) NOT NULL,
+  "new_role" varchar(20) NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+-- Role_Audit_Logs indexes
+CREATE INDEX idx_role_audit_logs_target_id ON "Role_Audit_Logs" ("target_id");
+CREATE INDEX idx_role_audit_logs_created_at ON "Role_Audit_Logs" ("created_at");
+
+-- Comments
+COMMENT ON COLUMN "Role_Audit_Logs"."actor_id" IS 'Admin who changed the role';
+COMMENT ON COLUMN "Role_Audit_Logs"."target_username" IS 'Kept so the record outlives the user';
+
+-- Role_Audit_Logs foreign keys (records are kept when users are deleted)
+ALTER TABLE "Role_Audit_Logs" 
+  ADD FOREIGN KEY ("actor_id") 
+  REFERENCES "Users" ("id") 
+  ON DELETE SET NULL;
+
+ALTER TABLE "Role_Audit_Logs" 
+  ADD FOREIGN KEY ("target_id") 
+  REFERENCES "Users" ("id") 
+  ON DELETE SET NULL;
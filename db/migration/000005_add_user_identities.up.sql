@@ -0,0 +1,25 @@
+-- ============================================
+-- USER IDENTITIES TABLE (OpenID Connect logins)
+-- ============================================
+CREATE TABLE "User_Identities" (
+  "user_identity_id" bigserial PRIMARY KEY,
+  "user_id" bigint NOT NULL,
+  "issuer" varchar(255) NOT NULL,
+  "subject" varchar(255) NOT NULL,
+  "email" varchar(255) NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+-- User_Identities indexes
+CREATE UNIQUE INDEX idx_user_identities_issuer_subject ON "User_Identities" ("issuer", "subject");
+CREATE INDEX idx_user_identities_user_id ON "User_Identities" ("user_id");
+
+-- Comments
+COMMENT ON COLUMN "User_Identities"."subject" IS 'Stable user ID at the provider (sub claim)';
+COMMENT ON COLUMN "User_Identities"."email" IS 'Email the provider reported when the identity was linked';
+
+-- User_Identities foreign key
+ALTER TABLE "User_Identities" 
+  ADD FOREIGN KEY ("user_id") 
+  REFERENCES "Users" ("id") 
+  ON DELETE CASCADE;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "API_Keys" CASCADE;
+ALTER TABLE "Users" DROP COLUMN IF EXISTS "bot_owner_id";
+ALTER TABLE "Users" DROP COLUMN IF EXISTS "is_bot";
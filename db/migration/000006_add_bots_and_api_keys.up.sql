@@ -0,0 +1,52 @@
+-- ============================================
+-- BOT ACCOUNTS
+-- ============================================
+ALTER TABLE "Users" ADD COLUMN "is_bot" boolean NOT NULL DEFAULT false;
+ALTER TABLE "Users" ADD COLUMN "bot_owner_id" bigint;
+
+-- Users indexes
+CREATE INDEX idx_users_bot_owner_id ON "Users" ("bot_owner_id") WHERE is_bot = true;
+
+-- Comments
+COMMENT ON COLUMN "Users"."bot_owner_id" IS 'User who manages the bot and its API keys';
+
+-- Users bot owner foreign key
+ALTER TABLE "Users" 
+  ADD FOREIGN KEY ("bot_owner_id") 
+  REFERENCES "Users" ("id") 
+  ON DELETE SET NULL;
+
+-- ============================================
+-- API KEYS TABLE
+-- ============================================
+CREATE TABLE "API_Keys" (
+  "api_key_id" bigserial PRIMARY KEY,
+  "user_id" bigint NOT NULL,
+  "name" varchar(100) NOT NULL,
+  "prefix" varchar(16) UNIQUE NOT NULL,
+  "key_hash" varchar(64) NOT NULL,
+  "scopes" text[] NOT NULL,
+  "created_by" bigint,
+  "last_used_at" timestamptz,
+  "expires_at" timestamptz,
+  "revoked_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+-- API_Keys indexes
+CREATE INDEX idx_api_keys_user_id ON "API_Keys" ("user_id");
+
+-- Comments
+COMMENT ON COLUMN "API_Keys"."prefix" IS 'Public part of the key, used to look it up';
+COMMENT ON COLUMN "API_Keys"."key_hash" IS 'sha256 of the whole key';
+
+-- API_Keys foreign keys
+ALTER TABLE "API_Keys" 
+  ADD FOREIGN KEY ("user_id") 
+  REFERENCES "Users" ("id") 
+  ON DELETE CASCADE;
+
+ALTER TABLE "API_Keys" 
+  ADD FOREIGN KEY ("created_by") 
+  REFERENCES "Users" ("id") 
+  ON DELETE SET NULL;
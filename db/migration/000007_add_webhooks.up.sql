@@ -0,0 +1,61 @@
+-- ============================================
+-- WEBHOOKS TABLE
+-- ============================================
+CREATE TABLE "Webhooks" (
+  "webhook_id" bigserial PRIMARY KEY,
+  "owner_id" bigint NOT NULL,
+  "conversation_id" bigint,
+  "url" text NOT NULL,
+  "secret" varchar(64) NOT NULL,
+  "events" text[] NOT NULL,
+  "is_active" boolean NOT NULL DEFAULT true,
+  "disabled_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+-- Webhooks indexes
+CREATE INDEX idx_webhooks_owner_id ON "Webhooks" ("owner_id");
+CREATE INDEX idx_webhooks_conversation_id ON "Webhooks" ("conversation_id") WHERE is_active = true;
+
+-- Comments
+COMMENT ON COLUMN "Webhooks"."conversation_id" IS 'NULL subscribes to events of every conversation (admins only)';
+COMMENT ON COLUMN "Webhooks"."secret" IS 'Signs deliveries with HMAC-SHA256, kept in plain text to sign with';
+
+-- Webhooks foreign keys
+ALTER TABLE "Webhooks" 
+  ADD FOREIGN KEY ("owner_id") 
+  REFERENCES "Users" ("id") 
+  ON DELETE CASCADE;
+
+ALTER TABLE "Webhooks" 
+  ADD FOREIGN KEY ("conversation_id") 
+  REFERENCES "Conversations" ("conversations_id") 
+  ON DELETE CASCADE;
+
+-- ============================================
+-- WEBHOOK DELIVERIES TABLE
+-- ============================================
+CREATE TABLE "Webhook_Deliveries" (
+  "delivery_id" bigserial PRIMARY KEY,
+  "webhook_id" bigint NOT NULL,
+  "event" varchar(50) NOT NULL,
+  "payload" jsonb NOT NULL,
+  "status" varchar(20) NOT NULL DEFAULT 'pending',
+  "attempts" int NOT NULL DEFAULT 0,
+  "response_status" int,
+  "last_error" text,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "delivered_at" timestamptz
+);
+
+-- Webhook_Deliveries indexes
+CREATE INDEX idx_webhook_deliveries_webhook_id ON "Webhook_Deliveries" ("webhook_id", "created_at");
+
+-- Comments
+COMMENT ON COLUMN "Webhook_Deliveries"."status" IS 'pending, succeeded or failed';
+
+-- Webhook_Deliveries foreign keys
+ALTER TABLE "Webhook_Deliveries" 
+  ADD FOREIGN KEY ("webhook_id") 
+  REFERENCES "Webhooks" ("webhook_id") 
+  ON DELETE CASCADE;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS "Incoming_Webhooks" CASCADE;
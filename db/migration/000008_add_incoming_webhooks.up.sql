@@ -0,0 +1,39 @@
+-- ============================================
+-- INCOMING WEBHOOKS TABLE
+-- ============================================
+CREATE TABLE "Incoming_Webhooks" (
+  "incoming_webhook_id" bigserial PRIMARY KEY,
+  "conversation_id" bigint NOT NULL,
+  "bot_user_id" bigint NOT NULL,
+  "name" varchar(100) NOT NULL,
+  "prefix" varchar(16) UNIQUE NOT NULL,
+  "token_hash" varchar(64) NOT NULL,
+  "created_by" bigint,
+  "last_used_at" timestamptz,
+  "revoked_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+-- Incoming_Webhooks indexes
+CREATE INDEX idx_incoming_webhooks_conversation_id ON "Incoming_Webhooks" ("conversation_id");
+
+-- Comments
+COMMENT ON COLUMN "Incoming_Webhooks"."bot_user_id" IS 'Bot the messages are attributed to';
+COMMENT ON COLUMN "Incoming_Webhooks"."prefix" IS 'Public part of the token, used to look it up';
+COMMENT ON COLUMN "Incoming_Webhooks"."token_hash" IS 'sha256 of the whole token';
+
+-- Incoming_Webhooks foreign keys
+ALTER TABLE "Incoming_Webhooks" 
+  ADD FOREIGN KEY ("conversation_id") 
+  REFERENCES "Conversations" ("conversations_id") 
+  ON DELETE CASCADE;
+
+ALTER TABLE "Incoming_Webhooks" 
+  ADD FOREIGN KEY ("bot_user_id") 
+  REFERENCES "Users" ("id") 
+  ON DELETE CASCADE;
+
+ALTER TABLE "Incoming_Webhooks" 
+  ADD FOREIGN KEY ("created_by") 
+  REFERENCES "Users" ("id") 
+  ON DELETE SET NULL;
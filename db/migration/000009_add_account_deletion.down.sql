@@ -0,0 +1 @@
+ALTER TABLE "Users" DROP COLUMN IF EXISTS "deletion_requested_at";
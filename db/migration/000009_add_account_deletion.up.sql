@@ -0,0 +1,7 @@
+ALTER TABLE "Users" ADD COLUMN "deletion_requested_at" timestamptz;
+
+-- Users indexes
+CREATE INDEX idx_users_deletion_requested_at ON "Users" ("deletion_requested_at") WHERE deletion_requested_at IS NOT NULL;
+
+-- Comments
+COMMENT ON COLUMN "Users"."deletion_requested_at" IS 'Set while a deletion request waits for its grace period';
@@ -0,0 +1,30 @@
+-- ============================================
+-- DATA EXPORTS TABLE
+-- ============================================
+CREATE TABLE "Data_Exports" (
+  "export_id" bigserial PRIMARY KEY,
+  "user_id" bigint NOT NULL,
+  "status" varchar(20) NOT NULL DEFAULT 'pending',
+  "file_key" varchar,
+  "prefix" varchar(16) UNIQUE,
+  "token_hash" varchar(64),
+  "expires_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "completed_at" timestamptz
+);
+
+-- Data_Exports indexes
+CREATE INDEX idx_data_exports_user_id ON "Data_Exports" ("user_id");
+CREATE UNIQUE INDEX idx_data_exports_one_pending ON "Data_Exports" ("user_id") WHERE status = 'pending';
+
+-- Comments
+COMMENT ON COLUMN "Data_Exports"."status" IS 'pending, ready or failed';
+COMMENT ON COLUMN "Data_Exports"."file_key" IS 'Where the archive is kept in file storage';
+COMMENT ON COLUMN "Data_Exports"."prefix" IS 'Public part of the download token, used to look it up';
+COMMENT ON COLUMN "Data_Exports"."token_hash" IS 'sha256 of the whole download token';
+
+-- Data_Exports foreign keys
+ALTER TABLE "Data_Exports" 
+  ADD FOREIGN KEY ("user_id") 
+  REFERENCES "Users" ("id") 
+  ON DELETE CASCADE;
@@ -0,0 +1,10 @@
+ALTER TABLE "Password_Resets" DROP CONSTRAINT IF EXISTS "Password_Resets_username_fkey";
+ALTER TABLE "Password_Resets" ADD FOREIGN KEY ("username") REFERENCES "Users" ("username") ON DELETE CASCADE;
+
+ALTER TABLE "Verify_Emails" DROP CONSTRAINT IF EXISTS "Verify_Emails_username_fkey";
+ALTER TABLE "Verify_Emails" ADD FOREIGN KEY ("username") REFERENCES "Users" ("username") ON DELETE CASCADE;
+
+ALTER TABLE "Sessions" DROP CONSTRAINT IF EXISTS "Sessions_username_fkey";
+ALTER TABLE "Sessions" ADD FOREIGN KEY ("username") REFERENCES "Users" ("username") ON DELETE CASCADE;
+
+ALTER TABLE "Users" DROP COLUMN IF EXISTS "username_changed_at";
@@ -0,0 +1,14 @@
+ALTER TABLE "Users" ADD COLUMN "username_changed_at" timestamptz;
+
+-- Comments
+COMMENT ON COLUMN "Users"."username_changed_at" IS 'Last username change, for the change cooldown';
+
+-- rows pointing at a username follow it when it changes
+ALTER TABLE "Sessions" DROP CONSTRAINT IF EXISTS "Sessions_username_fkey";
+ALTER TABLE "Sessions" ADD FOREIGN KEY ("username") REFERENCES "Users" ("username") ON DELETE CASCADE ON UPDATE CASCADE;
+
+ALTER TABLE "Verify_Emails" DROP CONSTRAINT IF EXISTS "Verify_Emails_username_fkey";
+ALTER TABLE "Verify_Emails" ADD FOREIGN KEY ("username") REFERENCES "Users" ("username") ON DELETE CASCADE ON UPDATE CASCADE;
+
+ALTER TABLE "Password_Resets" DROP CONSTRAINT IF EXISTS "Password_Resets_username_fkey";
+ALTER TABLE "Password_Resets" ADD FOREIGN KEY ("username") REFERENCES "Users" ("username") ON DELETE CASCADE ON UPDATE CASCADE;
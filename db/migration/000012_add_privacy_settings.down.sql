@@ -0,0 +1 @@
+DROP TABLE IF EXISTS "Privacy_Settings" CASCADE;
@@ -0,0 +1,24 @@
+-- ============================================
+-- PRIVACY SETTINGS TABLE
+-- ============================================
+CREATE TABLE "Privacy_Settings" (
+  "user_id" bigint PRIMARY KEY,
+  "last_seen" varchar(20) NOT NULL DEFAULT 'everyone',
+  "online" varchar(20) NOT NULL DEFAULT 'everyone',
+  "read_receipts" boolean NOT NULL DEFAULT true,
+  "dm_policy" varchar(20) NOT NULL DEFAULT 'everyone',
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+-- Comments
+COMMENT ON TABLE "Privacy_Settings" IS 'Users without a row use the defaults';
+COMMENT ON COLUMN "Privacy_Settings"."last_seen" IS 'Who sees last_seen_at: everyone, contacts or nobody';
+COMMENT ON COLUMN "Privacy_Settings"."online" IS 'Who sees is_online: everyone, contacts or nobody';
+COMMENT ON COLUMN "Privacy_Settings"."read_receipts" IS 'Whether others see how far the user has read';
+COMMENT ON COLUMN "Privacy_Settings"."dm_policy" IS 'Who can start a direct conversation: everyone, contacts or nobody';
+
+-- Privacy_Settings foreign keys
+ALTER TABLE "Privacy_Settings" 
+  ADD FOREIGN KEY ("user_id") 
+  REFERENCES "Users" ("id") 
+  ON DELETE CASCADE;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS "User_Blocks" CASCADE;
@@ -0,0 +1,27 @@
+-- ============================================
+-- USER BLOCKS TABLE
+-- ============================================
+CREATE TABLE "User_Blocks" (
+  "blocker_id" bigint NOT NULL,
+  "blocked_id" bigint NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("blocker_id", "blocked_id"),
+  CHECK ("blocker_id" <> "blocked_id")
+);
+
+-- User_Blocks indexes
+CREATE INDEX idx_user_blocks_blocked_id ON "User_Blocks" ("blocked_id");
+
+-- Comments
+COMMENT ON TABLE "User_Blocks" IS 'Never shown to the blocked user';
+
+-- User_Blocks foreign keys
+ALTER TABLE "User_Blocks" 
+  ADD FOREIGN KEY ("blocker_id") 
+  REFERENCES "Users" ("id") 
+  ON DELETE CASCADE;
+
+ALTER TABLE "User_Blocks" 
+  ADD FOREIGN KEY ("blocked_id") 
+  REFERENCES "Users" ("id") 
+  ON DELETE CASCADE;
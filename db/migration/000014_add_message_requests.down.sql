@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_conversation_participants_user_inbox;
+
+ALTER TABLE "ConversationParticipants" DROP COLUMN IF EXISTS "inbox";
@@ -0,0 +1,12 @@
+-- ============================================
+-- MESSAGE REQUESTS
+-- ============================================
+ALTER TABLE "ConversationParticipants"
+  ADD COLUMN "inbox" varchar(20) NOT NULL DEFAULT 'primary';
+
+-- ConversationParticipants indexes
+CREATE INDEX idx_conversation_participants_user_inbox
+  ON "ConversationParticipants" ("user_id", "inbox");
+
+-- Comments
+COMMENT ON COLUMN "ConversationParticipants"."inbox" IS 'primary, requests (first contact from a non-contact) or declined';
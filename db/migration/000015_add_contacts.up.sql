@@ -0,0 +1,29 @@
+-- ============================================
+-- CONTACTS TABLE
+-- ============================================
+CREATE TABLE "Contacts" (
+  "owner_id" bigint NOT NULL,
+  "contact_id" bigint NOT NULL,
+  "nickname" varchar(50),
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("owner_id", "contact_id"),
+  CHECK ("owner_id" <> "contact_id")
+);
+
+-- Contacts indexes
+CREATE INDEX idx_contacts_contact_id ON "Contacts" ("contact_id");
+
+-- Comments
+COMMENT ON TABLE "Contacts" IS 'A contact is mutual when both users added each other';
+COMMENT ON COLUMN "Contacts"."nickname" IS 'Only shown to the owner';
+
+-- Contacts foreign keys
+ALTER TABLE "Contacts" 
+  ADD FOREIGN KEY ("owner_id") 
+  REFERENCES "Users" ("id") 
+  ON DELETE CASCADE;
+
+ALTER TABLE "Contacts" 
+  ADD FOREIGN KEY ("contact_id") 
+  REFERENCES "Users" ("id") 
+  ON DELETE CASCADE;
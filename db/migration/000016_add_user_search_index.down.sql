@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_users_username_trgm;
+
+DROP EXTENSION IF EXISTS pg_trgm;
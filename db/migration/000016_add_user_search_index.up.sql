@@ -0,0 +1,8 @@
+-- ============================================
+-- USER SEARCH
+-- ============================================
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Users indexes, serves ILIKE '%q%' as well as similarity (%)
+CREATE INDEX idx_users_username_trgm
+  ON "Users" USING gin ("username" gin_trgm_ops);
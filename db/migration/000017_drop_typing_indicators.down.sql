@@ -0,0 +1,26 @@
+-- ============================================
+-- TYPING INDICATORS TABLE
+-- ============================================
+CREATE TABLE "TypingIndicators" (
+  "typing_indicators_id" bigserial PRIMARY KEY,
+  "conversation_id" bigint NOT NULL,
+  "user_id" bigint NOT NULL,
+  "started_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+-- TypingIndicators indexes
+CREATE UNIQUE INDEX idx_typing_indicators_unique 
+  ON "TypingIndicators" ("conversation_id", "user_id");
+CREATE INDEX idx_typing_indicators_conversation_id 
+  ON "TypingIndicators" ("conversation_id");
+
+-- TypingIndicators foreign keys
+ALTER TABLE "TypingIndicators" 
+  ADD FOREIGN KEY ("conversation_id") 
+  REFERENCES "Conversations" ("conversations_id") 
+  ON DELETE CASCADE;
+
+ALTER TABLE "TypingIndicators" 
+  ADD FOREIGN KEY ("user_id") 
+  REFERENCES "Users" ("id") 
+  ON DELETE CASCADE;
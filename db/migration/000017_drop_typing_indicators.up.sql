@@ -0,0 +1,2 @@
+-- typing state lives in redis or memory now
+DROP TABLE IF EXISTS "TypingIndicators" CASCADE;
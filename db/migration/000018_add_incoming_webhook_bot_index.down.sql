@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_incoming_webhooks_bot_user_id;
@@ -0,0 +1,2 @@
+-- direct message checks look up whether a participant is a webhook's bot
+CREATE INDEX idx_incoming_webhooks_bot_user_id ON "Incoming_Webhooks" ("bot_user_id");
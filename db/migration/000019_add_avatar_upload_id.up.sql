@@ -0,0 +1,4 @@
+ALTER TABLE "Users" ADD COLUMN "avatar_upload_id" varchar;
+
+-- Comments
+COMMENT ON COLUMN "Users"."avatar_upload_id" IS 'Latest accepted avatar upload, older ones processed later are dropped';
@@ -0,0 +1,4 @@
+UPDATE "Data_Exports" SET status = 'ready' WHERE status = 'expired';
+
+-- Comments
+COMMENT ON COLUMN "Data_Exports"."status" IS 'pending, ready or failed';
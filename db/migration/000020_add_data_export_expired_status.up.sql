@@ -0,0 +1,2 @@
+-- Comments
+COMMENT ON COLUMN "Data_Exports"."status" IS 'pending, ready, failed or expired';
@@ -0,0 +1,47 @@
+-- name: CreateAPIKey :one
+INSERT INTO "API_Keys" (
+    user_id,
+    name,
+    prefix,
+    key_hash,
+    scopes,
+    created_by,
+    expires_at
+    ) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+    ) 
+    RETURNING *;
+
+-- name: GetAPIKey :one
+SELECT * FROM "API_Keys"
+WHERE api_key_id = $1;
+
+-- name: GetAPIKeyByPrefix :one
+SELECT * FROM "API_Keys"
+WHERE prefix = $1;
+
+-- name: ListAPIKeys :many
+SELECT * FROM "API_Keys"
+WHERE user_id = $1
+ORDER BY created_at DESC;
+
+-- name: RevokeAPIKey :one
+UPDATE "API_Keys"
+SET revoked_at = now()
+WHERE api_key_id = $1 AND revoked_at IS NULL
+RETURNING *;
+
+-- name: RevokeBotAPIKeysByOwner :exec
+-- revokes every key of the owner's bots
+UPDATE "API_Keys"
+SET revoked_at = now()
+WHERE revoked_at IS NULL
+  AND user_id IN (
+    SELECT id FROM "Users" WHERE is_bot = true AND bot_owner_id = $1
+  );
+
+-- name: TouchAPIKey :exec
+UPDATE "API_Keys"
+SET last_used_at = now()
+WHERE api_key_id = $1
+  AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute');
@@ -0,0 +1,64 @@
+-- name: BlockUser :exec
+INSERT INTO "User_Blocks" (
+    blocker_id,
+    blocked_id
+    ) VALUES (
+    $1, $2
+    )
+ON CONFLICT (blocker_id, blocked_id) DO NOTHING;
+
+-- name: UnblockUser :exec
+DELETE FROM "User_Blocks"
+WHERE blocker_id = $1 AND blocked_id = $2;
+
+-- name: ListBlockedUsers :many
+SELECT b.blocked_id, u.username, u.profile_picture_url, b.created_at
+FROM "User_Blocks" b
+INNER JOIN "Users" u ON u.id = b.blocked_id
+WHERE b.blocker_id = $1
+ORDER BY b.created_at DESC;
+
+-- name: IsBlockedEitherWay :one
+SELECT EXISTS(
+  SELECT 1 FROM "User_Blocks"
+  WHERE (blocker_id = sqlc.arg(user_id) AND blocked_id = sqlc.arg(other_user_id))
+     OR (blocker_id = sqlc.arg(other_user_id) AND blocked_id = sqlc.arg(user_id))
+) as is_blocked;
+
+-- name: ListBlockedEitherWay :many
+SELECT blocked_id AS user_id FROM "User_Blocks"
+WHERE blocker_id = sqlc.arg(user_id)
+  AND blocked_id = ANY(sqlc.arg(user_ids)::bigint[])
+UNION
+SELECT blocker_id FROM "User_Blocks"
+WHERE blocked_id = sqlc.arg(user_id)
+  AND blocker_id = ANY(sqlc.arg(user_ids)::bigint[]);
+
+-- name: IsDirectMessageBlocked :one
+-- conversations of two people count as direct ones, webhook bots don't
+-- count as people and are blocked along with the webhook's creator
+WITH sender AS (
+  -- a webhook speaks for whoever created it
+  SELECT COALESCE(
+    (SELECT h.created_by FROM "Incoming_Webhooks" h
+     WHERE h.bot_user_id = sqlc.arg(sender_id)::bigint),
+    sqlc.arg(sender_id)::bigint
+  ) AS user_id
+), people AS (
+  SELECT cp.user_id
+  FROM "ConversationParticipants" cp
+  WHERE cp.conversation_id = sqlc.arg(conversation_id)
+    AND NOT EXISTS (
+      SELECT 1 FROM "Incoming_Webhooks" h WHERE h.bot_user_id = cp.user_id
+    )
+)
+SELECT EXISTS(
+  SELECT 1
+  FROM people p
+  CROSS JOIN sender s
+  INNER JOIN "User_Blocks" b
+    ON (b.blocker_id = p.user_id AND b.blocked_id = s.user_id)
+    OR (b.blocker_id = s.user_id AND b.blocked_id = p.user_id)
+  WHERE p.user_id <> s.user_id
+    AND (SELECT count(*) FROM people) = 2
+) as is_blocked;
@@ -7,6 +7,8 @@ SELECT
   u.profile_picture_url as other_user_avatar,
   u.is_online as other_user_online,
   u.last_seen_at as other_user_last_seen,
+  ct.nickname as other_user_nickname,
+  (ct.contact_id IS NOT NULL)::boolean as is_contact,
   latest_msg.encrypted_content as last_message_content,
   latest_msg.sent_at as last_message_time,
   latest_msg.sender_id as last_message_sender_id,
@@ -15,6 +17,7 @@ FROM "Conversations" c
 INNER JOIN "ConversationParticipants" cp ON c.conversations_id = cp.conversation_id
 INNER JOIN "ConversationParticipants" other_cp ON c.conversations_id = other_cp.conversation_id AND other_cp.user_id != cp.user_id
 INNER JOIN "Users" u ON other_cp.user_id = u.id
+LEFT JOIN "Contacts" ct ON ct.owner_id = cp.user_id AND ct.contact_id = u.id
 LEFT JOIN LATERAL (
   SELECT messages_id, encrypted_content, sent_at, sender_id
   FROM "Messages"
@@ -25,7 +28,11 @@ LEFT JOIN LATERAL (
 LEFT JOIN "Messages" unread_msg ON unread_msg.conversation_id = c.conversations_id 
   AND unread_msg.sent_at > COALESCE(cp.last_read_at, '1970-01-01'::timestamp)
   AND unread_msg.sender_id != cp.user_id
-WHERE cp.user_id = $1
+WHERE cp.user_id = sqlc.arg(user_id) AND cp.inbox = sqlc.arg(inbox)
+  AND (NOT sqlc.arg(contacts_only)::boolean OR ct.contact_id IS NOT NULL)
+  AND NOT EXISTS (
+    SELECT 1 FROM "Incoming_Webhooks" h WHERE h.bot_user_id = u.id
+  )
 GROUP BY 
   c.conversations_id, 
   c.updated_at,
@@ -34,11 +41,15 @@ GROUP BY
   u.profile_picture_url,
   u.is_online,
   u.last_seen_at,
+  ct.nickname,
+  ct.contact_id,
   latest_msg.encrypted_content,
   latest_msg.sent_at,
   latest_msg.sender_id
-ORDER BY COALESCE(latest_msg.sent_at, c.created_at) DESC
-LIMIT $2 OFFSET $3;
+ORDER BY
+  CASE WHEN sqlc.arg(contacts_first)::boolean AND ct.contact_id IS NOT NULL THEN 0 ELSE 1 END,
+  COALESCE(latest_msg.sent_at, c.created_at) DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
 
 -- name: GetOrCreateDirectConversation :one
 WITH existing_conv AS (
@@ -0,0 +1,50 @@
+-- name: UpsertContact :one
+INSERT INTO "Contacts" (
+    owner_id,
+    contact_id,
+    nickname
+    ) VALUES (
+    $1, $2, $3
+    )
+ON CONFLICT (owner_id, contact_id) DO UPDATE
+SET nickname = EXCLUDED.nickname
+RETURNING *;
+
+-- name: RemoveContact :exec
+DELETE FROM "Contacts"
+WHERE owner_id = $1 AND contact_id = $2;
+
+-- name: IsContact :one
+SELECT EXISTS(
+  SELECT 1 FROM "Contacts"
+  WHERE owner_id = $1 AND contact_id = $2
+) as is_contact;
+
+-- name: ListContacts :many
+SELECT
+  c.contact_id,
+  c.nickname,
+  c.created_at,
+  u.username,
+  u.profile_picture_url,
+  EXISTS(
+    SELECT 1 FROM "Contacts" m
+    WHERE m.owner_id = c.contact_id AND m.contact_id = c.owner_id
+  ) as is_mutual
+FROM "Contacts" c
+INNER JOIN "Users" u ON u.id = c.contact_id
+WHERE c.owner_id = $1
+  AND u.deletion_requested_at IS NULL
+ORDER BY lower(COALESCE(c.nickname, u.username));
+
+-- name: ListContactIDs :many
+-- which of user_ids owner_id added
+SELECT contact_id FROM "Contacts"
+WHERE owner_id = sqlc.arg(owner_id)
+  AND contact_id = ANY(sqlc.arg(user_ids)::bigint[]);
+
+-- name: ListContactOwnerIDs :many
+-- which of user_ids added contact_id
+SELECT owner_id FROM "Contacts"
+WHERE contact_id = sqlc.arg(contact_id)
+  AND owner_id = ANY(sqlc.arg(user_ids)::bigint[]);
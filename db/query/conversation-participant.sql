@@ -20,6 +20,16 @@ FROM "ConversationParticipants" cp
 INNER JOIN "Users" u ON cp.user_id = u.id
 WHERE cp.conversation_id = $1;
 
+-- name: GetConversationParticipant :one
+SELECT * FROM "ConversationParticipants"
+WHERE conversation_id = $1 AND user_id = $2;
+
+-- name: UpdateParticipantInbox :one
+UPDATE "ConversationParticipants"
+SET inbox = $3
+WHERE conversation_id = $1 AND user_id = $2
+RETURNING *;
+
 -- name: UpdateLastReadAt :exec
 UPDATE "ConversationParticipants"
 SET last_read_at = now()
@@ -43,3 +53,27 @@ SELECT EXISTS(
 -- name: RemoveParticipantFromConversation :exec
 DELETE FROM "ConversationParticipants"
 WHERE conversation_id = $1 AND user_id = $2;
+
+-- name: RemoveUserFromAllConversations :exec
+DELETE FROM "ConversationParticipants"
+WHERE user_id = $1;
+
+-- name: ListConversationPeople :many
+-- participants other than webhook bots, in the order they joined
+SELECT cp.* FROM "ConversationParticipants" cp
+WHERE cp.conversation_id = $1
+  AND NOT EXISTS (
+    SELECT 1 FROM "Incoming_Webhooks" h WHERE h.bot_user_id = cp.user_id
+  )
+ORDER BY cp.joined_at, cp.user_id;
+
+-- name: ListPendingRequestUserIDs :many
+-- users that haven't accepted a message request from user_id
+SELECT DISTINCT other.user_id
+FROM "ConversationParticipants" mine
+INNER JOIN "ConversationParticipants" other
+  ON mine.conversation_id = other.conversation_id
+WHERE mine.user_id = sqlc.arg(user_id)
+  AND other.user_id = ANY(sqlc.arg(user_ids)::bigint[])
+  AND mine.inbox = 'primary'
+  AND other.inbox <> 'primary';
@@ -0,0 +1,42 @@
+-- name: CreateDataExport :one
+INSERT INTO "Data_Exports" (
+    user_id
+    ) VALUES (
+    $1
+    ) 
+    RETURNING *;
+
+-- name: GetDataExport :one
+SELECT * FROM "Data_Exports"
+WHERE export_id = $1;
+
+-- name: GetDataExportByPrefix :one
+SELECT * FROM "Data_Exports"
+WHERE prefix = $1;
+
+-- name: ListDataExportsByUser :many
+SELECT * FROM "Data_Exports"
+WHERE user_id = $1
+ORDER BY created_at DESC;
+
+-- name: CompleteDataExport :one
+UPDATE "Data_Exports"
+SET status = 'ready',
+    file_key = $2,
+    prefix = $3,
+    token_hash = $4,
+    expires_at = $5,
+    completed_at = now()
+WHERE export_id = $1 AND status = 'pending'
+RETURNING *;
+
+-- name: FailDataExport :exec
+UPDATE "Data_Exports"
+SET status = 'failed',
+    completed_at = now()
+WHERE export_id = $1 AND status = 'pending';
+
+-- name: ExpireDataExport :exec
+UPDATE "Data_Exports"
+SET status = 'expired'
+WHERE export_id = $1 AND status = 'ready';
@@ -0,0 +1,42 @@
+-- name: CreateIncomingWebhook :one
+INSERT INTO "Incoming_Webhooks" (
+    conversation_id,
+    bot_user_id,
+    name,
+    prefix,
+    token_hash,
+    created_by
+    ) VALUES (
+    $1, $2, $3, $4, $5, $6
+    ) 
+    RETURNING *;
+
+-- name: GetIncomingWebhook :one
+SELECT * FROM "Incoming_Webhooks"
+WHERE incoming_webhook_id = $1;
+
+-- name: GetIncomingWebhookByPrefix :one
+SELECT * FROM "Incoming_Webhooks"
+WHERE prefix = $1;
+
+-- name: ListIncomingWebhooks :many
+SELECT * FROM "Incoming_Webhooks"
+WHERE conversation_id = $1
+ORDER BY created_at DESC;
+
+-- name: RevokeIncomingWebhook :one
+UPDATE "Incoming_Webhooks"
+SET revoked_at = now()
+WHERE incoming_webhook_id = $1 AND revoked_at IS NULL
+RETURNING *;
+
+-- name: RevokeIncomingWebhooksByCreator :exec
+UPDATE "Incoming_Webhooks"
+SET revoked_at = now()
+WHERE created_by = $1 AND revoked_at IS NULL;
+
+-- name: TouchIncomingWebhook :exec
+UPDATE "Incoming_Webhooks"
+SET last_used_at = now()
+WHERE incoming_webhook_id = $1
+  AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute');
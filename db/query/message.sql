@@ -102,4 +102,8 @@ INNER JOIN "Users" u ON m.sender_id = u.id
 WHERE m.conversation_id = $1
   AND m.encrypted_content ILIKE $2
 ORDER BY m.sent_at DESC
-LIMIT $3;
\ No newline at end of file
+LIMIT $3;
+
+-- name: DeleteMessagesBySender :exec
+DELETE FROM "Messages"
+WHERE sender_id = $1;
@@ -0,0 +1,25 @@
+-- name: CreatePasswordReset :one
+INSERT INTO "Password_Resets" (
+    username,
+    email,
+    secret_code
+    ) VALUES (
+    $1, $2, $3
+    ) 
+    RETURNING *;
+
+-- name: UsePasswordReset :one
+UPDATE "Password_Resets"
+SET
+    is_used = true
+WHERE
+    email = @email
+    AND secret_code = @secret_code
+    AND is_used = FALSE
+    AND expired_at > now()
+    RETURNING *;
+
+-- name: InvalidatePasswordResets :exec
+UPDATE "Password_Resets"
+SET is_used = true
+WHERE username = $1 AND is_used = false;
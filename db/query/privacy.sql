@@ -0,0 +1,25 @@
+-- name: GetPrivacySettings :one
+SELECT * FROM "Privacy_Settings"
+WHERE user_id = $1;
+
+-- name: ListPrivacySettings :many
+SELECT * FROM "Privacy_Settings"
+WHERE user_id = ANY(sqlc.arg(user_ids)::bigint[]);
+
+-- name: UpsertPrivacySettings :one
+INSERT INTO "Privacy_Settings" (
+    user_id,
+    last_seen,
+    online,
+    read_receipts,
+    dm_policy
+    ) VALUES (
+    $1, $2, $3, $4, $5
+    ) 
+ON CONFLICT (user_id) DO UPDATE
+SET last_seen = EXCLUDED.last_seen,
+    online = EXCLUDED.online,
+    read_receipts = EXCLUDED.read_receipts,
+    dm_policy = EXCLUDED.dm_policy,
+    updated_at = now()
+RETURNING *;
@@ -0,0 +1,16 @@
+-- name: CreateRoleAuditLog :one
+INSERT INTO "Role_Audit_Logs" (
+    actor_id,
+    target_id,
+    target_username,
+    old_role,
+    new_role
+    ) VALUES (
+    $1, $2, $3, $4, $5
+    ) 
+    RETURNING *;
+
+-- name: ListRoleAuditLogs :many
+SELECT * FROM "Role_Audit_Logs"
+ORDER BY created_at DESC
+LIMIT $1 OFFSET $2;
@@ -13,4 +13,19 @@ WHERE id = $1 LIMIT 1;
 -- name: BlockUserSessions :exec
 UPDATE "Sessions"
 SET is_blocked = true
-WHERE username = $1 AND is_blocked = false;
\ No newline at end of file
+WHERE username = $1 AND is_blocked = false;
+
+-- name: BlockOtherUserSessions :many
+UPDATE "Sessions"
+SET is_blocked = true
+WHERE username = $1 AND id != $2 AND is_blocked = false
+RETURNING id;
+
+-- name: DeleteUserSessions :exec
+DELETE FROM "Sessions"
+WHERE username = $1;
+
+-- name: ListUserSessions :many
+SELECT * FROM "Sessions"
+WHERE username = $1
+ORDER BY created_at DESC;
@@ -0,0 +1,55 @@
+-- name: UpsertTwoFactorSecret :one
+INSERT INTO "Two_Factor_Auth" (
+  user_id,
+  secret
+) VALUES (
+  $1, $2
+)
+ON CONFLICT (user_id)
+DO UPDATE SET secret = EXCLUDED.secret,
+              last_used_step = 0,
+              created_at = now()
+WHERE "Two_Factor_Auth".is_enabled = false
+RETURNING *;
+
+-- name: GetTwoFactorAuth :one
+SELECT * FROM "Two_Factor_Auth"
+WHERE user_id = $1;
+
+-- name: EnableTwoFactorAuth :one
+UPDATE "Two_Factor_Auth"
+SET is_enabled = true,
+    confirmed_at = now(),
+    last_used_step = $2
+WHERE user_id = $1
+RETURNING *;
+
+-- name: UseTwoFactorStep :execrows
+UPDATE "Two_Factor_Auth"
+SET last_used_step = $2
+WHERE user_id = $1 AND last_used_step < $2;
+
+-- name: DeleteTwoFactorAuth :exec
+DELETE FROM "Two_Factor_Auth"
+WHERE user_id = $1;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO "Recovery_Codes" (
+  user_id,
+  code_hash
+) VALUES (
+  $1, $2
+);
+
+-- name: UseRecoveryCode :execrows
+UPDATE "Recovery_Codes"
+SET used_at = now()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+
+-- name: CountUnusedRecoveryCodes :one
+SELECT COUNT(*) FROM "Recovery_Codes"
+WHERE user_id = $1 AND used_at IS NULL;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM "Recovery_Codes"
+WHERE user_id = $1;
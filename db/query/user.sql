@@ -28,23 +28,100 @@ SET is_online = $2,
     last_seen_at = CASE WHEN $2 = false THEN now() ELSE last_seen_at END
 WHERE id = $1;
 
--- name: UpdateUserProfile :exec
+-- name: MarkUserOnline :exec
+-- Called when a heartbeat brings a user online
 UPDATE "Users"
-SET profile_picture_url = COALESCE($2, profile_picture_url),
-    username = COALESCE($3, username)
+SET is_online = true,
+    last_seen_at = now()
 WHERE id = $1;
 
+-- name: MarkUserOffline :exec
+-- Skipped when the user came back online after last_seen_at
+UPDATE "Users"
+SET is_online = false,
+    last_seen_at = sqlc.arg(last_seen_at)
+WHERE id = sqlc.arg(id)
+  AND (last_seen_at IS NULL OR last_seen_at <= sqlc.arg(last_seen_at));
+
+-- name: UpdateUserProfile :one
+UPDATE "Users"
+SET profile_picture_url = COALESCE(sqlc.narg(profile_picture_url), profile_picture_url),
+    username = COALESCE(sqlc.narg(username), username),
+    username_changed_at = CASE
+      WHEN sqlc.narg(username) IS NOT NULL AND sqlc.narg(username) <> username THEN now()
+      ELSE username_changed_at
+    END
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: SetAvatarUploadID :exec
+UPDATE "Users"
+SET avatar_upload_id = sqlc.arg(upload_id)
+WHERE id = sqlc.arg(id);
+
+-- name: ReplaceAvatar :one
+-- only while the upload is still the latest one, returns the replaced url
+WITH old AS (
+  SELECT id, profile_picture_url FROM "Users"
+  WHERE id = sqlc.arg(id) AND avatar_upload_id = sqlc.arg(upload_id)
+  FOR UPDATE
+)
+UPDATE "Users" u
+SET profile_picture_url = sqlc.arg(profile_picture_url)
+FROM old
+WHERE u.id = old.id
+RETURNING old.profile_picture_url;
+
 -- name: GetOnlineUsers :many
 SELECT id, username, profile_picture_url, last_seen_at
 FROM "Users"
 WHERE is_online = true
 ORDER BY last_seen_at DESC;
 
--- name: SearchUsersByUsername :many
-SELECT id, username, email, profile_picture_url, is_online
+-- name: ListPublicProfiles :many
+SELECT id, username, profile_picture_url, is_online, last_seen_at, is_bot
 FROM "Users"
-WHERE username ILIKE $1
-LIMIT $2;
+WHERE id = ANY(sqlc.arg(ids)::bigint[])
+  AND deletion_requested_at IS NULL
+ORDER BY id;
+
+-- name: SearchUsers :many
+-- Ranks exact over prefix over fuzzy matches, contacts first within each,
+-- and pages by (tier, username)
+WITH candidates AS (
+  SELECT u.id FROM "Users" u
+  WHERE u.username ILIKE sqlc.arg(contains) OR u.username % sqlc.arg(query)::text
+  UNION
+  SELECT ct.contact_id FROM "Contacts" ct
+  WHERE ct.owner_id = sqlc.arg(viewer_id)
+    AND (ct.nickname ILIKE sqlc.arg(contains) OR ct.nickname % sqlc.arg(query)::text)
+), ranked AS (
+  SELECT u.id, u.username, u.profile_picture_url, u.is_online, u.is_bot,
+    ct.nickname, (ct.contact_id IS NOT NULL)::boolean as is_contact,
+    (CASE
+      WHEN lower(u.username) = lower(sqlc.arg(query)::text)
+        OR lower(ct.nickname) = lower(sqlc.arg(query)::text) THEN 0
+      WHEN u.username ILIKE sqlc.arg(prefix) OR ct.nickname ILIKE sqlc.arg(prefix) THEN 2
+      ELSE 4
+    END + CASE WHEN ct.contact_id IS NULL THEN 1 ELSE 0 END)::int as tier
+  FROM candidates c
+  INNER JOIN "Users" u ON u.id = c.id
+  LEFT JOIN "Contacts" ct ON ct.owner_id = sqlc.arg(viewer_id) AND ct.contact_id = u.id
+  WHERE u.id <> sqlc.arg(viewer_id)
+    AND u.is_banned = false
+    AND u.deletion_requested_at IS NULL
+    AND NOT EXISTS (
+      SELECT 1 FROM "User_Blocks" b
+      WHERE (b.blocker_id = sqlc.arg(viewer_id) AND b.blocked_id = u.id)
+         OR (b.blocker_id = u.id AND b.blocked_id = sqlc.arg(viewer_id))
+    )
+    AND (NOT sqlc.arg(contacts_only)::boolean OR ct.contact_id IS NOT NULL)
+)
+SELECT id, username, profile_picture_url, is_online, is_bot, nickname, is_contact, tier
+FROM ranked
+WHERE (tier, username) > (sqlc.arg(after_tier)::int, sqlc.arg(after_username)::text)
+ORDER BY tier, username
+LIMIT sqlc.arg('limit');
 
 -- name: GetAllUsers :many
 SELECT * FROM "Users" 
@@ -73,4 +150,64 @@ email = COALESCE(sqlc.narg(email), email),
 is_email_verified = COALESCE(sqlc.narg(is_email_verified), is_email_verified)
 WHERE
 username = sqlc.arg(username)
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+
+-- name: GetUserByIDForUpdate :one
+SELECT * FROM "Users"
+WHERE id = $1
+FOR UPDATE;
+
+-- name: ListAdminIDsForUpdate :many
+SELECT id FROM "Users"
+WHERE role = sqlc.arg(admin_role)
+ORDER BY id
+FOR UPDATE;
+
+-- name: CountActiveAdmins :one
+-- admins who can still log in and act: not banned, not leaving, not bots
+SELECT count(*) FROM "Users"
+WHERE role = sqlc.arg(admin_role)
+  AND is_banned = false
+  AND deletion_requested_at IS NULL
+  AND is_bot = false;
+
+-- name: UpdateUserRole :one
+UPDATE "Users"
+SET role = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: CreateBot :one
+INSERT INTO "Users" (
+  username,
+  email,
+  password_hash,
+  role,
+  is_email_verified,
+  is_bot,
+  bot_owner_id
+) VALUES (
+  $1, $2, $3, $4, true, true, $5
+)
+RETURNING *;
+
+-- name: ListBotsByOwner :many
+SELECT * FROM "Users"
+WHERE is_bot = true AND bot_owner_id = $1
+ORDER BY created_at DESC;
+
+-- name: RequestUserDeletion :one
+UPDATE "Users"
+SET deletion_requested_at = now(),
+    is_online = false
+WHERE id = $1
+RETURNING *;
+
+-- name: CancelUserDeletion :exec
+UPDATE "Users"
+SET deletion_requested_at = NULL
+WHERE id = $1;
+
+-- name: DeleteUser :exec
+DELETE FROM "Users"
+WHERE id = $1;
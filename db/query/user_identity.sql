@@ -0,0 +1,14 @@
+-- name: CreateUserIdentity :one
+INSERT INTO "User_Identities" (
+    user_id,
+    issuer,
+    subject,
+    email
+    ) VALUES (
+    $1, $2, $3, $4
+    ) 
+    RETURNING *;
+
+-- name: GetUserIdentity :one
+SELECT * FROM "User_Identities"
+WHERE issuer = $1 AND subject = $2;
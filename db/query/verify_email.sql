@@ -17,4 +17,8 @@ WHERE
     AND secret_code = @secret_code
     AND is_used = FALSE
     AND expired_at > now()
-    RETURNING *;
\ No newline at end of file
+    RETURNING *;
+
+-- name: DeleteVerifyEmails :exec
+DELETE FROM "Verify_Emails"
+WHERE username = $1;
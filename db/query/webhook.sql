@@ -0,0 +1,74 @@
+-- name: CreateWebhook :one
+INSERT INTO "Webhooks" (
+    owner_id,
+    conversation_id,
+    url,
+    secret,
+    events
+    ) VALUES (
+    $1, $2, $3, $4, $5
+    ) 
+    RETURNING *;
+
+-- name: GetWebhook :one
+SELECT * FROM "Webhooks"
+WHERE webhook_id = $1;
+
+-- name: ListWebhooksByOwner :many
+SELECT * FROM "Webhooks"
+WHERE owner_id = $1
+ORDER BY created_at DESC;
+
+-- name: ListWebhooksForEvent :many
+-- owners have to still be allowed the webhook: not banned or leaving,
+-- in the conversation or, for global webhooks, still an admin
+SELECT w.* FROM "Webhooks" w
+INNER JOIN "Users" u ON u.id = w.owner_id
+WHERE w.is_active = true
+  AND sqlc.arg(event)::text = ANY(w.events)
+  AND u.is_banned = false
+  AND u.deletion_requested_at IS NULL
+  AND (
+    (w.conversation_id IS NULL AND u.role = sqlc.arg(admin_role)::text)
+    OR (w.conversation_id = sqlc.narg(conversation_id) AND EXISTS (
+      SELECT 1 FROM "ConversationParticipants" cp
+      WHERE cp.conversation_id = w.conversation_id AND cp.user_id = w.owner_id
+    ))
+  );
+
+-- name: DisableWebhook :one
+UPDATE "Webhooks"
+SET is_active = false,
+    disabled_at = now()
+WHERE webhook_id = $1 AND is_active = true
+RETURNING *;
+
+-- name: CreateWebhookDelivery :one
+INSERT INTO "Webhook_Deliveries" (
+    webhook_id,
+    event,
+    payload
+    ) VALUES (
+    $1, $2, $3
+    ) 
+    RETURNING *;
+
+-- name: GetWebhookDelivery :one
+SELECT * FROM "Webhook_Deliveries"
+WHERE delivery_id = $1;
+
+-- name: ListWebhookDeliveries :many
+SELECT * FROM "Webhook_Deliveries"
+WHERE webhook_id = $1
+ORDER BY created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: RecordWebhookAttempt :one
+UPDATE "Webhook_Deliveries"
+SET attempts = attempts + 1,
+    status = sqlc.arg(status),
+    response_status = sqlc.narg(response_status),
+    last_error = sqlc.narg(last_error),
+    delivered_at = CASE WHEN sqlc.arg(status) = 'succeeded' THEN now() ELSE delivered_at END
+WHERE delivery_id = sqlc.arg(delivery_id)
+RETURNING *;
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type ChangeEmailTxParams struct {
+	Username string
+	NewEmail string
+	// e.g. send confirmation and notification emails
+	AfterUpdate func(oldUser User, newUser User) error
+}
+
+type ChangeEmailTxResults struct {
+	User User
+}
+
+// ChangeEmailTx sets a new email address and marks it unverified
+func (store *SQLStore) ChangeEmailTx(ctx context.Context,
+	arg ChangeEmailTxParams) (ChangeEmailTxResults, error) {
+	var result ChangeEmailTxResults
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		oldUser, err := q.GetUserByUsername(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		result.User, err = q.UpdateUser(ctx, UpdateUserParams{
+			Username: arg.Username,
+			Email: pgtype.Text{
+				String: arg.NewEmail,
+				Valid:  true,
+			},
+			IsEmailVerified: pgtype.Bool{
+				Bool:  false,
+				Valid: true,
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		return arg.AfterUpdate(oldUser, result.User)
+	})
+
+	return result, err
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type ChangePasswordTxParams struct {
+	Username     string
+	PasswordHash string
+	// session that made the change, it stays active
+	CurrentSessionID uuid.UUID
+}
+
+type ChangePasswordTxResults struct {
+	User              User
+	BlockedSessionIDs []uuid.UUID
+}
+
+// ChangePasswordTx sets a new password and blocks
+// every other session of the user
+func (store *SQLStore) ChangePasswordTx(ctx context.Context,
+	arg ChangePasswordTxParams) (ChangePasswordTxResults, error) {
+	var result ChangePasswordTxResults
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.User, err = q.UpdateUser(ctx, UpdateUserParams{
+			Username: arg.Username,
+			PasswordHash: pgtype.Text{
+				String: arg.PasswordHash,
+				Valid:  true,
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		result.BlockedSessionIDs, err = q.BlockOtherUserSessions(ctx,
+			BlockOtherUserSessionsParams{
+				Username: arg.Username,
+				ID:       arg.CurrentSessionID,
+			})
+
+		return err
+	})
+
+	return result, err
+}
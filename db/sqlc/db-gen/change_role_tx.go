@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	ErrLastAdmin     = errors.New("cannot remove the last admin")
+	ErrRoleUnchanged = errors.New("user already has this role")
+)
+
+type ChangeRoleTxParams struct {
+	// admin making the change
+	ActorID  int64
+	TargetID int64
+	NewRole  string
+	// role of which at least one user has to remain
+	AdminRole string
+}
+
+type ChangeRoleTxResults struct {
+	User     User
+	AuditLog RoleAuditLog
+}
+
+// ChangeRoleTx promotes or demotes a user and records who did it.
+// Admin rows are locked first so two concurrent demotions
+// can't leave the app without an admin who can act.
+func (store *SQLStore) ChangeRoleTx(ctx context.Context,
+	arg ChangeRoleTxParams) (ChangeRoleTxResults, error) {
+	var result ChangeRoleTxResults
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		_, err := q.ListAdminIDsForUpdate(ctx, arg.AdminRole)
+		if err != nil {
+			return err
+		}
+
+		target, err := q.GetUserByIDForUpdate(ctx, arg.TargetID)
+		if err != nil {
+			return err
+		}
+		if target.Role == arg.NewRole {
+			return ErrRoleUnchanged
+		}
+		last, err := isLastActiveAdmin(ctx, q, target, arg.AdminRole)
+		if err != nil {
+			return err
+		}
+		if last {
+			return ErrLastAdmin
+		}
+
+		result.User, err = q.UpdateUserRole(ctx, UpdateUserRoleParams{
+			ID:   target.ID,
+			Role: arg.NewRole,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.AuditLog, err = q.CreateRoleAuditLog(ctx, CreateRoleAuditLogParams{
+			ActorID:        pgtype.Int8{Int64: arg.ActorID, Valid: true},
+			TargetID:       pgtype.Int8{Int64: target.ID, Valid: true},
+			TargetUsername: target.Username,
+			OldRole:        target.Role,
+			NewRole:        arg.NewRole,
+		})
+		return err
+	})
+
+	return result, err
+}
+
+// reports whether user is the only admin left who can act, banned
+// admins, ones pending deletion and bots can't. Callers lock the admin
+// rows with ListAdminIDsForUpdate first.
+func isLastActiveAdmin(ctx context.Context, q *Queries, user User,
+	adminRole string) (bool, error) {
+	if user.Role != adminRole || user.IsBanned ||
+		user.DeletionRequestedAt.Valid || user.IsBot {
+		return false, nil
+	}
+
+	count, err := q.CountActiveAdmins(ctx, adminRole)
+	if err != nil {
+		return false, err
+	}
+
+	return count <= 1, nil
+}
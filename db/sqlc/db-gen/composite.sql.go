@@ -69,6 +69,8 @@ SELECT
   u.profile_picture_url as other_user_avatar,
   u.is_online as other_user_online,
   u.last_seen_at as other_user_last_seen,
+  ct.nickname as other_user_nickname,
+  (ct.contact_id IS NOT NULL)::boolean as is_contact,
   latest_msg.encrypted_content as last_message_content,
   latest_msg.sent_at as last_message_time,
   latest_msg.sender_id as last_message_sender_id,
@@ -77,6 +79,7 @@ FROM "Conversations" c
 INNER JOIN "ConversationParticipants" cp ON c.conversations_id = cp.conversation_id
 INNER JOIN "ConversationParticipants" other_cp ON c.conversations_id = other_cp.conversation_id AND other_cp.user_id != cp.user_id
 INNER JOIN "Users" u ON other_cp.user_id = u.id
+LEFT JOIN "Contacts" ct ON ct.owner_id = cp.user_id AND ct.contact_id = u.id
 LEFT JOIN LATERAL (
   SELECT messages_id, encrypted_content, sent_at, sender_id
   FROM "Messages"
@@ -87,7 +90,11 @@ LEFT JOIN LATERAL (
 LEFT JOIN "Messages" unread_msg ON unread_msg.conversation_id = c.conversations_id 
   AND unread_msg.sent_at > COALESCE(cp.last_read_at, '1970-01-01'::timestamp)
   AND unread_msg.sender_id != cp.user_id
-WHERE cp.user_id = $1
+WHERE cp.user_id = $1 AND cp.inbox = $2
+  AND (NOT $3::boolean OR ct.contact_id IS NOT NULL)
+  AND NOT EXISTS (
+    SELECT 1 FROM "Incoming_Webhooks" h WHERE h.bot_user_id = u.id
+  )
 GROUP BY 
   c.conversations_id, 
   c.updated_at,
@@ -96,17 +103,24 @@ GROUP BY
   u.profile_picture_url,
   u.is_online,
   u.last_seen_at,
+  ct.nickname,
+  ct.contact_id,
   latest_msg.encrypted_content,
   latest_msg.sent_at,
   latest_msg.sender_id
-ORDER BY COALESCE(latest_msg.sent_at, c.created_at) DESC
-LIMIT $2 OFFSET $3
+ORDER BY
+  CASE WHEN $4::boolean AND ct.contact_id IS NOT NULL THEN 0 ELSE 1 END,
+  COALESCE(latest_msg.sent_at, c.created_at) DESC
+LIMIT $5 OFFSET $6
 `
 
 type GetUserConversationsWithLastMessageParams struct {
-	UserID int64 `json:"user_id"`
-	Limit  int32 `json:"limit"`
-	Offset int32 `json:"offset"`
+	UserID        int64  `json:"user_id"`
+	Inbox         string `json:"inbox"`
+	ContactsOnly  bool   `json:"contacts_only"`
+	ContactsFirst bool   `json:"contacts_first"`
+	Limit         int32  `json:"limit"`
+	Offset        int32  `json:"offset"`
 }
 
 type GetUserConversationsWithLastMessageRow struct {
@@ -117,6 +131,8 @@ type GetUserConversationsWithLastMessageRow struct {
 	OtherUserAvatar     pgtype.Text        `json:"other_user_avatar"`
 	OtherUserOnline     bool               `json:"other_user_online"`
 	OtherUserLastSeen   pgtype.Timestamptz `json:"other_user_last_seen"`
+	OtherUserNickname   pgtype.Text        `json:"other_user_nickname"`
+	IsContact           bool               `json:"is_contact"`
 	LastMessageContent  string             `json:"last_message_content"`
 	LastMessageTime     time.Time          `json:"last_message_time"`
 	LastMessageSenderID int64              `json:"last_message_sender_id"`
@@ -124,7 +140,14 @@ type GetUserConversationsWithLastMessageRow struct {
 }
 
 func (q *Queries) GetUserConversationsWithLastMessage(ctx context.Context, arg GetUserConversationsWithLastMessageParams) ([]GetUserConversationsWithLastMessageRow, error) {
-	rows, err := q.db.Query(ctx, getUserConversationsWithLastMessage, arg.UserID, arg.Limit, arg.Offset)
+	rows, err := q.db.Query(ctx, getUserConversationsWithLastMessage,
+		arg.UserID,
+		arg.Inbox,
+		arg.ContactsOnly,
+		arg.ContactsFirst,
+		arg.Limit,
+		arg.Offset,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -140,6 +163,8 @@ func (q *Queries) GetUserConversationsWithLastMessage(ctx context.Context, arg G
 			&i.OtherUserAvatar,
 			&i.OtherUserOnline,
 			&i.OtherUserLastSeen,
+			&i.OtherUserNickname,
+			&i.IsContact,
 			&i.LastMessageContent,
 			&i.LastMessageTime,
 			&i.LastMessageSenderID,
@@ -19,7 +19,7 @@ INSERT INTO "ConversationParticipants" (
 ) VALUES (
   $1, $2
 )
-RETURNING conversation_participants_id, conversation_id, user_id, last_read_at, joined_at
+RETURNING conversation_participants_id, conversation_id, user_id, last_read_at, joined_at, inbox
 `
 
 type AddParticipantToConversationParams struct {
@@ -36,6 +36,31 @@ func (q *Queries) AddParticipantToConversation(ctx context.Context, arg AddParti
 		&i.UserID,
 		&i.LastReadAt,
 		&i.JoinedAt,
+		&i.Inbox,
+	)
+	return i, err
+}
+
+const getConversationParticipant = `-- name: GetConversationParticipant :one
+SELECT conversation_participants_id, conversation_id, user_id, last_read_at, joined_at, inbox FROM "ConversationParticipants"
+WHERE conversation_id = $1 AND user_id = $2
+`
+
+type GetConversationParticipantParams struct {
+	ConversationID int64 `json:"conversation_id"`
+	UserID         int64 `json:"user_id"`
+}
+
+func (q *Queries) GetConversationParticipant(ctx context.Context, arg GetConversationParticipantParams) (ConversationParticipant, error) {
+	row := q.db.QueryRow(ctx, getConversationParticipant, arg.ConversationID, arg.UserID)
+	var i ConversationParticipant
+	err := row.Scan(
+		&i.ConversationParticipantsID,
+		&i.ConversationID,
+		&i.UserID,
+		&i.LastReadAt,
+		&i.JoinedAt,
+		&i.Inbox,
 	)
 	return i, err
 }
@@ -133,6 +158,80 @@ func (q *Queries) IsUserInConversation(ctx context.Context, arg IsUserInConversa
 	return is_participant, err
 }
 
+const listConversationPeople = `-- name: ListConversationPeople :many
+SELECT cp.conversation_participants_id, cp.conversation_id, cp.user_id, cp.last_read_at, cp.joined_at, cp.inbox FROM "ConversationParticipants" cp
+WHERE cp.conversation_id = $1
+  AND NOT EXISTS (
+    SELECT 1 FROM "Incoming_Webhooks" h WHERE h.bot_user_id = cp.user_id
+  )
+ORDER BY cp.joined_at, cp.user_id
+`
+
+// participants other than webhook bots, in the order they joined
+func (q *Queries) ListConversationPeople(ctx context.Context, conversationID int64) ([]ConversationParticipant, error) {
+	rows, err := q.db.Query(ctx, listConversationPeople, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ConversationParticipant{}
+	for rows.Next() {
+		var i ConversationParticipant
+		if err := rows.Scan(
+			&i.ConversationParticipantsID,
+			&i.ConversationID,
+			&i.UserID,
+			&i.LastReadAt,
+			&i.JoinedAt,
+			&i.Inbox,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPendingRequestUserIDs = `-- name: ListPendingRequestUserIDs :many
+SELECT DISTINCT other.user_id
+FROM "ConversationParticipants" mine
+INNER JOIN "ConversationParticipants" other
+  ON mine.conversation_id = other.conversation_id
+WHERE mine.user_id = $1
+  AND other.user_id = ANY($2::bigint[])
+  AND mine.inbox = 'primary'
+  AND other.inbox <> 'primary'
+`
+
+type ListPendingRequestUserIDsParams struct {
+	UserID  int64   `json:"user_id"`
+	UserIds []int64 `json:"user_ids"`
+}
+
+// users that haven't accepted a message request from user_id
+func (q *Queries) ListPendingRequestUserIDs(ctx context.Context, arg ListPendingRequestUserIDsParams) ([]int64, error) {
+	rows, err := q.db.Query(ctx, listPendingRequestUserIDs, arg.UserID, arg.UserIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int64{}
+	for rows.Next() {
+		var user_id int64
+		if err := rows.Scan(&user_id); err != nil {
+			return nil, err
+		}
+		items = append(items, user_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const removeParticipantFromConversation = `-- name: RemoveParticipantFromConversation :exec
 DELETE FROM "ConversationParticipants"
 WHERE conversation_id = $1 AND user_id = $2
@@ -148,6 +247,16 @@ func (q *Queries) RemoveParticipantFromConversation(ctx context.Context, arg Rem
 	return err
 }
 
+const removeUserFromAllConversations = `-- name: RemoveUserFromAllConversations :exec
+DELETE FROM "ConversationParticipants"
+WHERE user_id = $1
+`
+
+func (q *Queries) RemoveUserFromAllConversations(ctx context.Context, userID int64) error {
+	_, err := q.db.Exec(ctx, removeUserFromAllConversations, userID)
+	return err
+}
+
 const updateLastReadAt = `-- name: UpdateLastReadAt :exec
 UPDATE "ConversationParticipants"
 SET last_read_at = now()
@@ -163,3 +272,30 @@ func (q *Queries) UpdateLastReadAt(ctx context.Context, arg UpdateLastReadAtPara
 	_, err := q.db.Exec(ctx, updateLastReadAt, arg.ConversationID, arg.UserID)
 	return err
 }
+
+const updateParticipantInbox = `-- name: UpdateParticipantInbox :one
+UPDATE "ConversationParticipants"
+SET inbox = $3
+WHERE conversation_id = $1 AND user_id = $2
+RETURNING conversation_participants_id, conversation_id, user_id, last_read_at, joined_at, inbox
+`
+
+type UpdateParticipantInboxParams struct {
+	ConversationID int64  `json:"conversation_id"`
+	UserID         int64  `json:"user_id"`
+	Inbox          string `json:"inbox"`
+}
+
+func (q *Queries) UpdateParticipantInbox(ctx context.Context, arg UpdateParticipantInboxParams) (ConversationParticipant, error) {
+	row := q.db.QueryRow(ctx, updateParticipantInbox, arg.ConversationID, arg.UserID, arg.Inbox)
+	var i ConversationParticipant
+	err := row.Scan(
+		&i.ConversationParticipantsID,
+		&i.ConversationID,
+		&i.UserID,
+		&i.LastReadAt,
+		&i.JoinedAt,
+		&i.Inbox,
+	)
+	return i, err
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+)
+
+type CreateIncomingWebhookTxParams struct {
+	// identity the webhook's messages are attributed to
+	Bot            CreateBotParams
+	ConversationID int64
+	Name           string
+	Prefix         string
+	TokenHash      string
+}
+
+type CreateIncomingWebhookTxResults struct {
+	Bot             User
+	IncomingWebhook IncomingWebhook
+}
+
+// CreateIncomingWebhookTx creates the webhook together with its bot,
+// which joins the conversation so its messages show up like any other
+func (store *SQLStore) CreateIncomingWebhookTx(ctx context.Context,
+	arg CreateIncomingWebhookTxParams) (CreateIncomingWebhookTxResults, error) {
+	var result CreateIncomingWebhookTxResults
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.Bot, err = q.CreateBot(ctx, arg.Bot)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.AddParticipantToConversation(ctx, AddParticipantToConversationParams{
+			ConversationID: arg.ConversationID,
+			UserID:         result.Bot.ID,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.IncomingWebhook, err = q.CreateIncomingWebhook(ctx, CreateIncomingWebhookParams{
+			ConversationID: arg.ConversationID,
+			BotUserID:      result.Bot.ID,
+			Name:           arg.Name,
+			Prefix:         arg.Prefix,
+			TokenHash:      arg.TokenHash,
+			CreatedBy:      arg.Bot.BotOwnerID,
+		})
+		return err
+	})
+
+	return result, err
+}
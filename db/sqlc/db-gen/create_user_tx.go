@@ -1,9 +1,16 @@
 package db
 
-import "context"
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
 
 type CreateUserTxParams struct {
 	CreateUserParams
+	// links an external login, the provider already verified the email
+	Identity *CreateUserIdentityParams
+	// optional
 	AfterCreate func(user User) error
 }
 
@@ -23,9 +30,33 @@ func (store *SQLStore) CreateUserTx(ctx context.Context,
 			return err
 		}
 
+		if arg.Identity != nil {
+			result.User, err = q.UpdateUser(ctx, UpdateUserParams{
+				Username: result.User.Username,
+				IsEmailVerified: pgtype.Bool{
+					Bool:  true,
+					Valid: true,
+				},
+			})
+			if err != nil {
+				return err
+			}
+
+			identity := *arg.Identity
+			identity.UserID = result.User.ID
+			_, err = q.CreateUserIdentity(ctx, identity)
+			if err != nil {
+				return err
+			}
+		}
+
+		if arg.AfterCreate == nil {
+			return nil
+		}
+
 		err = arg.AfterCreate(result.User)
 		return err
 	})
 
 	return result, err
-}
\ No newline at end of file
+}
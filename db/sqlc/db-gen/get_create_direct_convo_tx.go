@@ -2,10 +2,14 @@ package db
 
 import (
 	"context"
+	"errors"
 
 	"github.com/jackc/pgx/v5"
 )
 
+// one of the two users blocked the other
+var ErrUserBlocked = errors.New("user is blocked")
+
 // ============================================
 // TRANSACTION 3: Get or Create Direct Conversation
 // Uses SERIALIZABLE isolation level -> Prevents
@@ -16,6 +20,8 @@ import (
 type GetOrCreateDirectConversationTxParams struct {
 	User1ID int64
 	User2ID int64
+	// a new conversation lands in User2's requests inbox
+	Request bool
 }
 
 type GetOrCreateDirectConversationTxResult struct {
@@ -26,6 +32,7 @@ type GetOrCreateDirectConversationTxResult struct {
 // GetOrCreateDirectConversationTx atomically
 // gets or creates a direct conversation
 // Uses SERIALIZABLE isolation to prevent duplicate conversations
+// Returns ErrUserBlocked if either user blocked the other
 func (store *SQLStore) GetOrCreateDirectConversationTx(
 	ctx context.Context,
 	arg GetOrCreateDirectConversationTxParams) (
@@ -43,6 +50,19 @@ func (store *SQLStore) GetOrCreateDirectConversationTx(
 
 	q := New(tx)
 
+	// Blocked users can't reach each other, not even
+	// through a conversation they already have
+	blocked, err := q.IsBlockedEitherWay(ctx, IsBlockedEitherWayParams{
+		UserID:      arg.User1ID,
+		OtherUserID: arg.User2ID,
+	})
+	if err != nil {
+		return result, err
+	}
+	if blocked {
+		return result, ErrUserBlocked
+	}
+
 	// Try to find existing conversation
 	conversationID, err := q.FindDirectConversation(ctx, FindDirectConversationParams{
 		UserID:   arg.User1ID,
@@ -74,6 +94,17 @@ func (store *SQLStore) GetOrCreateDirectConversationTx(
 			return result, err
 		}
 
+		if arg.Request {
+			_, err = q.UpdateParticipantInbox(ctx, UpdateParticipantInboxParams{
+				ConversationID: conversation.ConversationsID,
+				UserID:         arg.User2ID,
+				Inbox:          "requests",
+			})
+			if err != nil {
+				return result, err
+			}
+		}
+
 		result.Conversation = conversation
 		result.IsNew = true
 	} else if err != nil {
@@ -60,6 +60,16 @@ func (q *Queries) DeleteMessage(ctx context.Context, messagesID int64) error {
 	return err
 }
 
+const deleteMessagesBySender = `-- name: DeleteMessagesBySender :exec
+DELETE FROM "Messages"
+WHERE sender_id = $1
+`
+
+func (q *Queries) DeleteMessagesBySender(ctx context.Context, senderID int64) error {
+	_, err := q.db.Exec(ctx, deleteMessagesBySender, senderID)
+	return err
+}
+
 const getConversationMessages = `-- name: GetConversationMessages :many
 SELECT 
   m.messages_id,
@@ -11,6 +11,31 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type APIKey struct {
+	ApiKeyID int64  `json:"api_key_id"`
+	UserID   int64  `json:"user_id"`
+	Name     string `json:"name"`
+	// Public part of the key, used to look it up
+	Prefix string `json:"prefix"`
+	// sha256 of the whole key
+	KeyHash    string             `json:"key_hash"`
+	Scopes     []string           `json:"scopes"`
+	CreatedBy  pgtype.Int8        `json:"created_by"`
+	LastUsedAt pgtype.Timestamptz `json:"last_used_at"`
+	ExpiresAt  pgtype.Timestamptz `json:"expires_at"`
+	RevokedAt  pgtype.Timestamptz `json:"revoked_at"`
+	CreatedAt  time.Time          `json:"created_at"`
+}
+
+// A contact is mutual when both users added each other
+type Contact struct {
+	OwnerID   int64 `json:"owner_id"`
+	ContactID int64 `json:"contact_id"`
+	// Only shown to the owner
+	Nickname  pgtype.Text `json:"nickname"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
 type Conversation struct {
 	ConversationsID int64     `json:"conversations_id"`
 	CreatedAt       time.Time `json:"created_at"`
@@ -24,6 +49,40 @@ type ConversationParticipant struct {
 	// For read receipts
 	LastReadAt pgtype.Timestamptz `json:"last_read_at"`
 	JoinedAt   time.Time          `json:"joined_at"`
+	// primary, requests (first contact from a non-contact) or declined
+	Inbox string `json:"inbox"`
+}
+
+type DataExport struct {
+	ExportID int64 `json:"export_id"`
+	UserID   int64 `json:"user_id"`
+	// pending, ready, failed or expired
+	Status string `json:"status"`
+	// Where the archive is kept in file storage
+	FileKey pgtype.Text `json:"file_key"`
+	// Public part of the download token, used to look it up
+	Prefix pgtype.Text `json:"prefix"`
+	// sha256 of the whole download token
+	TokenHash   pgtype.Text        `json:"token_hash"`
+	ExpiresAt   pgtype.Timestamptz `json:"expires_at"`
+	CreatedAt   time.Time          `json:"created_at"`
+	CompletedAt pgtype.Timestamptz `json:"completed_at"`
+}
+
+type IncomingWebhook struct {
+	IncomingWebhookID int64 `json:"incoming_webhook_id"`
+	ConversationID    int64 `json:"conversation_id"`
+	// Bot the messages are attributed to
+	BotUserID int64  `json:"bot_user_id"`
+	Name      string `json:"name"`
+	// Public part of the token, used to look it up
+	Prefix string `json:"prefix"`
+	// sha256 of the whole token
+	TokenHash  string             `json:"token_hash"`
+	CreatedBy  pgtype.Int8        `json:"created_by"`
+	LastUsedAt pgtype.Timestamptz `json:"last_used_at"`
+	RevokedAt  pgtype.Timestamptz `json:"revoked_at"`
+	CreatedAt  time.Time          `json:"created_at"`
 }
 
 type Message struct {
@@ -37,6 +96,51 @@ type Message struct {
 	SentAt          time.Time `json:"sent_at"`
 }
 
+type PasswordReset struct {
+	ResetID    int64     `json:"reset_id"`
+	Username   string    `json:"username"`
+	Email      string    `json:"email"`
+	SecretCode string    `json:"secret_code"`
+	IsUsed     bool      `json:"is_used"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiredAt  time.Time `json:"expired_at"`
+}
+
+// Users without a row use the defaults
+type PrivacySetting struct {
+	UserID int64 `json:"user_id"`
+	// Who sees last_seen_at: everyone, contacts or nobody
+	LastSeen string `json:"last_seen"`
+	// Who sees is_online: everyone, contacts or nobody
+	Online string `json:"online"`
+	// Whether others see how far the user has read
+	ReadReceipts bool `json:"read_receipts"`
+	// Who can start a direct conversation: everyone, contacts or nobody
+	DmPolicy  string    `json:"dm_policy"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type RecoveryCode struct {
+	RecoveryCodesID int64 `json:"recovery_codes_id"`
+	UserID          int64 `json:"user_id"`
+	// sha256 of the single-use code
+	CodeHash  string             `json:"code_hash"`
+	UsedAt    pgtype.Timestamptz `json:"used_at"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+type RoleAuditLog struct {
+	RoleAuditLogID int64 `json:"role_audit_log_id"`
+	// Admin who changed the role
+	ActorID  pgtype.Int8 `json:"actor_id"`
+	TargetID pgtype.Int8 `json:"target_id"`
+	// Kept so the record outlives the user
+	TargetUsername string    `json:"target_username"`
+	OldRole        string    `json:"old_role"`
+	NewRole        string    `json:"new_role"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
 type Session struct {
 	ID           uuid.UUID `json:"id"`
 	Username     string    `json:"username"`
@@ -48,11 +152,14 @@ type Session struct {
 	CreatedAt    time.Time `json:"created_at"`
 }
 
-type TypingIndicator struct {
-	TypingIndicatorsID int64     `json:"typing_indicators_id"`
-	ConversationID     int64     `json:"conversation_id"`
-	UserID             int64     `json:"user_id"`
-	StartedAt          time.Time `json:"started_at"`
+type TwoFactorAuth struct {
+	UserID    int64  `json:"user_id"`
+	Secret    string `json:"secret"`
+	IsEnabled bool   `json:"is_enabled"`
+	// Prevents reusing a TOTP code
+	LastUsedStep int64              `json:"last_used_step"`
+	ConfirmedAt  pgtype.Timestamptz `json:"confirmed_at"`
+	CreatedAt    time.Time          `json:"created_at"`
 }
 
 type User struct {
@@ -69,6 +176,33 @@ type User struct {
 	BannedAt          pgtype.Timestamptz `json:"banned_at"`
 	BannedReason      pgtype.Text        `json:"banned_reason"`
 	CreatedAt         time.Time          `json:"created_at"`
+	IsBot             bool               `json:"is_bot"`
+	// User who manages the bot and its API keys
+	BotOwnerID pgtype.Int8 `json:"bot_owner_id"`
+	// Set while a deletion request waits for its grace period
+	DeletionRequestedAt pgtype.Timestamptz `json:"deletion_requested_at"`
+	// Last username change, for the change cooldown
+	UsernameChangedAt pgtype.Timestamptz `json:"username_changed_at"`
+	// Latest accepted avatar upload, older ones processed later are dropped
+	AvatarUploadID pgtype.Text `json:"avatar_upload_id"`
+}
+
+// Never shown to the blocked user
+type UserBlock struct {
+	BlockerID int64     `json:"blocker_id"`
+	BlockedID int64     `json:"blocked_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type UserIdentity struct {
+	UserIdentityID int64  `json:"user_identity_id"`
+	UserID         int64  `json:"user_id"`
+	Issuer         string `json:"issuer"`
+	// Stable user ID at the provider (sub claim)
+	Subject string `json:"subject"`
+	// Email the provider reported when the identity was linked
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type VerifyEmail struct {
@@ -80,3 +214,31 @@ type VerifyEmail struct {
 	CreatedAt  time.Time `json:"created_at"`
 	ExpiredAt  time.Time `json:"expired_at"`
 }
+
+type Webhook struct {
+	WebhookID int64 `json:"webhook_id"`
+	OwnerID   int64 `json:"owner_id"`
+	// NULL subscribes to events of every conversation (admins only)
+	ConversationID pgtype.Int8 `json:"conversation_id"`
+	Url            string      `json:"url"`
+	// Signs deliveries with HMAC-SHA256, kept in plain text to sign with
+	Secret     string             `json:"secret"`
+	Events     []string           `json:"events"`
+	IsActive   bool               `json:"is_active"`
+	DisabledAt pgtype.Timestamptz `json:"disabled_at"`
+	CreatedAt  time.Time          `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	DeliveryID int64  `json:"delivery_id"`
+	WebhookID  int64  `json:"webhook_id"`
+	Event      string `json:"event"`
+	Payload    []byte `json:"payload"`
+	// pending, succeeded or failed
+	Status         string             `json:"status"`
+	Attempts       int32              `json:"attempts"`
+	ResponseStatus pgtype.Int4        `json:"response_status"`
+	LastError      pgtype.Text        `json:"last_error"`
+	CreatedAt      time.Time          `json:"created_at"`
+	DeliveredAt    pgtype.Timestamptz `json:"delivered_at"`
+}
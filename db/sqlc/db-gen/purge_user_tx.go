@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// the user logged in (or asked again) after the purge was scheduled
+var ErrDeletionCancelled = errors.New("account deletion was cancelled")
+
+type PurgeUserTxParams struct {
+	UserID int64
+	// deletion request the purge was scheduled for
+	RequestedAt time.Time
+}
+
+type PurgeUserTxResults struct {
+	// the user as it was before the purge
+	User User
+}
+
+// PurgeUserTx removes a user whose deletion grace period is over,
+// together with their messages, sessions, verification emails and
+// conversation memberships. The rest goes with the user row
+// through ON DELETE CASCADE. Their bots and incoming webhooks would
+// outlive them ownerless, so the bots' API keys and the webhooks
+// are revoked.
+func (store *SQLStore) PurgeUserTx(ctx context.Context,
+	arg PurgeUserTxParams) (PurgeUserTxResults, error) {
+	var result PurgeUserTxResults
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		// a login cancelling the deletion waits for this lock
+		result.User, err = q.GetUserByIDForUpdate(ctx, arg.UserID)
+		if err != nil {
+			return err
+		}
+
+		requested := result.User.DeletionRequestedAt
+		if !requested.Valid ||
+			requested.Time.UnixMicro() != arg.RequestedAt.UnixMicro() {
+			return ErrDeletionCancelled
+		}
+
+		err = q.DeleteMessagesBySender(ctx, arg.UserID)
+		if err != nil {
+			return err
+		}
+
+		err = q.RemoveUserFromAllConversations(ctx, arg.UserID)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteUserSessions(ctx, result.User.Username)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteVerifyEmails(ctx, result.User.Username)
+		if err != nil {
+			return err
+		}
+
+		owner := pgtype.Int8{Int64: arg.UserID, Valid: true}
+		err = q.RevokeBotAPIKeysByOwner(ctx, owner)
+		if err != nil {
+			return err
+		}
+
+		err = q.RevokeIncomingWebhooksByCreator(ctx, owner)
+		if err != nil {
+			return err
+		}
+
+		return q.DeleteUser(ctx, arg.UserID)
+	})
+
+	return result, err
+}
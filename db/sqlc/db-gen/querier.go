@@ -8,26 +8,60 @@ import (
 	"context"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Querier interface {
 	AddParticipantToConversation(ctx context.Context, arg AddParticipantToConversationParams) (ConversationParticipant, error)
 	BanUser(ctx context.Context, arg BanUserParams) error
+	BlockOtherUserSessions(ctx context.Context, arg BlockOtherUserSessionsParams) ([]uuid.UUID, error)
+	BlockUser(ctx context.Context, arg BlockUserParams) error
 	BlockUserSessions(ctx context.Context, username string) error
-	CleanupStaleTypingIndicators(ctx context.Context) error
+	CancelUserDeletion(ctx context.Context, id int64) error
+	CompleteDataExport(ctx context.Context, arg CompleteDataExportParams) (DataExport, error)
+	// admins who can still log in and act: not banned, not leaving, not bots
+	CountActiveAdmins(ctx context.Context, adminRole string) (int64, error)
+	CountUnusedRecoveryCodes(ctx context.Context, userID int64) (int64, error)
+	CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (APIKey, error)
+	CreateBot(ctx context.Context, arg CreateBotParams) (User, error)
 	CreateConversation(ctx context.Context) (Conversation, error)
+	CreateDataExport(ctx context.Context, userID int64) (DataExport, error)
+	CreateIncomingWebhook(ctx context.Context, arg CreateIncomingWebhookParams) (IncomingWebhook, error)
 	CreateMessage(ctx context.Context, arg CreateMessageParams) (Message, error)
+	CreatePasswordReset(ctx context.Context, arg CreatePasswordResetParams) (PasswordReset, error)
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) error
+	CreateRoleAuditLog(ctx context.Context, arg CreateRoleAuditLogParams) (RoleAuditLog, error)
 	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateUserIdentity(ctx context.Context, arg CreateUserIdentityParams) (UserIdentity, error)
 	CreateVerifyEmail(ctx context.Context, arg CreateVerifyEmailParams) (VerifyEmail, error)
+	CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error)
+	CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) (WebhookDelivery, error)
 	DeleteMessage(ctx context.Context, messagesID int64) error
+	DeleteMessagesBySender(ctx context.Context, senderID int64) error
+	DeleteRecoveryCodes(ctx context.Context, userID int64) error
+	DeleteTwoFactorAuth(ctx context.Context, userID int64) error
+	DeleteUser(ctx context.Context, id int64) error
+	DeleteUserSessions(ctx context.Context, username string) error
+	DeleteVerifyEmails(ctx context.Context, username string) error
+	DisableWebhook(ctx context.Context, webhookID int64) (Webhook, error)
+	EnableTwoFactorAuth(ctx context.Context, arg EnableTwoFactorAuthParams) (TwoFactorAuth, error)
+	ExpireDataExport(ctx context.Context, exportID int64) error
+	FailDataExport(ctx context.Context, exportID int64) error
 	FindDirectConversation(ctx context.Context, arg FindDirectConversationParams) (int64, error)
+	GetAPIKey(ctx context.Context, apiKeyID int64) (APIKey, error)
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (APIKey, error)
 	GetAllConversations(ctx context.Context, arg GetAllConversationsParams) ([]Conversation, error)
 	GetAllUsers(ctx context.Context, arg GetAllUsersParams) ([]User, error)
 	GetConversationByID(ctx context.Context, conversationsID int64) (Conversation, error)
 	GetConversationMessages(ctx context.Context, arg GetConversationMessagesParams) ([]GetConversationMessagesRow, error)
+	GetConversationParticipant(ctx context.Context, arg GetConversationParticipantParams) (ConversationParticipant, error)
 	GetConversationParticipants(ctx context.Context, conversationID int64) ([]GetConversationParticipantsRow, error)
 	GetConversationWithParticipants(ctx context.Context, conversationsID int64) ([]GetConversationWithParticipantsRow, error)
+	GetDataExport(ctx context.Context, exportID int64) (DataExport, error)
+	GetDataExportByPrefix(ctx context.Context, prefix pgtype.Text) (DataExport, error)
+	GetIncomingWebhook(ctx context.Context, incomingWebhookID int64) (IncomingWebhook, error)
+	GetIncomingWebhookByPrefix(ctx context.Context, prefix string) (IncomingWebhook, error)
 	GetLatestMessage(ctx context.Context, conversationID int64) (GetLatestMessageRow, error)
 	GetMessageByClientID(ctx context.Context, clientMessageID string) (Message, error)
 	GetMessageByID(ctx context.Context, messagesID int64) (GetMessageByIDRow, error)
@@ -37,30 +71,93 @@ type Querier interface {
 	GetOnlineUsers(ctx context.Context) ([]GetOnlineUsersRow, error)
 	GetOnlineUsersCount(ctx context.Context) (int64, error)
 	GetOrCreateDirectConversation(ctx context.Context, arg GetOrCreateDirectConversationParams) (int64, error)
+	GetPrivacySettings(ctx context.Context, userID int64) (PrivacySetting, error)
 	GetSessionByID(ctx context.Context, id uuid.UUID) (Session, error)
 	GetTotalConversations(ctx context.Context) (int64, error)
 	GetTotalMessages(ctx context.Context) (int64, error)
 	GetTotalUsers(ctx context.Context) (int64, error)
-	GetTypingUsers(ctx context.Context, conversationID int64) ([]GetTypingUsersRow, error)
+	GetTwoFactorAuth(ctx context.Context, userID int64) (TwoFactorAuth, error)
 	GetUnreadCount(ctx context.Context, arg GetUnreadCountParams) (int64, error)
 	GetUserByEmail(ctx context.Context, email string) (User, error)
 	GetUserByID(ctx context.Context, id int64) (User, error)
+	GetUserByIDForUpdate(ctx context.Context, id int64) (User, error)
 	GetUserByUsername(ctx context.Context, username string) (User, error)
 	GetUserConversations(ctx context.Context, arg GetUserConversationsParams) ([]GetUserConversationsRow, error)
 	GetUserConversationsWithLastMessage(ctx context.Context, arg GetUserConversationsWithLastMessageParams) ([]GetUserConversationsWithLastMessageRow, error)
+	GetUserIdentity(ctx context.Context, arg GetUserIdentityParams) (UserIdentity, error)
+	GetWebhook(ctx context.Context, webhookID int64) (Webhook, error)
+	GetWebhookDelivery(ctx context.Context, deliveryID int64) (WebhookDelivery, error)
+	InvalidatePasswordResets(ctx context.Context, username string) error
+	IsBlockedEitherWay(ctx context.Context, arg IsBlockedEitherWayParams) (bool, error)
+	IsContact(ctx context.Context, arg IsContactParams) (bool, error)
+	// conversations of two people count as direct ones, webhook bots don't
+	// count as people and are blocked along with the webhook's creator
+	IsDirectMessageBlocked(ctx context.Context, arg IsDirectMessageBlockedParams) (bool, error)
 	IsUserInConversation(ctx context.Context, arg IsUserInConversationParams) (bool, error)
+	ListAPIKeys(ctx context.Context, userID int64) ([]APIKey, error)
+	ListAdminIDsForUpdate(ctx context.Context, adminRole string) ([]int64, error)
+	ListBlockedEitherWay(ctx context.Context, arg ListBlockedEitherWayParams) ([]int64, error)
+	ListBlockedUsers(ctx context.Context, blockerID int64) ([]ListBlockedUsersRow, error)
+	ListBotsByOwner(ctx context.Context, botOwnerID pgtype.Int8) ([]User, error)
+	// which of user_ids owner_id added
+	ListContactIDs(ctx context.Context, arg ListContactIDsParams) ([]int64, error)
+	// which of user_ids added contact_id
+	ListContactOwnerIDs(ctx context.Context, arg ListContactOwnerIDsParams) ([]int64, error)
+	ListContacts(ctx context.Context, ownerID int64) ([]ListContactsRow, error)
+	// participants other than webhook bots, in the order they joined
+	ListConversationPeople(ctx context.Context, conversationID int64) ([]ConversationParticipant, error)
+	ListDataExportsByUser(ctx context.Context, userID int64) ([]DataExport, error)
+	ListIncomingWebhooks(ctx context.Context, conversationID int64) ([]IncomingWebhook, error)
+	// users that haven't accepted a message request from user_id
+	ListPendingRequestUserIDs(ctx context.Context, arg ListPendingRequestUserIDsParams) ([]int64, error)
+	ListPrivacySettings(ctx context.Context, userIds []int64) ([]PrivacySetting, error)
+	ListPublicProfiles(ctx context.Context, ids []int64) ([]ListPublicProfilesRow, error)
+	ListRoleAuditLogs(ctx context.Context, arg ListRoleAuditLogsParams) ([]RoleAuditLog, error)
+	ListUserSessions(ctx context.Context, username string) ([]Session, error)
+	ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	ListWebhooksByOwner(ctx context.Context, ownerID int64) ([]Webhook, error)
+	// owners have to still be allowed the webhook: not banned or leaving,
+	// in the conversation or, for global webhooks, still an admin
+	ListWebhooksForEvent(ctx context.Context, arg ListWebhooksForEventParams) ([]Webhook, error)
+	// Skipped when the user came back online after last_seen_at
+	MarkUserOffline(ctx context.Context, arg MarkUserOfflineParams) error
+	// Called when a heartbeat brings a user online
+	MarkUserOnline(ctx context.Context, id int64) error
+	RecordWebhookAttempt(ctx context.Context, arg RecordWebhookAttemptParams) (WebhookDelivery, error)
+	RemoveContact(ctx context.Context, arg RemoveContactParams) error
 	RemoveParticipantFromConversation(ctx context.Context, arg RemoveParticipantFromConversationParams) error
-	RemoveTypingIndicator(ctx context.Context, arg RemoveTypingIndicatorParams) error
+	RemoveUserFromAllConversations(ctx context.Context, userID int64) error
+	// only while the upload is still the latest one, returns the replaced url
+	ReplaceAvatar(ctx context.Context, arg ReplaceAvatarParams) (pgtype.Text, error)
+	RequestUserDeletion(ctx context.Context, id int64) (User, error)
+	RevokeAPIKey(ctx context.Context, apiKeyID int64) (APIKey, error)
+	// revokes every key of the owner's bots
+	RevokeBotAPIKeysByOwner(ctx context.Context, botOwnerID pgtype.Int8) error
+	RevokeIncomingWebhook(ctx context.Context, incomingWebhookID int64) (IncomingWebhook, error)
+	RevokeIncomingWebhooksByCreator(ctx context.Context, createdBy pgtype.Int8) error
 	SearchMessages(ctx context.Context, arg SearchMessagesParams) ([]SearchMessagesRow, error)
-	SearchUsersByUsername(ctx context.Context, arg SearchUsersByUsernameParams) ([]SearchUsersByUsernameRow, error)
-	SetTypingIndicator(ctx context.Context, arg SetTypingIndicatorParams) (TypingIndicator, error)
+	// Ranks exact over prefix over fuzzy matches, contacts first within each,
+	// and pages by (tier, username)
+	SearchUsers(ctx context.Context, arg SearchUsersParams) ([]SearchUsersRow, error)
+	SetAvatarUploadID(ctx context.Context, arg SetAvatarUploadIDParams) error
+	TouchAPIKey(ctx context.Context, apiKeyID int64) error
+	TouchIncomingWebhook(ctx context.Context, incomingWebhookID int64) error
 	UnbanUser(ctx context.Context, id int64) error
+	UnblockUser(ctx context.Context, arg UnblockUserParams) error
 	UpdateConversationTimestamp(ctx context.Context, conversationsID int64) error
 	UpdateLastReadAt(ctx context.Context, arg UpdateLastReadAtParams) error
+	UpdateParticipantInbox(ctx context.Context, arg UpdateParticipantInboxParams) (ConversationParticipant, error)
 	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
 	UpdateUserOnlineStatus(ctx context.Context, arg UpdateUserOnlineStatusParams) error
-	UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) error
+	UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (User, error)
+	UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error)
 	UpdateVerifyEmail(ctx context.Context, arg UpdateVerifyEmailParams) (VerifyEmail, error)
+	UpsertContact(ctx context.Context, arg UpsertContactParams) (Contact, error)
+	UpsertPrivacySettings(ctx context.Context, arg UpsertPrivacySettingsParams) (PrivacySetting, error)
+	UpsertTwoFactorSecret(ctx context.Context, arg UpsertTwoFactorSecretParams) (TwoFactorAuth, error)
+	UsePasswordReset(ctx context.Context, arg UsePasswordResetParams) (PasswordReset, error)
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error)
+	UseTwoFactorStep(ctx context.Context, arg UseTwoFactorStepParams) (int64, error)
 }
 
 var _ Querier = (*Queries)(nil)
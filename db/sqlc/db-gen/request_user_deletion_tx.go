@@ -0,0 +1,48 @@
+package db
+
+import (
+	"context"
+)
+
+type RequestUserDeletionTxParams struct {
+	UserID int64
+	// role of which at least one user has to remain
+	AdminRole string
+}
+
+type RequestUserDeletionTxResults struct {
+	User User
+}
+
+// RequestUserDeletionTx deactivates the user until the purge, the same
+// way ChangeRoleTx guards a demotion it refuses to take away the last
+// admin who can act
+func (store *SQLStore) RequestUserDeletionTx(ctx context.Context,
+	arg RequestUserDeletionTxParams) (RequestUserDeletionTxResults, error) {
+	var result RequestUserDeletionTxResults
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		_, err := q.ListAdminIDsForUpdate(ctx, arg.AdminRole)
+		if err != nil {
+			return err
+		}
+
+		user, err := q.GetUserByIDForUpdate(ctx, arg.UserID)
+		if err != nil {
+			return err
+		}
+
+		last, err := isLastActiveAdmin(ctx, q, user, arg.AdminRole)
+		if err != nil {
+			return err
+		}
+		if last {
+			return ErrLastAdmin
+		}
+
+		result.User, err = q.RequestUserDeletion(ctx, user.ID)
+		return err
+	})
+
+	return result, err
+}
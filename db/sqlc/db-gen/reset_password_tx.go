@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type ResetPasswordTxParams struct {
+	Email        string
+	SecretCode   string
+	PasswordHash string
+}
+
+type ResetPasswordTxResults struct {
+	User          User
+	PasswordReset PasswordReset
+}
+
+// ResetPasswordTx consumes a reset code, sets the new password
+// and blocks every session of the user
+func (store *SQLStore) ResetPasswordTx(ctx context.Context,
+	arg ResetPasswordTxParams) (ResetPasswordTxResults, error) {
+	var result ResetPasswordTxResults
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.PasswordReset, err = q.UsePasswordReset(ctx, UsePasswordResetParams{
+			Email:      arg.Email,
+			SecretCode: arg.SecretCode,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.User, err = q.UpdateUser(ctx, UpdateUserParams{
+			Username: result.PasswordReset.Username,
+			PasswordHash: pgtype.Text{
+				String: arg.PasswordHash,
+				Valid:  true,
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		// other outstanding codes must not work anymore
+		err = q.InvalidatePasswordResets(ctx, result.User.Username)
+		if err != nil {
+			return err
+		}
+
+		return q.BlockUserSessions(ctx, result.User.Username)
+	})
+
+	return result, err
+}
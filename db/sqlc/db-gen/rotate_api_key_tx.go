@@ -0,0 +1,37 @@
+package db
+
+import (
+	"context"
+)
+
+type RotateAPIKeyTxParams struct {
+	// key being replaced, must not be revoked yet
+	OldAPIKeyID int64
+	NewAPIKey   CreateAPIKeyParams
+}
+
+type RotateAPIKeyTxResults struct {
+	OldAPIKey APIKey
+	NewAPIKey APIKey
+}
+
+// RotateAPIKeyTx revokes a key and creates its replacement,
+// so there is never a moment with both or neither
+func (store *SQLStore) RotateAPIKeyTx(ctx context.Context,
+	arg RotateAPIKeyTxParams) (RotateAPIKeyTxResults, error) {
+	var result RotateAPIKeyTxResults
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.OldAPIKey, err = q.RevokeAPIKey(ctx, arg.OldAPIKeyID)
+		if err != nil {
+			return err
+		}
+
+		result.NewAPIKey, err = q.CreateAPIKey(ctx, arg.NewAPIKey)
+		return err
+	})
+
+	return result, err
+}
@@ -22,12 +22,26 @@ type SendMessageTxResult struct {
 }
 
 // SendMessageTx creates a message and updates the conversation timestamp atomically
+// Returns ErrUserBlocked if the conversation is a direct one and
+// either participant blocked the other
 func (store *SQLStore) SendMessageTx(ctx context.Context, arg SendMessageTxParams) (SendMessageTxResult, error) {
 	var result SendMessageTxResult
 
 	err := store.execTx(ctx, func(q *Queries) error {
 		var err error
 
+		// In a direct conversation a block works both ways
+		blocked, err := q.IsDirectMessageBlocked(ctx, IsDirectMessageBlockedParams{
+			SenderID:       arg.SenderID,
+			ConversationID: arg.ConversationID,
+		})
+		if err != nil {
+			return err
+		}
+		if blocked {
+			return ErrUserBlocked
+		}
+
 		// Create the message
 		result.Message, err = q.CreateMessage(ctx, CreateMessageParams{
 			ConversationID:   arg.ConversationID,
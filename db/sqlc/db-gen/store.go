@@ -25,6 +25,25 @@ type Store interface {
 		arg CreateUserTxParams) (CreateUserTxResults, error)
 	VerifyEmailTx(ctx context.Context,
 		arg VerifyEmailTxParams) (VerifyEmailTxResults, error)
+	ResetPasswordTx(ctx context.Context,
+		arg ResetPasswordTxParams) (ResetPasswordTxResults, error)
+	ChangePasswordTx(ctx context.Context,
+		arg ChangePasswordTxParams) (ChangePasswordTxResults, error)
+	ChangeEmailTx(ctx context.Context,
+		arg ChangeEmailTxParams) (ChangeEmailTxResults, error)
+	EnableTwoFactorTx(ctx context.Context,
+		arg EnableTwoFactorTxParams) (EnableTwoFactorTxResult, error)
+	DisableTwoFactorTx(ctx context.Context, userID int64) error
+	ChangeRoleTx(ctx context.Context,
+		arg ChangeRoleTxParams) (ChangeRoleTxResults, error)
+	RotateAPIKeyTx(ctx context.Context,
+		arg RotateAPIKeyTxParams) (RotateAPIKeyTxResults, error)
+	CreateIncomingWebhookTx(ctx context.Context,
+		arg CreateIncomingWebhookTxParams) (CreateIncomingWebhookTxResults, error)
+	PurgeUserTx(ctx context.Context,
+		arg PurgeUserTxParams) (PurgeUserTxResults, error)
+	RequestUserDeletionTx(ctx context.Context,
+		arg RequestUserDeletionTxParams) (RequestUserDeletionTxResults, error)
 }
 
 // SQLStore provides all funcs for SQL queries and transactions
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+)
+
+// ============================================
+// Enable / Disable TOTP two-factor authentication
+// together with the user's recovery codes
+// ============================================
+
+type EnableTwoFactorTxParams struct {
+	UserID int64
+	// step of the code that confirmed the enrollment
+	Step int64
+	// sha256 hashes of the new recovery codes
+	RecoveryCodeHashes []string
+}
+
+type EnableTwoFactorTxResult struct {
+	TwoFactorAuth TwoFactorAuth
+}
+
+// EnableTwoFactorTx turns on 2FA and replaces the recovery codes
+func (store *SQLStore) EnableTwoFactorTx(ctx context.Context,
+	arg EnableTwoFactorTxParams) (EnableTwoFactorTxResult, error) {
+	var result EnableTwoFactorTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.TwoFactorAuth, err = q.EnableTwoFactorAuth(ctx, EnableTwoFactorAuthParams{
+			UserID:       arg.UserID,
+			LastUsedStep: arg.Step,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteRecoveryCodes(ctx, arg.UserID)
+		if err != nil {
+			return err
+		}
+
+		for _, codeHash := range arg.RecoveryCodeHashes {
+			err = q.CreateRecoveryCode(ctx, CreateRecoveryCodeParams{
+				UserID:   arg.UserID,
+				CodeHash: codeHash,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+// DisableTwoFactorTx removes the TOTP secret and all recovery codes
+func (store *SQLStore) DisableTwoFactorTx(ctx context.Context,
+	userID int64) error {
+	return store.execTx(ctx, func(q *Queries) error {
+		err := q.DeleteRecoveryCodes(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		return q.DeleteTwoFactorAuth(ctx, userID)
+	})
+}
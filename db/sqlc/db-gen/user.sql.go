@@ -29,6 +29,88 @@ func (q *Queries) BanUser(ctx context.Context, arg BanUserParams) error {
 	return err
 }
 
+const cancelUserDeletion = `-- name: CancelUserDeletion :exec
+UPDATE "Users"
+SET deletion_requested_at = NULL
+WHERE id = $1
+`
+
+func (q *Queries) CancelUserDeletion(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, cancelUserDeletion, id)
+	return err
+}
+
+const countActiveAdmins = `-- name: CountActiveAdmins :one
+SELECT count(*) FROM "Users"
+WHERE role = $1
+  AND is_banned = false
+  AND deletion_requested_at IS NULL
+  AND is_bot = false
+`
+
+// admins who can still log in and act: not banned, not leaving, not bots
+func (q *Queries) CountActiveAdmins(ctx context.Context, adminRole string) (int64, error) {
+	row := q.db.QueryRow(ctx, countActiveAdmins, adminRole)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createBot = `-- name: CreateBot :one
+INSERT INTO "Users" (
+  username,
+  email,
+  password_hash,
+  role,
+  is_email_verified,
+  is_bot,
+  bot_owner_id
+) VALUES (
+  $1, $2, $3, $4, true, true, $5
+)
+RETURNING id, username, email, is_email_verified, password_hash, profile_picture_url, is_online, last_seen_at, role, is_banned, banned_at, banned_reason, created_at, is_bot, bot_owner_id, deletion_requested_at, username_changed_at, avatar_upload_id
+`
+
+type CreateBotParams struct {
+	Username     string      `json:"username"`
+	Email        string      `json:"email"`
+	PasswordHash string      `json:"password_hash"`
+	Role         string      `json:"role"`
+	BotOwnerID   pgtype.Int8 `json:"bot_owner_id"`
+}
+
+func (q *Queries) CreateBot(ctx context.Context, arg CreateBotParams) (User, error) {
+	row := q.db.QueryRow(ctx, createBot,
+		arg.Username,
+		arg.Email,
+		arg.PasswordHash,
+		arg.Role,
+		arg.BotOwnerID,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.IsEmailVerified,
+		&i.PasswordHash,
+		&i.ProfilePictureUrl,
+		&i.IsOnline,
+		&i.LastSeenAt,
+		&i.Role,
+		&i.IsBanned,
+		&i.BannedAt,
+		&i.BannedReason,
+		&i.CreatedAt,
+		&i.IsBot,
+		&i.BotOwnerID,
+		&i.DeletionRequestedAt,
+		&i.UsernameChangedAt,
+		&i.AvatarUploadID,
+	)
+	return i, err
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO "Users" (
   username,
@@ -39,7 +121,7 @@ INSERT INTO "Users" (
 ) VALUES (
   $1, $2, $3, $4, $5
 )
-RETURNING id, username, email, is_email_verified, password_hash, profile_picture_url, is_online, last_seen_at, role, is_banned, banned_at, banned_reason, created_at
+RETURNING id, username, email, is_email_verified, password_hash, profile_picture_url, is_online, last_seen_at, role, is_banned, banned_at, banned_reason, created_at, is_bot, bot_owner_id, deletion_requested_at, username_changed_at, avatar_upload_id
 `
 
 type CreateUserParams struct {
@@ -73,12 +155,27 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.BannedAt,
 		&i.BannedReason,
 		&i.CreatedAt,
+		&i.IsBot,
+		&i.BotOwnerID,
+		&i.DeletionRequestedAt,
+		&i.UsernameChangedAt,
+		&i.AvatarUploadID,
 	)
 	return i, err
 }
 
+const deleteUser = `-- name: DeleteUser :exec
+DELETE FROM "Users"
+WHERE id = $1
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, deleteUser, id)
+	return err
+}
+
 const getAllUsers = `-- name: GetAllUsers :many
-SELECT id, username, email, is_email_verified, password_hash, profile_picture_url, is_online, last_seen_at, role, is_banned, banned_at, banned_reason, created_at FROM "Users" 
+SELECT id, username, email, is_email_verified, password_hash, profile_picture_url, is_online, last_seen_at, role, is_banned, banned_at, banned_reason, created_at, is_bot, bot_owner_id, deletion_requested_at, username_changed_at, avatar_upload_id FROM "Users" 
 ORDER BY created_at DESC
 LIMIT $1 OFFSET $2
 `
@@ -111,6 +208,11 @@ func (q *Queries) GetAllUsers(ctx context.Context, arg GetAllUsersParams) ([]Use
 			&i.BannedAt,
 			&i.BannedReason,
 			&i.CreatedAt,
+			&i.IsBot,
+			&i.BotOwnerID,
+			&i.DeletionRequestedAt,
+			&i.UsernameChangedAt,
+			&i.AvatarUploadID,
 		); err != nil {
 			return nil, err
 		}
@@ -162,7 +264,7 @@ func (q *Queries) GetOnlineUsers(ctx context.Context) ([]GetOnlineUsersRow, erro
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, username, email, is_email_verified, password_hash, profile_picture_url, is_online, last_seen_at, role, is_banned, banned_at, banned_reason, created_at FROM "Users"
+SELECT id, username, email, is_email_verified, password_hash, profile_picture_url, is_online, last_seen_at, role, is_banned, banned_at, banned_reason, created_at, is_bot, bot_owner_id, deletion_requested_at, username_changed_at, avatar_upload_id FROM "Users"
 WHERE email = $1
 `
 
@@ -183,12 +285,17 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.BannedAt,
 		&i.BannedReason,
 		&i.CreatedAt,
+		&i.IsBot,
+		&i.BotOwnerID,
+		&i.DeletionRequestedAt,
+		&i.UsernameChangedAt,
+		&i.AvatarUploadID,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, username, email, is_email_verified, password_hash, profile_picture_url, is_online, last_seen_at, role, is_banned, banned_at, banned_reason, created_at FROM "Users"
+SELECT id, username, email, is_email_verified, password_hash, profile_picture_url, is_online, last_seen_at, role, is_banned, banned_at, banned_reason, created_at, is_bot, bot_owner_id, deletion_requested_at, username_changed_at, avatar_upload_id FROM "Users"
 WHERE id = $1
 `
 
@@ -209,12 +316,49 @@ func (q *Queries) GetUserByID(ctx context.Context, id int64) (User, error) {
 		&i.BannedAt,
 		&i.BannedReason,
 		&i.CreatedAt,
+		&i.IsBot,
+		&i.BotOwnerID,
+		&i.DeletionRequestedAt,
+		&i.UsernameChangedAt,
+		&i.AvatarUploadID,
+	)
+	return i, err
+}
+
+const getUserByIDForUpdate = `-- name: GetUserByIDForUpdate :one
+SELECT id, username, email, is_email_verified, password_hash, profile_picture_url, is_online, last_seen_at, role, is_banned, banned_at, banned_reason, created_at, is_bot, bot_owner_id, deletion_requested_at, username_changed_at, avatar_upload_id FROM "Users"
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetUserByIDForUpdate(ctx context.Context, id int64) (User, error) {
+	row := q.db.QueryRow(ctx, getUserByIDForUpdate, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.IsEmailVerified,
+		&i.PasswordHash,
+		&i.ProfilePictureUrl,
+		&i.IsOnline,
+		&i.LastSeenAt,
+		&i.Role,
+		&i.IsBanned,
+		&i.BannedAt,
+		&i.BannedReason,
+		&i.CreatedAt,
+		&i.IsBot,
+		&i.BotOwnerID,
+		&i.DeletionRequestedAt,
+		&i.UsernameChangedAt,
+		&i.AvatarUploadID,
 	)
 	return i, err
 }
 
 const getUserByUsername = `-- name: GetUserByUsername :one
-SELECT id, username, email, is_email_verified, password_hash, profile_picture_url, is_online, last_seen_at, role, is_banned, banned_at, banned_reason, created_at FROM "Users"
+SELECT id, username, email, is_email_verified, password_hash, profile_picture_url, is_online, last_seen_at, role, is_banned, banned_at, banned_reason, created_at, is_bot, bot_owner_id, deletion_requested_at, username_changed_at, avatar_upload_id FROM "Users"
 WHERE username = $1
 `
 
@@ -235,45 +379,312 @@ func (q *Queries) GetUserByUsername(ctx context.Context, username string) (User,
 		&i.BannedAt,
 		&i.BannedReason,
 		&i.CreatedAt,
+		&i.IsBot,
+		&i.BotOwnerID,
+		&i.DeletionRequestedAt,
+		&i.UsernameChangedAt,
+		&i.AvatarUploadID,
 	)
 	return i, err
 }
 
-const searchUsersByUsername = `-- name: SearchUsersByUsername :many
-SELECT id, username, email, profile_picture_url, is_online
+const listAdminIDsForUpdate = `-- name: ListAdminIDsForUpdate :many
+SELECT id FROM "Users"
+WHERE role = $1
+ORDER BY id
+FOR UPDATE
+`
+
+func (q *Queries) ListAdminIDsForUpdate(ctx context.Context, adminRole string) ([]int64, error) {
+	rows, err := q.db.Query(ctx, listAdminIDsForUpdate, adminRole)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listBotsByOwner = `-- name: ListBotsByOwner :many
+SELECT id, username, email, is_email_verified, password_hash, profile_picture_url, is_online, last_seen_at, role, is_banned, banned_at, banned_reason, created_at, is_bot, bot_owner_id, deletion_requested_at, username_changed_at, avatar_upload_id FROM "Users"
+WHERE is_bot = true AND bot_owner_id = $1
+ORDER BY created_at DESC
+`
+
+func (q *Queries) ListBotsByOwner(ctx context.Context, botOwnerID pgtype.Int8) ([]User, error) {
+	rows, err := q.db.Query(ctx, listBotsByOwner, botOwnerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []User{}
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Username,
+			&i.Email,
+			&i.IsEmailVerified,
+			&i.PasswordHash,
+			&i.ProfilePictureUrl,
+			&i.IsOnline,
+			&i.LastSeenAt,
+			&i.Role,
+			&i.IsBanned,
+			&i.BannedAt,
+			&i.BannedReason,
+			&i.CreatedAt,
+			&i.IsBot,
+			&i.BotOwnerID,
+			&i.DeletionRequestedAt,
+			&i.UsernameChangedAt,
+			&i.AvatarUploadID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPublicProfiles = `-- name: ListPublicProfiles :many
+SELECT id, username, profile_picture_url, is_online, last_seen_at, is_bot
 FROM "Users"
-WHERE username ILIKE $1
-LIMIT $2
+WHERE id = ANY($1::bigint[])
+  AND deletion_requested_at IS NULL
+ORDER BY id
 `
 
-type SearchUsersByUsernameParams struct {
-	Username string `json:"username"`
-	Limit    int32  `json:"limit"`
+type ListPublicProfilesRow struct {
+	ID                int64              `json:"id"`
+	Username          string             `json:"username"`
+	ProfilePictureUrl pgtype.Text        `json:"profile_picture_url"`
+	IsOnline          bool               `json:"is_online"`
+	LastSeenAt        pgtype.Timestamptz `json:"last_seen_at"`
+	IsBot             bool               `json:"is_bot"`
 }
 
-type SearchUsersByUsernameRow struct {
+func (q *Queries) ListPublicProfiles(ctx context.Context, ids []int64) ([]ListPublicProfilesRow, error) {
+	rows, err := q.db.Query(ctx, listPublicProfiles, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListPublicProfilesRow{}
+	for rows.Next() {
+		var i ListPublicProfilesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Username,
+			&i.ProfilePictureUrl,
+			&i.IsOnline,
+			&i.LastSeenAt,
+			&i.IsBot,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markUserOffline = `-- name: MarkUserOffline :exec
+UPDATE "Users"
+SET is_online = false,
+    last_seen_at = $1
+WHERE id = $2
+  AND (last_seen_at IS NULL OR last_seen_at <= $1)
+`
+
+type MarkUserOfflineParams struct {
+	LastSeenAt pgtype.Timestamptz `json:"last_seen_at"`
+	ID         int64              `json:"id"`
+}
+
+// Skipped when the user came back online after last_seen_at
+func (q *Queries) MarkUserOffline(ctx context.Context, arg MarkUserOfflineParams) error {
+	_, err := q.db.Exec(ctx, markUserOffline, arg.LastSeenAt, arg.ID)
+	return err
+}
+
+const markUserOnline = `-- name: MarkUserOnline :exec
+UPDATE "Users"
+SET is_online = true,
+    last_seen_at = now()
+WHERE id = $1
+`
+
+// Called when a heartbeat brings a user online
+func (q *Queries) MarkUserOnline(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, markUserOnline, id)
+	return err
+}
+
+const replaceAvatar = `-- name: ReplaceAvatar :one
+WITH old AS (
+  SELECT id, profile_picture_url FROM "Users"
+  WHERE id = $1 AND avatar_upload_id = $2
+  FOR UPDATE
+)
+UPDATE "Users" u
+SET profile_picture_url = $3
+FROM old
+WHERE u.id = old.id
+RETURNING old.profile_picture_url
+`
+
+type ReplaceAvatarParams struct {
+	ID                int64       `json:"id"`
+	UploadID          pgtype.Text `json:"upload_id"`
+	ProfilePictureUrl pgtype.Text `json:"profile_picture_url"`
+}
+
+// only while the upload is still the latest one, returns the replaced url
+func (q *Queries) ReplaceAvatar(ctx context.Context, arg ReplaceAvatarParams) (pgtype.Text, error) {
+	row := q.db.QueryRow(ctx, replaceAvatar, arg.ID, arg.UploadID, arg.ProfilePictureUrl)
+	var profile_picture_url pgtype.Text
+	err := row.Scan(&profile_picture_url)
+	return profile_picture_url, err
+}
+
+const requestUserDeletion = `-- name: RequestUserDeletion :one
+UPDATE "Users"
+SET deletion_requested_at = now(),
+    is_online = false
+WHERE id = $1
+RETURNING id, username, email, is_email_verified, password_hash, profile_picture_url, is_online, last_seen_at, role, is_banned, banned_at, banned_reason, created_at, is_bot, bot_owner_id, deletion_requested_at, username_changed_at, avatar_upload_id
+`
+
+func (q *Queries) RequestUserDeletion(ctx context.Context, id int64) (User, error) {
+	row := q.db.QueryRow(ctx, requestUserDeletion, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.IsEmailVerified,
+		&i.PasswordHash,
+		&i.ProfilePictureUrl,
+		&i.IsOnline,
+		&i.LastSeenAt,
+		&i.Role,
+		&i.IsBanned,
+		&i.BannedAt,
+		&i.BannedReason,
+		&i.CreatedAt,
+		&i.IsBot,
+		&i.BotOwnerID,
+		&i.DeletionRequestedAt,
+		&i.UsernameChangedAt,
+		&i.AvatarUploadID,
+	)
+	return i, err
+}
+
+const searchUsers = `-- name: SearchUsers :many
+WITH candidates AS (
+  SELECT u.id FROM "Users" u
+  WHERE u.username ILIKE $1 OR u.username % $2::text
+  UNION
+  SELECT ct.contact_id FROM "Contacts" ct
+  WHERE ct.owner_id = $3
+    AND (ct.nickname ILIKE $1 OR ct.nickname % $2::text)
+), ranked AS (
+  SELECT u.id, u.username, u.profile_picture_url, u.is_online, u.is_bot,
+    ct.nickname, (ct.contact_id IS NOT NULL)::boolean as is_contact,
+    (CASE
+      WHEN lower(u.username) = lower($2::text)
+        OR lower(ct.nickname) = lower($2::text) THEN 0
+      WHEN u.username ILIKE $4 OR ct.nickname ILIKE $4 THEN 2
+      ELSE 4
+    END + CASE WHEN ct.contact_id IS NULL THEN 1 ELSE 0 END)::int as tier
+  FROM candidates c
+  INNER JOIN "Users" u ON u.id = c.id
+  LEFT JOIN "Contacts" ct ON ct.owner_id = $3 AND ct.contact_id = u.id
+  WHERE u.id <> $3
+    AND u.is_banned = false
+    AND u.deletion_requested_at IS NULL
+    AND NOT EXISTS (
+      SELECT 1 FROM "User_Blocks" b
+      WHERE (b.blocker_id = $3 AND b.blocked_id = u.id)
+         OR (b.blocker_id = u.id AND b.blocked_id = $3)
+    )
+    AND (NOT $5::boolean OR ct.contact_id IS NOT NULL)
+)
+SELECT id, username, profile_picture_url, is_online, is_bot, nickname, is_contact, tier
+FROM ranked
+WHERE (tier, username) > ($6::int, $7::text)
+ORDER BY tier, username
+LIMIT $8
+`
+
+type SearchUsersParams struct {
+	Contains      string `json:"contains"`
+	Query         string `json:"query"`
+	ViewerID      int64  `json:"viewer_id"`
+	Prefix        string `json:"prefix"`
+	ContactsOnly  bool   `json:"contacts_only"`
+	AfterTier     int32  `json:"after_tier"`
+	AfterUsername string `json:"after_username"`
+	Limit         int32  `json:"limit"`
+}
+
+type SearchUsersRow struct {
 	ID                int64       `json:"id"`
 	Username          string      `json:"username"`
-	Email             string      `json:"email"`
 	ProfilePictureUrl pgtype.Text `json:"profile_picture_url"`
 	IsOnline          bool        `json:"is_online"`
+	IsBot             bool        `json:"is_bot"`
+	Nickname          pgtype.Text `json:"nickname"`
+	IsContact         bool        `json:"is_contact"`
+	Tier              int32       `json:"tier"`
 }
 
-func (q *Queries) SearchUsersByUsername(ctx context.Context, arg SearchUsersByUsernameParams) ([]SearchUsersByUsernameRow, error) {
-	rows, err := q.db.Query(ctx, searchUsersByUsername, arg.Username, arg.Limit)
+// Ranks exact over prefix over fuzzy matches, contacts first within each,
+// and pages by (tier, username)
+func (q *Queries) SearchUsers(ctx context.Context, arg SearchUsersParams) ([]SearchUsersRow, error) {
+	rows, err := q.db.Query(ctx, searchUsers,
+		arg.Contains,
+		arg.Query,
+		arg.ViewerID,
+		arg.Prefix,
+		arg.ContactsOnly,
+		arg.AfterTier,
+		arg.AfterUsername,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []SearchUsersByUsernameRow{}
+	items := []SearchUsersRow{}
 	for rows.Next() {
-		var i SearchUsersByUsernameRow
+		var i SearchUsersRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.Username,
-			&i.Email,
 			&i.ProfilePictureUrl,
 			&i.IsOnline,
+			&i.IsBot,
+			&i.Nickname,
+			&i.IsContact,
+			&i.Tier,
 		); err != nil {
 			return nil, err
 		}
@@ -285,6 +696,22 @@ func (q *Queries) SearchUsersByUsername(ctx context.Context, arg SearchUsersByUs
 	return items, nil
 }
 
+const setAvatarUploadID = `-- name: SetAvatarUploadID :exec
+UPDATE "Users"
+SET avatar_upload_id = $1
+WHERE id = $2
+`
+
+type SetAvatarUploadIDParams struct {
+	UploadID pgtype.Text `json:"upload_id"`
+	ID       int64       `json:"id"`
+}
+
+func (q *Queries) SetAvatarUploadID(ctx context.Context, arg SetAvatarUploadIDParams) error {
+	_, err := q.db.Exec(ctx, setAvatarUploadID, arg.UploadID, arg.ID)
+	return err
+}
+
 const unbanUser = `-- name: UnbanUser :exec
 UPDATE "Users"
 SET is_banned = false,
@@ -306,7 +733,7 @@ email = COALESCE($2, email),
 is_email_verified = COALESCE($3, is_email_verified)
 WHERE
 username = $4
-RETURNING id, username, email, is_email_verified, password_hash, profile_picture_url, is_online, last_seen_at, role, is_banned, banned_at, banned_reason, created_at
+RETURNING id, username, email, is_email_verified, password_hash, profile_picture_url, is_online, last_seen_at, role, is_banned, banned_at, banned_reason, created_at, is_bot, bot_owner_id, deletion_requested_at, username_changed_at, avatar_upload_id
 `
 
 type UpdateUserParams struct {
@@ -338,6 +765,11 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.BannedAt,
 		&i.BannedReason,
 		&i.CreatedAt,
+		&i.IsBot,
+		&i.BotOwnerID,
+		&i.DeletionRequestedAt,
+		&i.UsernameChangedAt,
+		&i.AvatarUploadID,
 	)
 	return i, err
 }
@@ -359,20 +791,84 @@ func (q *Queries) UpdateUserOnlineStatus(ctx context.Context, arg UpdateUserOnli
 	return err
 }
 
-const updateUserProfile = `-- name: UpdateUserProfile :exec
+const updateUserProfile = `-- name: UpdateUserProfile :one
 UPDATE "Users"
-SET profile_picture_url = COALESCE($2, profile_picture_url),
-    username = COALESCE($3, username)
-WHERE id = $1
+SET profile_picture_url = COALESCE($1, profile_picture_url),
+    username = COALESCE($2, username),
+    username_changed_at = CASE
+      WHEN $2 IS NOT NULL AND $2 <> username THEN now()
+      ELSE username_changed_at
+    END
+WHERE id = $3
+RETURNING id, username, email, is_email_verified, password_hash, profile_picture_url, is_online, last_seen_at, role, is_banned, banned_at, banned_reason, created_at, is_bot, bot_owner_id, deletion_requested_at, username_changed_at, avatar_upload_id
 `
 
 type UpdateUserProfileParams struct {
-	ID                int64       `json:"id"`
 	ProfilePictureUrl pgtype.Text `json:"profile_picture_url"`
-	Username          string      `json:"username"`
+	Username          pgtype.Text `json:"username"`
+	ID                int64       `json:"id"`
 }
 
-func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) error {
-	_, err := q.db.Exec(ctx, updateUserProfile, arg.ID, arg.ProfilePictureUrl, arg.Username)
-	return err
+func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (User, error) {
+	row := q.db.QueryRow(ctx, updateUserProfile, arg.ProfilePictureUrl, arg.Username, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.IsEmailVerified,
+		&i.PasswordHash,
+		&i.ProfilePictureUrl,
+		&i.IsOnline,
+		&i.LastSeenAt,
+		&i.Role,
+		&i.IsBanned,
+		&i.BannedAt,
+		&i.BannedReason,
+		&i.CreatedAt,
+		&i.IsBot,
+		&i.BotOwnerID,
+		&i.DeletionRequestedAt,
+		&i.UsernameChangedAt,
+		&i.AvatarUploadID,
+	)
+	return i, err
+}
+
+const updateUserRole = `-- name: UpdateUserRole :one
+UPDATE "Users"
+SET role = $2
+WHERE id = $1
+RETURNING id, username, email, is_email_verified, password_hash, profile_picture_url, is_online, last_seen_at, role, is_banned, banned_at, banned_reason, created_at, is_bot, bot_owner_id, deletion_requested_at, username_changed_at, avatar_upload_id
+`
+
+type UpdateUserRoleParams struct {
+	ID   int64  `json:"id"`
+	Role string `json:"role"`
+}
+
+func (q *Queries) UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error) {
+	row := q.db.QueryRow(ctx, updateUserRole, arg.ID, arg.Role)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.IsEmailVerified,
+		&i.PasswordHash,
+		&i.ProfilePictureUrl,
+		&i.IsOnline,
+		&i.LastSeenAt,
+		&i.Role,
+		&i.IsBanned,
+		&i.BannedAt,
+		&i.BannedReason,
+		&i.CreatedAt,
+		&i.IsBot,
+		&i.BotOwnerID,
+		&i.DeletionRequestedAt,
+		&i.UsernameChangedAt,
+		&i.AvatarUploadID,
+	)
+	return i, err
 }
@@ -41,6 +41,16 @@ func (q *Queries) CreateVerifyEmail(ctx context.Context, arg CreateVerifyEmailPa
 	return i, err
 }
 
+const deleteVerifyEmails = `-- name: DeleteVerifyEmails :exec
+DELETE FROM "Verify_Emails"
+WHERE username = $1
+`
+
+func (q *Queries) DeleteVerifyEmails(ctx context.Context, username string) error {
+	_, err := q.db.Exec(ctx, deleteVerifyEmails, username)
+	return err
+}
+
 const updateVerifyEmail = `-- name: UpdateVerifyEmail :one
 UPDATE "Verify_Emails"
 SET
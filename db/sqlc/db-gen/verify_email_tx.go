@@ -2,10 +2,13 @@ package db
 
 import (
 	"context"
+	"errors"
 
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+var ErrVerifyEmailOutdated = errors.New("verification code is for a previous email address")
+
 type VerifyEmailTxParams struct {
 	EmailID    int64
 	SecretCode string
@@ -31,6 +34,15 @@ func (store *SQLStore) VerifyEmailTx(ctx context.Context,
 			return err
 		}
 
+		// the address may have changed since the code was sent
+		user, err := q.GetUserByUsername(ctx, result.VerifyEmail.Username)
+		if err != nil {
+			return err
+		}
+		if user.Email != result.VerifyEmail.Email {
+			return ErrVerifyEmailOutdated
+		}
+
 		result.User, err = q.UpdateUser(ctx, UpdateUserParams{
 			Username: result.VerifyEmail.Username,
 			IsEmailVerified: pgtype.Bool{
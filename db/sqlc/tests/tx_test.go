@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/kratos069/message-app/db/sqlc/db-gen"
 	"github.com/kratos069/message-app/util"
@@ -181,6 +182,164 @@ func TestSendMessageTxInvalidConversation(t *testing.T) {
 	require.Empty(t, result.Message.MessagesID)
 }
 
+func TestSendMessageTxBlocked(t *testing.T) {
+	ctx := context.Background()
+
+	user1 := createRandomUser(t)
+	user2 := createRandomUser(t)
+
+	convResult, err := testStore.CreateConversationTx(
+		ctx, db.CreateConversationTxParams{
+			User1ID: user1.ID,
+			User2ID: user2.ID,
+		})
+	require.NoError(t, err)
+
+	err = testStore.BlockUser(ctx, db.BlockUserParams{
+		BlockerID: user2.ID,
+		BlockedID: user1.ID,
+	})
+	require.NoError(t, err)
+
+	// Neither side can send while the block exists
+	for _, sender := range []db.User{user1, user2} {
+		clientMsgID := util.RandomClientMessageID()
+		_, err = testStore.SendMessageTx(ctx,
+			db.SendMessageTxParams{
+				ConversationID:   convResult.Conversation.ConversationsID,
+				SenderID:         sender.ID,
+				EncryptedContent: util.RandomEncryptedContent(),
+				ClientMessageID:  &clientMsgID,
+			})
+		require.ErrorIs(t, err, db.ErrUserBlocked)
+	}
+
+	err = testStore.UnblockUser(ctx, db.UnblockUserParams{
+		BlockerID: user2.ID,
+		BlockedID: user1.ID,
+	})
+	require.NoError(t, err)
+
+	clientMsgID := util.RandomClientMessageID()
+	_, err = testStore.SendMessageTx(ctx,
+		db.SendMessageTxParams{
+			ConversationID:   convResult.Conversation.ConversationsID,
+			SenderID:         user1.ID,
+			EncryptedContent: util.RandomEncryptedContent(),
+			ClientMessageID:  &clientMsgID,
+		})
+	require.NoError(t, err)
+}
+
+func TestSendMessageTxBlockedWithWebhook(t *testing.T) {
+	ctx := context.Background()
+
+	user1 := createRandomUser(t)
+	user2 := createRandomUser(t)
+
+	convResult, err := testStore.CreateConversationTx(
+		ctx, db.CreateConversationTxParams{
+			User1ID: user1.ID,
+			User2ID: user2.ID,
+		})
+	require.NoError(t, err)
+	conversationID := convResult.Conversation.ConversationsID
+
+	// the webhook's bot makes three participants, but only two people
+	_, prefix := util.GenerateAPIKey()
+	hook, err := testStore.CreateIncomingWebhookTx(ctx, db.CreateIncomingWebhookTxParams{
+		Bot: db.CreateBotParams{
+			Username:     util.RandomUsername(),
+			Email:        util.RandomEmail(),
+			PasswordHash: util.RandomString(60),
+			Role:         util.CustomerRole,
+			BotOwnerID:   pgtype.Int8{Int64: user1.ID, Valid: true},
+		},
+		ConversationID: conversationID,
+		Name:           "ci",
+		Prefix:         prefix,
+		TokenHash:      util.HashSecret(util.RandomString(32)),
+	})
+	require.NoError(t, err)
+
+	// the bot isn't listed as the other side of the conversation
+	conversations, err := testStore.GetUserConversationsWithLastMessage(ctx,
+		db.GetUserConversationsWithLastMessageParams{
+			UserID: user2.ID,
+			Inbox:  util.InboxPrimary,
+			Limit:  10,
+		})
+	require.NoError(t, err)
+	require.Len(t, conversations, 1)
+	require.Equal(t, user1.ID, conversations[0].OtherUserID)
+
+	// nor picked as the sender of a message request
+	people, err := testStore.ListConversationPeople(ctx, conversationID)
+	require.NoError(t, err)
+	require.Len(t, people, 2)
+	for _, person := range people {
+		require.NotEqual(t, hook.Bot.ID, person.UserID)
+	}
+
+	err = testStore.BlockUser(ctx, db.BlockUserParams{
+		BlockerID: user2.ID,
+		BlockedID: user1.ID,
+	})
+	require.NoError(t, err)
+
+	// the blocked user can't send, neither directly nor through the bot
+	for _, senderID := range []int64{user1.ID, user2.ID, hook.Bot.ID} {
+		clientMsgID := util.RandomClientMessageID()
+		_, err = testStore.SendMessageTx(ctx,
+			db.SendMessageTxParams{
+				ConversationID:   conversationID,
+				SenderID:         senderID,
+				EncryptedContent: util.RandomEncryptedContent(),
+				ClientMessageID:  &clientMsgID,
+			})
+		require.ErrorIs(t, err, db.ErrUserBlocked)
+	}
+}
+
+func TestSendMessageTxBlockedBot(t *testing.T) {
+	ctx := context.Background()
+
+	owner := createRandomUser(t)
+	user := createRandomUser(t)
+	bot, err := testStore.CreateBot(ctx, db.CreateBotParams{
+		Username:     util.RandomUsername(),
+		Email:        util.RandomEmail(),
+		PasswordHash: util.RandomString(60),
+		Role:         util.CustomerRole,
+		BotOwnerID:   pgtype.Int8{Int64: owner.ID, Valid: true},
+	})
+	require.NoError(t, err)
+
+	// a bot with an API key talks for itself, blocking it is enough
+	convResult, err := testStore.CreateConversationTx(
+		ctx, db.CreateConversationTxParams{
+			User1ID: bot.ID,
+			User2ID: user.ID,
+		})
+	require.NoError(t, err)
+
+	err = testStore.BlockUser(ctx, db.BlockUserParams{
+		BlockerID: user.ID,
+		BlockedID: bot.ID,
+	})
+	require.NoError(t, err)
+
+	clientMsgID := util.RandomClientMessageID()
+	_, err = testStore.SendMessageTx(ctx,
+		db.SendMessageTxParams{
+			ConversationID:   convResult.Conversation.ConversationsID,
+			SenderID:         bot.ID,
+			EncryptedContent: util.RandomEncryptedContent(),
+			ClientMessageID:  &clientMsgID,
+		})
+	require.ErrorIs(t, err, db.ErrUserBlocked)
+}
+
 // ============================================
 // TEST: GetOrCreateDirectConversationTx
 // ============================================
@@ -245,6 +404,75 @@ func TestGetOrCreateDirectConversationTxGet(t *testing.T) {
 	require.Equal(t, result1.Conversation.ConversationsID, result3.Conversation.ConversationsID)
 }
 
+func TestGetOrCreateDirectConversationTxBlocked(t *testing.T) {
+	ctx := context.Background()
+
+	user1 := createRandomUser(t)
+	user2 := createRandomUser(t)
+
+	err := testStore.BlockUser(ctx, db.BlockUserParams{
+		BlockerID: user1.ID,
+		BlockedID: user2.ID,
+	})
+	require.NoError(t, err)
+
+	// Blocked either way, whoever asks
+	_, err = testStore.GetOrCreateDirectConversationTx(
+		ctx, db.GetOrCreateDirectConversationTxParams{
+			User1ID: user2.ID,
+			User2ID: user1.ID,
+		})
+	require.ErrorIs(t, err, db.ErrUserBlocked)
+
+	_, err = testStore.GetOrCreateDirectConversationTx(
+		ctx, db.GetOrCreateDirectConversationTxParams{
+			User1ID: user1.ID,
+			User2ID: user2.ID,
+		})
+	require.ErrorIs(t, err, db.ErrUserBlocked)
+}
+
+func TestGetOrCreateDirectConversationTxRequest(t *testing.T) {
+	ctx := context.Background()
+
+	user1 := createRandomUser(t)
+	user2 := createRandomUser(t)
+
+	result, err := testStore.GetOrCreateDirectConversationTx(
+		ctx, db.GetOrCreateDirectConversationTxParams{
+			User1ID: user1.ID,
+			User2ID: user2.ID,
+			Request: true,
+		})
+	require.NoError(t, err)
+	require.True(t, result.IsNew)
+
+	// Only the recipient gets it as a request
+	sender, err := testStore.GetConversationParticipant(ctx,
+		db.GetConversationParticipantParams{
+			ConversationID: result.Conversation.ConversationsID,
+			UserID:         user1.ID,
+		})
+	require.NoError(t, err)
+	require.Equal(t, "primary", sender.Inbox)
+
+	recipient, err := testStore.GetConversationParticipant(ctx,
+		db.GetConversationParticipantParams{
+			ConversationID: result.Conversation.ConversationsID,
+			UserID:         user2.ID,
+		})
+	require.NoError(t, err)
+	require.Equal(t, "requests", recipient.Inbox)
+
+	pending, err := testStore.ListPendingRequestUserIDs(ctx,
+		db.ListPendingRequestUserIDsParams{
+			UserID:  user1.ID,
+			UserIds: []int64{user2.ID},
+		})
+	require.NoError(t, err)
+	require.Equal(t, []int64{user2.ID}, pending)
+}
+
 // 10 simultaneous requests chat between the same two users
 // ➜ Only one conversation should be created
 // ➜ All other calls should return that same conversation
@@ -456,3 +684,333 @@ func TestMarkMessagesAsReadTxNewMessages(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, int64(1), unread)
 }
+
+// ============================================
+// TEST: ResetPasswordTx
+// ============================================
+
+func TestResetPasswordTx(t *testing.T) {
+	ctx := context.Background()
+
+	user := createRandomUser(t)
+
+	session, err := testStore.CreateSession(ctx, db.CreateSessionParams{
+		ID:           util.RandomUUID(),
+		Username:     user.Username,
+		RefreshToken: util.RandomString(32),
+		UserAgent:    "test",
+		ClientIp:     "127.0.0.1",
+		ExpiredAt:    time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	reset, err := testStore.CreatePasswordReset(ctx, db.CreatePasswordResetParams{
+		Username:   user.Username,
+		Email:      user.Email,
+		SecretCode: util.RandomString(10),
+	})
+	require.NoError(t, err)
+
+	newHash, err := util.HashPassword(util.RandomString(8))
+	require.NoError(t, err)
+
+	arg := db.ResetPasswordTxParams{
+		Email:        user.Email,
+		SecretCode:   reset.SecretCode,
+		PasswordHash: newHash,
+	}
+
+	result, err := testStore.ResetPasswordTx(ctx, arg)
+	require.NoError(t, err)
+	require.Equal(t, user.ID, result.User.ID)
+	require.Equal(t, newHash, result.User.PasswordHash)
+	require.True(t, result.PasswordReset.IsUsed)
+
+	// every session was blocked
+	session, err = testStore.GetSessionByID(ctx, session.ID)
+	require.NoError(t, err)
+	require.True(t, session.IsBlocked)
+
+	// code is single-use
+	_, err = testStore.ResetPasswordTx(ctx, arg)
+	require.Error(t, err)
+}
+
+// ============================================
+// TEST: ChangeRoleTx
+// ============================================
+
+func TestChangeRoleTx(t *testing.T) {
+	ctx := context.Background()
+
+	actor := createRandomUser(t)
+	user1 := createRandomUser(t)
+	user2 := createRandomUser(t)
+
+	for _, user := range []db.User{user1, user2} {
+		result, err := testStore.ChangeRoleTx(ctx, db.ChangeRoleTxParams{
+			ActorID:   actor.ID,
+			TargetID:  user.ID,
+			NewRole:   util.AdminRole,
+			AdminRole: util.AdminRole,
+		})
+		require.NoError(t, err)
+		require.Equal(t, util.AdminRole, result.User.Role)
+
+		require.Equal(t, actor.ID, result.AuditLog.ActorID.Int64)
+		require.Equal(t, user.ID, result.AuditLog.TargetID.Int64)
+		require.Equal(t, user.Username, result.AuditLog.TargetUsername)
+		require.Equal(t, util.CustomerRole, result.AuditLog.OldRole)
+		require.Equal(t, util.AdminRole, result.AuditLog.NewRole)
+	}
+
+	_, err := testStore.ChangeRoleTx(ctx, db.ChangeRoleTxParams{
+		ActorID:   actor.ID,
+		TargetID:  user1.ID,
+		NewRole:   util.AdminRole,
+		AdminRole: util.AdminRole,
+	})
+	require.ErrorIs(t, err, db.ErrRoleUnchanged)
+
+	// user2 is still an admin
+	result, err := testStore.ChangeRoleTx(ctx, db.ChangeRoleTxParams{
+		ActorID:   actor.ID,
+		TargetID:  user1.ID,
+		NewRole:   util.CustomerRole,
+		AdminRole: util.AdminRole,
+	})
+	require.NoError(t, err)
+	require.Equal(t, util.CustomerRole, result.User.Role)
+}
+
+func TestChangeRoleTxLastActiveAdmin(t *testing.T) {
+	ctx := context.Background()
+
+	// the guard counts admins across the whole database, so admins
+	// left behind by other tests can't count
+	adminIDs, err := testStore.ListAdminIDsForUpdate(ctx, util.AdminRole)
+	require.NoError(t, err)
+	for _, adminID := range adminIDs {
+		err = testStore.BanUser(ctx, db.BanUserParams{ID: adminID})
+		require.NoError(t, err)
+	}
+
+	active := createRandomUser(t)
+	banned := createRandomUser(t)
+	for _, user := range []db.User{active, banned} {
+		_, err = testStore.UpdateUserRole(ctx, db.UpdateUserRoleParams{
+			ID:   user.ID,
+			Role: util.AdminRole,
+		})
+		require.NoError(t, err)
+	}
+	err = testStore.BanUser(ctx, db.BanUserParams{ID: banned.ID})
+	require.NoError(t, err)
+
+	// the banned admin can't log in, so it doesn't count
+	_, err = testStore.ChangeRoleTx(ctx, db.ChangeRoleTxParams{
+		ActorID:   active.ID,
+		TargetID:  active.ID,
+		NewRole:   util.CustomerRole,
+		AdminRole: util.AdminRole,
+	})
+	require.ErrorIs(t, err, db.ErrLastAdmin)
+
+	// while demoting the banned one is fine
+	result, err := testStore.ChangeRoleTx(ctx, db.ChangeRoleTxParams{
+		ActorID:   active.ID,
+		TargetID:  banned.ID,
+		NewRole:   util.CustomerRole,
+		AdminRole: util.AdminRole,
+	})
+	require.NoError(t, err)
+	require.Equal(t, util.CustomerRole, result.User.Role)
+}
+
+// ============================================
+// TEST: RequestUserDeletionTx
+// ============================================
+
+func TestRequestUserDeletionTxLastAdmin(t *testing.T) {
+	ctx := context.Background()
+
+	// the guard counts admins across the whole database
+	adminIDs, err := testStore.ListAdminIDsForUpdate(ctx, util.AdminRole)
+	require.NoError(t, err)
+	for _, adminID := range adminIDs {
+		err = testStore.BanUser(ctx, db.BanUserParams{ID: adminID})
+		require.NoError(t, err)
+	}
+
+	admin := createRandomUser(t)
+	_, err = testStore.UpdateUserRole(ctx, db.UpdateUserRoleParams{
+		ID:   admin.ID,
+		Role: util.AdminRole,
+	})
+	require.NoError(t, err)
+
+	_, err = testStore.RequestUserDeletionTx(ctx, db.RequestUserDeletionTxParams{
+		UserID:    admin.ID,
+		AdminRole: util.AdminRole,
+	})
+	require.ErrorIs(t, err, db.ErrLastAdmin)
+
+	user, err := testStore.GetUserByID(ctx, admin.ID)
+	require.NoError(t, err)
+	require.False(t, user.DeletionRequestedAt.Valid)
+
+	// with a second admin around the first one can leave
+	other := createRandomUser(t)
+	_, err = testStore.UpdateUserRole(ctx, db.UpdateUserRoleParams{
+		ID:   other.ID,
+		Role: util.AdminRole,
+	})
+	require.NoError(t, err)
+
+	result, err := testStore.RequestUserDeletionTx(ctx, db.RequestUserDeletionTxParams{
+		UserID:    admin.ID,
+		AdminRole: util.AdminRole,
+	})
+	require.NoError(t, err)
+	require.True(t, result.User.DeletionRequestedAt.Valid)
+}
+
+// ============================================
+// TEST: RotateAPIKeyTx
+// ============================================
+
+func TestRotateAPIKeyTx(t *testing.T) {
+	ctx := context.Background()
+
+	owner := createRandomUser(t)
+	bot, err := testStore.CreateBot(ctx, db.CreateBotParams{
+		Username:     util.RandomUsername(),
+		Email:        util.RandomEmail(),
+		PasswordHash: util.RandomString(60),
+		Role:         util.CustomerRole,
+		BotOwnerID:   pgtype.Int8{Int64: owner.ID, Valid: true},
+	})
+	require.NoError(t, err)
+	require.True(t, bot.IsBot)
+
+	newKeyParams := func() db.CreateAPIKeyParams {
+		key, prefix := util.GenerateAPIKey()
+		return db.CreateAPIKeyParams{
+			UserID:  bot.ID,
+			Name:    "ci",
+			Prefix:  prefix,
+			KeyHash: util.HashSecret(key),
+			Scopes:  []string{util.ScopeMessagesSend},
+		}
+	}
+
+	oldKey, err := testStore.CreateAPIKey(ctx, newKeyParams())
+	require.NoError(t, err)
+
+	result, err := testStore.RotateAPIKeyTx(ctx, db.RotateAPIKeyTxParams{
+		OldAPIKeyID: oldKey.ApiKeyID,
+		NewAPIKey:   newKeyParams(),
+	})
+	require.NoError(t, err)
+	require.True(t, result.OldAPIKey.RevokedAt.Valid)
+	require.False(t, result.NewAPIKey.RevokedAt.Valid)
+	require.Equal(t, []string{util.ScopeMessagesSend}, result.NewAPIKey.Scopes)
+
+	// a revoked key can't be rotated again
+	_, err = testStore.RotateAPIKeyTx(ctx, db.RotateAPIKeyTxParams{
+		OldAPIKeyID: oldKey.ApiKeyID,
+		NewAPIKey:   newKeyParams(),
+	})
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+}
+
+// ============================================
+// TEST: PurgeUserTx
+// ============================================
+
+func TestPurgeUserTx(t *testing.T) {
+	ctx := context.Background()
+
+	user := createRandomUser(t)
+
+	user, err := testStore.RequestUserDeletion(ctx, user.ID)
+	require.NoError(t, err)
+	require.True(t, user.DeletionRequestedAt.Valid)
+	requestedAt := user.DeletionRequestedAt.Time
+
+	// logging in cancelled the request the purge was scheduled for
+	err = testStore.CancelUserDeletion(ctx, user.ID)
+	require.NoError(t, err)
+
+	_, err = testStore.PurgeUserTx(ctx, db.PurgeUserTxParams{
+		UserID:      user.ID,
+		RequestedAt: requestedAt,
+	})
+	require.ErrorIs(t, err, db.ErrDeletionCancelled)
+
+	// a bot with a key and an incoming webhook, both must stop working
+	owner := pgtype.Int8{Int64: user.ID, Valid: true}
+	bot, err := testStore.CreateBot(ctx, db.CreateBotParams{
+		Username:     util.RandomUsername(),
+		Email:        util.RandomEmail(),
+		PasswordHash: util.RandomString(60),
+		Role:         util.CustomerRole,
+		BotOwnerID:   owner,
+	})
+	require.NoError(t, err)
+	key, prefix := util.GenerateAPIKey()
+	apiKey, err := testStore.CreateAPIKey(ctx, db.CreateAPIKeyParams{
+		UserID:  bot.ID,
+		Name:    "ci",
+		Prefix:  prefix,
+		KeyHash: util.HashSecret(key),
+		Scopes:  []string{util.ScopeMessagesSend},
+	})
+	require.NoError(t, err)
+
+	other := createRandomUser(t)
+	convResult, err := testStore.CreateConversationTx(
+		ctx, db.CreateConversationTxParams{
+			User1ID: user.ID,
+			User2ID: other.ID,
+		})
+	require.NoError(t, err)
+	_, hookPrefix := util.GenerateAPIKey()
+	hook, err := testStore.CreateIncomingWebhookTx(ctx, db.CreateIncomingWebhookTxParams{
+		Bot: db.CreateBotParams{
+			Username:     util.RandomUsername(),
+			Email:        util.RandomEmail(),
+			PasswordHash: util.RandomString(60),
+			Role:         util.CustomerRole,
+			BotOwnerID:   owner,
+		},
+		ConversationID: convResult.Conversation.ConversationsID,
+		Name:           "ci",
+		Prefix:         hookPrefix,
+		TokenHash:      util.HashSecret(util.RandomString(32)),
+	})
+	require.NoError(t, err)
+
+	user, err = testStore.RequestUserDeletion(ctx, user.ID)
+	require.NoError(t, err)
+
+	result, err := testStore.PurgeUserTx(ctx, db.PurgeUserTxParams{
+		UserID:      user.ID,
+		RequestedAt: user.DeletionRequestedAt.Time,
+	})
+	require.NoError(t, err)
+	require.Equal(t, user.Username, result.User.Username)
+
+	_, err = testStore.GetUserByID(ctx, user.ID)
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+
+	apiKey, err = testStore.GetAPIKey(ctx, apiKey.ApiKeyID)
+	require.NoError(t, err)
+	require.True(t, apiKey.RevokedAt.Valid)
+
+	incomingWebhook, err := testStore.GetIncomingWebhook(ctx,
+		hook.IncomingWebhook.IncomingWebhookID)
+	require.NoError(t, err)
+	require.True(t, incomingWebhook.RevokedAt.Valid)
+	require.False(t, incomingWebhook.CreatedBy.Valid)
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/kratos069/message-app/db/sqlc/db-gen"
 	"github.com/kratos069/message-app/util"
 	"github.com/stretchr/testify/require"
@@ -65,3 +66,147 @@ func TestGetUserByID(t *testing.T) {
 	require.Equal(t, user1.PasswordHash, user2.PasswordHash)
 	require.WithinDuration(t, user1.CreatedAt, user2.CreatedAt, time.Second)
 }
+
+func createUserNamed(t *testing.T, username string) db.User {
+	user, err := testStore.CreateUser(context.Background(), db.CreateUserParams{
+		Username:     username,
+		PasswordHash: util.RandomString(20),
+		Email:        util.RandomEmail(),
+		Role:         util.CustomerRole,
+	})
+	require.NoError(t, err)
+	return user
+}
+
+func searchUsers(t *testing.T, viewerID int64, query string,
+	after db.SearchUsersRow, limit int32) []db.SearchUsersRow {
+	users, err := testStore.SearchUsers(context.Background(),
+		db.SearchUsersParams{
+			Contains:      "%" + query + "%",
+			Query:         query,
+			ViewerID:      viewerID,
+			Prefix:        query + "%",
+			AfterTier:     after.Tier,
+			AfterUsername: after.Username,
+			Limit:         limit,
+		})
+	require.NoError(t, err)
+	return users
+}
+
+func TestSearchUsersRanking(t *testing.T) {
+	ctx := context.Background()
+	viewer := createRandomUser(t)
+
+	base := "srch" + util.RandomString(10)
+	exact := createUserNamed(t, base)
+	prefix := createUserNamed(t, base+"x")
+	prefixContact := createUserNamed(t, base+"y")
+	fuzzy := createUserNamed(t, "zz"+base)
+	banned := createUserNamed(t, base+"b")
+	deleted := createUserNamed(t, base+"d")
+	blocking := createUserNamed(t, base+"k")
+
+	_, err := testStore.UpsertContact(ctx, db.UpsertContactParams{
+		OwnerID:   viewer.ID,
+		ContactID: prefixContact.ID,
+	})
+	require.NoError(t, err)
+	require.NoError(t, testStore.BanUser(ctx, db.BanUserParams{ID: banned.ID}))
+	_, err = testStore.RequestUserDeletion(ctx, deleted.ID)
+	require.NoError(t, err)
+	require.NoError(t, testStore.BlockUser(ctx, db.BlockUserParams{
+		BlockerID: blocking.ID,
+		BlockedID: viewer.ID,
+	}))
+
+	// exact, then prefix with contacts first, then fuzzy
+	start := db.SearchUsersRow{Tier: -1}
+	users := searchUsers(t, viewer.ID, base, start, 10)
+	require.Len(t, users, 4)
+	require.Equal(t, exact.ID, users[0].ID)
+	require.Equal(t, prefixContact.ID, users[1].ID)
+	require.True(t, users[1].IsContact)
+	require.Equal(t, prefix.ID, users[2].ID)
+	require.Equal(t, fuzzy.ID, users[3].ID)
+
+	// pages pick up after the last row
+	page := searchUsers(t, viewer.ID, base, start, 2)
+	require.Equal(t, users[:2], page)
+	page = searchUsers(t, viewer.ID, base, page[1], 2)
+	require.Equal(t, users[2:], page)
+	require.Empty(t, searchUsers(t, viewer.ID, base, page[1], 2))
+
+	// nobody finds themselves
+	for _, user := range searchUsers(t, exact.ID, base, start, 10) {
+		require.NotEqual(t, exact.ID, user.ID)
+	}
+}
+
+func TestSearchUsersContacts(t *testing.T) {
+	ctx := context.Background()
+
+	owner := createRandomUser(t)
+	friend := createRandomUser(t)
+
+	nickname := "nick" + util.RandomString(10)
+	_, err := testStore.UpsertContact(ctx, db.UpsertContactParams{
+		OwnerID:   owner.ID,
+		ContactID: friend.ID,
+		Nickname:  pgtype.Text{String: nickname, Valid: true},
+	})
+	require.NoError(t, err)
+
+	// nicknames match too, only for their owner
+	users := searchUsers(t, owner.ID, nickname, db.SearchUsersRow{Tier: -1}, 10)
+	require.Len(t, users, 1)
+	require.Equal(t, friend.ID, users[0].ID)
+	require.True(t, users[0].IsContact)
+	require.Equal(t, nickname, users[0].Nickname.String)
+	require.Empty(t, searchUsers(t, friend.ID, nickname,
+		db.SearchUsersRow{Tier: -1}, 10))
+
+	users, err = testStore.SearchUsers(ctx, db.SearchUsersParams{
+		Contains:     "%" + friend.Username + "%",
+		Query:        friend.Username,
+		ViewerID:     owner.ID,
+		Prefix:       friend.Username + "%",
+		ContactsOnly: true,
+		AfterTier:    -1,
+		Limit:        10,
+	})
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	require.Equal(t, friend.ID, users[0].ID)
+}
+
+func TestMarkUserOffline(t *testing.T) {
+	ctx := context.Background()
+	user := createRandomUser(t)
+
+	require.NoError(t, testStore.MarkUserOnline(ctx, user.ID))
+	online, err := testStore.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	require.True(t, online.IsOnline)
+
+	// an expiry from before the user came back online is ignored
+	err = testStore.MarkUserOffline(ctx, db.MarkUserOfflineParams{
+		ID:         user.ID,
+		LastSeenAt: pgtype.Timestamptz{Time: time.Now().Add(-time.Hour), Valid: true},
+	})
+	require.NoError(t, err)
+	stale, err := testStore.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	require.True(t, stale.IsOnline)
+
+	lastSeenAt := time.Now().Add(time.Second)
+	err = testStore.MarkUserOffline(ctx, db.MarkUserOfflineParams{
+		ID:         user.ID,
+		LastSeenAt: pgtype.Timestamptz{Time: lastSeenAt, Valid: true},
+	})
+	require.NoError(t, err)
+	offline, err := testStore.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	require.False(t, offline.IsOnline)
+	require.WithinDuration(t, lastSeenAt, offline.LastSeenAt.Time, time.Millisecond)
+}
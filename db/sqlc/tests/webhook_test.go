@@ -0,0 +1,111 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomWebhook(t *testing.T, ownerID int64,
+	conversationID pgtype.Int8) db.Webhook {
+	webhook, err := testStore.CreateWebhook(context.Background(),
+		db.CreateWebhookParams{
+			OwnerID:        ownerID,
+			ConversationID: conversationID,
+			Url:            "https://example.com/hook",
+			Secret:         util.RandomString(32),
+			Events:         []string{util.WebhookEventMessageSent},
+		})
+	require.NoError(t, err)
+
+	return webhook
+}
+
+// other tests' webhooks are listed too, so only look for ours
+func isWebhookListed(t *testing.T, webhook db.Webhook,
+	conversationID pgtype.Int8) bool {
+	webhooks, err := testStore.ListWebhooksForEvent(context.Background(),
+		db.ListWebhooksForEventParams{
+			Event:          util.WebhookEventMessageSent,
+			AdminRole:      util.AdminRole,
+			ConversationID: conversationID,
+		})
+	require.NoError(t, err)
+
+	for _, listed := range webhooks {
+		if listed.WebhookID == webhook.WebhookID {
+			return true
+		}
+	}
+	return false
+}
+
+func TestListWebhooksForEventOwner(t *testing.T) {
+	ctx := context.Background()
+
+	owner := createRandomUser(t)
+	other := createRandomUser(t)
+	convResult, err := testStore.CreateConversationTx(
+		ctx, db.CreateConversationTxParams{
+			User1ID: owner.ID,
+			User2ID: other.ID,
+		})
+	require.NoError(t, err)
+	conversationID := pgtype.Int8{
+		Int64: convResult.Conversation.ConversationsID,
+		Valid: true,
+	}
+
+	webhook := createRandomWebhook(t, owner.ID, conversationID)
+	require.True(t, isWebhookListed(t, webhook, conversationID))
+
+	// leaving the conversation ends the subscription
+	err = testStore.RemoveParticipantFromConversation(ctx,
+		db.RemoveParticipantFromConversationParams{
+			ConversationID: conversationID.Int64,
+			UserID:         owner.ID,
+		})
+	require.NoError(t, err)
+	require.False(t, isWebhookListed(t, webhook, conversationID))
+
+	// so does a ban
+	banned := createRandomUser(t)
+	_, err = testStore.AddParticipantToConversation(ctx,
+		db.AddParticipantToConversationParams{
+			ConversationID: conversationID.Int64,
+			UserID:         banned.ID,
+		})
+	require.NoError(t, err)
+	webhook = createRandomWebhook(t, banned.ID, conversationID)
+	require.True(t, isWebhookListed(t, webhook, conversationID))
+
+	err = testStore.BanUser(ctx, db.BanUserParams{ID: banned.ID})
+	require.NoError(t, err)
+	require.False(t, isWebhookListed(t, webhook, conversationID))
+}
+
+func TestListWebhooksForEventGlobal(t *testing.T) {
+	ctx := context.Background()
+
+	admin := createRandomUser(t)
+	_, err := testStore.UpdateUserRole(ctx, db.UpdateUserRoleParams{
+		ID:   admin.ID,
+		Role: util.AdminRole,
+	})
+	require.NoError(t, err)
+
+	webhook := createRandomWebhook(t, admin.ID, pgtype.Int8{})
+	require.True(t, isWebhookListed(t, webhook, pgtype.Int8{}))
+
+	// a former admin's global webhook stops with the demotion
+	_, err = testStore.UpdateUserRole(ctx, db.UpdateUserRoleParams{
+		ID:   admin.ID,
+		Role: util.CustomerRole,
+	})
+	require.NoError(t, err)
+	require.False(t, isWebhookListed(t, webhook, pgtype.Int8{}))
+}
@@ -0,0 +1,69 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+// what happened in a conversation
+const (
+	TypeMessageSent   = "message.sent"
+	TypeMessagesRead  = "messages.read"
+	TypeTypingStarted = "typing.started"
+	TypeTypingStopped = "typing.stopped"
+	TypeMemberJoined  = "member.joined"
+)
+
+// how many events a subscription holds before new ones are dropped
+const subscriptionBuffer = 64
+
+// Event is something that happened in a conversation, delivered to
+// subscribers of that conversation on every instance
+type Event struct {
+	Type           string          `json:"type"`
+	ConversationID int64           `json:"conversation_id"`
+	OccurredAt     time.Time       `json:"occurred_at"`
+	Data           json.RawMessage `json:"data"`
+	// who may see the event, everyone in the conversation when empty
+	Recipients []int64 `json:"recipients,omitempty"`
+}
+
+// NewEvent encodes data as the payload of a new event
+func NewEvent(eventType string, conversationID int64, data any) (Event, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+
+	return Event{
+		Type:           eventType,
+		ConversationID: conversationID,
+		OccurredAt:     time.Now().UTC(),
+		Data:           payload,
+	}, nil
+}
+
+// For reports whether the event may be delivered to userID
+func (event Event) For(userID int64) bool {
+	return len(event.Recipients) == 0 || slices.Contains(event.Recipients, userID)
+}
+
+// Bus fans events out to subscribers. Delivery is at most once, slow
+// subscribers and ones that weren't subscribed yet miss events.
+// Subscribers get every event of their conversations and check For
+// before passing one on to a user.
+type Bus interface {
+	// sends the event to the subscribers of its conversation
+	Publish(ctx context.Context, event Event) error
+	// receives the events of the conversations until closed
+	Subscribe(ctx context.Context, conversationIDs ...int64) (Subscription, error)
+}
+
+// Subscription is one subscriber's stream of events
+type Subscription interface {
+	// closed once the subscription is
+	Events() <-chan Event
+	Close() error
+}
@@ -0,0 +1,93 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryBus only reaches subscribers of this instance,
+// for single-node deployments and tests
+type MemoryBus struct {
+	mu sync.Mutex
+	// conversation id to its subscriptions
+	subscribers map[int64]map[*memorySubscription]struct{}
+}
+
+func NewMemoryBus() Bus {
+	return &MemoryBus{
+		subscribers: make(map[int64]map[*memorySubscription]struct{}),
+	}
+}
+
+func (bus *MemoryBus) Publish(ctx context.Context, event Event) error {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+
+	for subscription := range bus.subscribers[event.ConversationID] {
+		select {
+		case subscription.events <- event:
+		default:
+			// full, like a redis subscriber that fell behind
+		}
+	}
+
+	return nil
+}
+
+func (bus *MemoryBus) Subscribe(ctx context.Context,
+	conversationIDs ...int64) (Subscription, error) {
+	subscription := &memorySubscription{
+		bus:             bus,
+		conversationIDs: conversationIDs,
+		events:          make(chan Event, subscriptionBuffer),
+	}
+
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+
+	for _, conversationID := range conversationIDs {
+		subscriptions, ok := bus.subscribers[conversationID]
+		if !ok {
+			subscriptions = make(map[*memorySubscription]struct{})
+			bus.subscribers[conversationID] = subscriptions
+		}
+		subscriptions[subscription] = struct{}{}
+	}
+
+	return subscription, nil
+}
+
+type memorySubscription struct {
+	bus             *MemoryBus
+	conversationIDs []int64
+	events          chan Event
+	closed          bool
+}
+
+func (subscription *memorySubscription) Events() <-chan Event {
+	return subscription.events
+}
+
+func (subscription *memorySubscription) Close() error {
+	bus := subscription.bus
+
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+
+	if subscription.closed {
+		return nil
+	}
+	subscription.closed = true
+
+	for _, conversationID := range subscription.conversationIDs {
+		subscriptions := bus.subscribers[conversationID]
+		delete(subscriptions, subscription)
+		if len(subscriptions) == 0 {
+			delete(bus.subscribers, conversationID)
+		}
+	}
+	// Publish holds the lock too, so it never sends on a closed channel
+	close(subscription.events)
+
+	return nil
+}
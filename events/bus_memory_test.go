@@ -0,0 +1,65 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryBus(t *testing.T) {
+	ctx := context.Background()
+	bus := NewMemoryBus()
+
+	first, err := bus.Subscribe(ctx, 1, 2)
+	require.NoError(t, err)
+	second, err := bus.Subscribe(ctx, 2)
+	require.NoError(t, err)
+
+	event, err := NewEvent(TypeMessageSent, 2, map[string]int64{"message_id": 7})
+	require.NoError(t, err)
+	require.NoError(t, bus.Publish(ctx, event))
+
+	// every subscriber of the conversation gets it
+	for _, subscription := range []Subscription{first, second} {
+		received := <-subscription.Events()
+		require.Equal(t, TypeMessageSent, received.Type)
+		require.Equal(t, int64(2), received.ConversationID)
+
+		var data map[string]int64
+		require.NoError(t, json.Unmarshal(received.Data, &data))
+		require.Equal(t, int64(7), data["message_id"])
+	}
+
+	event, err = NewEvent(TypeTypingStarted, 1, nil)
+	require.NoError(t, err)
+	require.NoError(t, bus.Publish(ctx, event))
+	require.Equal(t, TypeTypingStarted, (<-first.Events()).Type)
+	require.Empty(t, second.Events())
+
+	// closing ends the stream, publishing afterwards is fine
+	require.NoError(t, first.Close())
+	require.NoError(t, first.Close())
+	_, ok := <-first.Events()
+	require.False(t, ok)
+	require.NoError(t, bus.Publish(ctx, event))
+
+	// a full subscription drops events instead of blocking
+	for range subscriptionBuffer + 1 {
+		event, err = NewEvent(TypeTypingStarted, 2, nil)
+		require.NoError(t, err)
+		require.NoError(t, bus.Publish(ctx, event))
+	}
+	require.Len(t, second.Events(), subscriptionBuffer)
+}
+
+func TestEventFor(t *testing.T) {
+	event, err := NewEvent(TypeMessagesRead, 1, nil)
+	require.NoError(t, err)
+	require.True(t, event.For(5))
+
+	event.Recipients = []int64{2, 3}
+	require.True(t, event.For(3))
+	require.False(t, event.For(5))
+}
@@ -0,0 +1,103 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// one pub/sub channel per conversation
+const conversationChannelPrefix = "events:conversation:"
+
+// RedisBus reaches subscribers on every server instance
+type RedisBus struct {
+	client *redis.Client
+}
+
+func NewRedisBus(client *redis.Client) Bus {
+	return &RedisBus{
+		client: client,
+	}
+}
+
+func (bus *RedisBus) Publish(ctx context.Context, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	err = bus.client.Publish(ctx, conversationChannel(event.ConversationID),
+		payload).Err()
+	if err != nil {
+		return fmt.Errorf("failed to publish event: %w", err)
+	}
+
+	return nil
+}
+
+func (bus *RedisBus) Subscribe(ctx context.Context,
+	conversationIDs ...int64) (Subscription, error) {
+	channels := make([]string, 0, len(conversationIDs))
+	for _, conversationID := range conversationIDs {
+		channels = append(channels, conversationChannel(conversationID))
+	}
+
+	pubsub := bus.client.Subscribe(ctx, channels...)
+
+	// events published before the confirmation would be missed
+	_, err := pubsub.Receive(ctx)
+	if err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	subscription := &redisSubscription{
+		pubsub: pubsub,
+		events: make(chan Event, subscriptionBuffer),
+	}
+	go subscription.forward()
+
+	return subscription, nil
+}
+
+type redisSubscription struct {
+	pubsub *redis.PubSub
+	events chan Event
+}
+
+// decodes messages until the pubsub is closed
+func (subscription *redisSubscription) forward() {
+	defer close(subscription.events)
+
+	for message := range subscription.pubsub.Channel() {
+		var event Event
+		err := json.Unmarshal([]byte(message.Payload), &event)
+		if err != nil {
+			log.Error().Err(err).Str("channel", message.Channel).
+				Msg("cannot decode event")
+			continue
+		}
+
+		select {
+		case subscription.events <- event:
+		default:
+			// the subscriber fell behind
+		}
+	}
+}
+
+func (subscription *redisSubscription) Events() <-chan Event {
+	return subscription.events
+}
+
+func (subscription *redisSubscription) Close() error {
+	return subscription.pubsub.Close()
+}
+
+func conversationChannel(conversationID int64) string {
+	return conversationChannelPrefix + strconv.FormatInt(conversationID, 10)
+}
@@ -0,0 +1,91 @@
+package limiter
+
+import (
+	"context"
+	"time"
+)
+
+// LoginLimiter tracks failed login attempts per account and per IP.
+// After a few failures every further attempt has to wait
+// exponentially longer, after many the account is locked for a while.
+type LoginLimiter interface {
+	// returns how long the caller has to wait before trying again,
+	// zero if the attempt is allowed
+	Check(ctx context.Context, account, ip string) (time.Duration, error)
+
+	// records a failed attempt, reports whether it locked the account
+	RecordFailure(ctx context.Context, account, ip string) (bool, error)
+
+	// clears the failures of an account after a successful login
+	Reset(ctx context.Context, account string) error
+
+	// lifts a lockout and clears the failures of an account
+	Unlock(ctx context.Context, account string) error
+}
+
+// Policy configures the limits of one scope (account or IP)
+type Policy struct {
+	// failures before delays kick in
+	BackoffAfter int64
+	// failures before the scope is locked out, 0 never locks
+	LockoutAfter    int64
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	LockoutDuration time.Duration
+	// failures are forgotten this long after the last one
+	Window time.Duration
+}
+
+// DefaultAccountPolicy locks an account after lockoutAfter failures
+func DefaultAccountPolicy(lockoutAfter int64,
+	lockoutDuration time.Duration) Policy {
+	if lockoutAfter == 0 {
+		lockoutAfter = 10
+	}
+	if lockoutDuration == 0 {
+		lockoutDuration = 15 * time.Minute
+	}
+
+	return Policy{
+		BackoffAfter:    3,
+		LockoutAfter:    lockoutAfter,
+		BaseDelay:       time.Second,
+		MaxDelay:        5 * time.Minute,
+		LockoutDuration: lockoutDuration,
+		Window:          time.Hour,
+	}
+}
+
+// DefaultIPPolicy is looser since many users can share an IP,
+// and never locks out
+func DefaultIPPolicy() Policy {
+	return Policy{
+		BackoffAfter: 20,
+		BaseDelay:    time.Second,
+		MaxDelay:     5 * time.Minute,
+		Window:       time.Hour,
+	}
+}
+
+// returns how long to wait after the last of count failures
+func (policy Policy) delay(count int64) time.Duration {
+	if count < policy.BackoffAfter {
+		return 0
+	}
+
+	shift := count - policy.BackoffAfter
+	if shift > 20 {
+		return policy.MaxDelay
+	}
+
+	return min(policy.BaseDelay<<shift, policy.MaxDelay)
+}
+
+// returns how long to wait from now, given failures and the last one's time
+func (policy Policy) wait(count int64, last time.Time, now time.Time) time.Duration {
+	return max(last.Add(policy.delay(count)).Sub(now), 0)
+}
+
+func (policy Policy) locks(count int64) bool {
+	return policy.LockoutAfter > 0 && count >= policy.LockoutAfter
+}
@@ -0,0 +1,126 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type attempts struct {
+	count       int64
+	last        time.Time
+	lockedUntil time.Time
+}
+
+// MemoryLoginLimiter keeps attempts in process memory,
+// for single-node deployments and tests
+type MemoryLoginLimiter struct {
+	mu            sync.Mutex
+	accountPolicy Policy
+	ipPolicy      Policy
+	accounts      map[string]*attempts
+	ips           map[string]*attempts
+}
+
+func NewMemoryLoginLimiter(accountPolicy, ipPolicy Policy) LoginLimiter {
+	return &MemoryLoginLimiter{
+		accountPolicy: accountPolicy,
+		ipPolicy:      ipPolicy,
+		accounts:      make(map[string]*attempts),
+		ips:           make(map[string]*attempts),
+	}
+}
+
+func (limiter *MemoryLoginLimiter) Check(ctx context.Context,
+	account, ip string) (time.Duration, error) {
+	now := time.Now()
+
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	accountWait := limiter.waitFor(limiter.accounts, account,
+		limiter.accountPolicy, now)
+	ipWait := limiter.waitFor(limiter.ips, ip, limiter.ipPolicy, now)
+
+	return max(accountWait, ipWait), nil
+}
+
+func (limiter *MemoryLoginLimiter) RecordFailure(ctx context.Context,
+	account, ip string) (bool, error) {
+	now := time.Now()
+
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	limiter.record(limiter.ips, ip, limiter.ipPolicy, now)
+	return limiter.record(limiter.accounts, account,
+		limiter.accountPolicy, now), nil
+}
+
+func (limiter *MemoryLoginLimiter) Reset(ctx context.Context,
+	account string) error {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	// a running lockout is not lifted by a successful login
+	if state, ok := limiter.accounts[account]; ok &&
+		time.Now().Before(state.lockedUntil) {
+		return nil
+	}
+
+	delete(limiter.accounts, account)
+	return nil
+}
+
+func (limiter *MemoryLoginLimiter) Unlock(ctx context.Context,
+	account string) error {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	delete(limiter.accounts, account)
+	return nil
+}
+
+// must be called with the lock held
+func (limiter *MemoryLoginLimiter) waitFor(states map[string]*attempts,
+	key string, policy Policy, now time.Time) time.Duration {
+	state, ok := states[key]
+	if !ok {
+		return 0
+	}
+
+	if now.Before(state.lockedUntil) {
+		return state.lockedUntil.Sub(now)
+	}
+
+	if now.Sub(state.last) > policy.Window {
+		delete(states, key)
+		return 0
+	}
+
+	return policy.wait(state.count, state.last, now)
+}
+
+// must be called with the lock held, reports whether the key got locked
+func (limiter *MemoryLoginLimiter) record(states map[string]*attempts,
+	key string, policy Policy, now time.Time) bool {
+	state, ok := states[key]
+	if !ok || now.Sub(state.last) > policy.Window {
+		state = &attempts{}
+		states[key] = state
+	}
+
+	state.count++
+	state.last = now
+
+	if policy.locks(state.count) {
+		// failures start over once the lockout ends
+		states[key] = &attempts{
+			last:        now,
+			lockedUntil: now.Add(policy.LockoutDuration),
+		}
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,125 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testPolicies() (Policy, Policy) {
+	account := Policy{
+		BackoffAfter:    2,
+		LockoutAfter:    4,
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Minute,
+		LockoutDuration: time.Hour,
+		Window:          time.Hour,
+	}
+	ip := Policy{
+		BackoffAfter: 10,
+		BaseDelay:    time.Second,
+		MaxDelay:     time.Minute,
+		Window:       time.Hour,
+	}
+
+	return account, ip
+}
+
+func TestPolicyDelay(t *testing.T) {
+	policy, _ := testPolicies()
+
+	require.Zero(t, policy.delay(0))
+	require.Zero(t, policy.delay(1))
+	require.Equal(t, time.Second, policy.delay(2))
+	require.Equal(t, 2*time.Second, policy.delay(3))
+	require.Equal(t, 4*time.Second, policy.delay(4))
+	require.Equal(t, time.Minute, policy.delay(10))
+	require.Equal(t, time.Minute, policy.delay(1000))
+}
+
+func TestMemoryLoginLimiterBackoff(t *testing.T) {
+	limiter := NewMemoryLoginLimiter(testPolicies())
+	ctx := context.Background()
+
+	locked, err := limiter.RecordFailure(ctx, "a@example.com", "1.2.3.4")
+	require.NoError(t, err)
+	require.False(t, locked)
+
+	wait, err := limiter.Check(ctx, "a@example.com", "1.2.3.4")
+	require.NoError(t, err)
+	require.Zero(t, wait)
+
+	_, err = limiter.RecordFailure(ctx, "a@example.com", "1.2.3.4")
+	require.NoError(t, err)
+
+	wait, err = limiter.Check(ctx, "a@example.com", "1.2.3.4")
+	require.NoError(t, err)
+	require.Positive(t, wait)
+	require.LessOrEqual(t, wait, time.Second)
+
+	// other accounts are not slowed down
+	wait, err = limiter.Check(ctx, "b@example.com", "1.2.3.4")
+	require.NoError(t, err)
+	require.Zero(t, wait)
+
+	require.NoError(t, limiter.Reset(ctx, "a@example.com"))
+
+	wait, err = limiter.Check(ctx, "a@example.com", "1.2.3.4")
+	require.NoError(t, err)
+	require.Zero(t, wait)
+}
+
+func TestMemoryLoginLimiterLockout(t *testing.T) {
+	limiter := NewMemoryLoginLimiter(testPolicies())
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		locked, err := limiter.RecordFailure(ctx, "a@example.com", "1.2.3.4")
+		require.NoError(t, err)
+		require.False(t, locked)
+	}
+
+	locked, err := limiter.RecordFailure(ctx, "a@example.com", "1.2.3.4")
+	require.NoError(t, err)
+	require.True(t, locked)
+
+	wait, err := limiter.Check(ctx, "a@example.com", "1.2.3.4")
+	require.NoError(t, err)
+	require.Greater(t, wait, 59*time.Minute)
+
+	// a correct password doesn't lift the lockout
+	require.NoError(t, limiter.Reset(ctx, "a@example.com"))
+	wait, err = limiter.Check(ctx, "a@example.com", "1.2.3.4")
+	require.NoError(t, err)
+	require.Positive(t, wait)
+
+	require.NoError(t, limiter.Unlock(ctx, "a@example.com"))
+	wait, err = limiter.Check(ctx, "a@example.com", "1.2.3.4")
+	require.NoError(t, err)
+	require.Zero(t, wait)
+}
+
+func TestMemoryLoginLimiterIP(t *testing.T) {
+	limiter := NewMemoryLoginLimiter(testPolicies())
+	ctx := context.Background()
+
+	// spraying many accounts from one IP
+	for i := 0; i < 10; i++ {
+		_, err := limiter.RecordFailure(ctx, testAccount(i), "1.2.3.4")
+		require.NoError(t, err)
+	}
+
+	wait, err := limiter.Check(ctx, "new@example.com", "1.2.3.4")
+	require.NoError(t, err)
+	require.Positive(t, wait)
+
+	wait, err = limiter.Check(ctx, "new@example.com", "5.6.7.8")
+	require.NoError(t, err)
+	require.Zero(t, wait)
+}
+
+func testAccount(i int) string {
+	return "user" + string(rune('a'+i)) + "@example.com"
+}
@@ -0,0 +1,165 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	loginFailureKeyPrefix = "login:fail:"
+	loginLockKeyPrefix    = "login:lock:"
+
+	scopeAccount = "account:"
+	scopeIP      = "ip:"
+)
+
+// RedisLoginLimiter shares attempts between all server instances
+type RedisLoginLimiter struct {
+	client        *redis.Client
+	accountPolicy Policy
+	ipPolicy      Policy
+}
+
+func NewRedisLoginLimiter(client *redis.Client,
+	accountPolicy, ipPolicy Policy) LoginLimiter {
+	return &RedisLoginLimiter{
+		client:        client,
+		accountPolicy: accountPolicy,
+		ipPolicy:      ipPolicy,
+	}
+}
+
+func (limiter *RedisLoginLimiter) Check(ctx context.Context,
+	account, ip string) (time.Duration, error) {
+	pipe := limiter.client.Pipeline()
+	lock := pipe.PTTL(ctx, loginLockKeyPrefix+scopeAccount+account)
+	accountFailures := pipe.HMGet(ctx,
+		loginFailureKeyPrefix+scopeAccount+account, "count", "last")
+	ipFailures := pipe.HMGet(ctx,
+		loginFailureKeyPrefix+scopeIP+ip, "count", "last")
+
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check login attempts: %w", err)
+	}
+
+	// PTTL is negative when the key doesn't exist
+	if ttl := lock.Val(); ttl > 0 {
+		return ttl, nil
+	}
+
+	now := time.Now()
+	accountWait := waitFromHash(accountFailures.Val(), limiter.accountPolicy, now)
+	ipWait := waitFromHash(ipFailures.Val(), limiter.ipPolicy, now)
+
+	return max(accountWait, ipWait), nil
+}
+
+func (limiter *RedisLoginLimiter) RecordFailure(ctx context.Context,
+	account, ip string) (bool, error) {
+	now := time.Now()
+
+	_, err := limiter.record(ctx, scopeIP+ip, limiter.ipPolicy, now)
+	if err != nil {
+		return false, err
+	}
+
+	count, err := limiter.record(ctx, scopeAccount+account,
+		limiter.accountPolicy, now)
+	if err != nil {
+		return false, err
+	}
+
+	if !limiter.accountPolicy.locks(count) {
+		return false, nil
+	}
+
+	// only the request which sets the lock reports it,
+	// so the user is notified once per lockout
+	locked, err := limiter.client.SetNX(ctx,
+		loginLockKeyPrefix+scopeAccount+account, now.UnixNano(),
+		limiter.accountPolicy.LockoutDuration).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to lock account: %w", err)
+	}
+
+	// failures start over once the lockout ends
+	err = limiter.client.Del(ctx,
+		loginFailureKeyPrefix+scopeAccount+account).Err()
+	if err != nil {
+		return false, fmt.Errorf("failed to clear login attempts: %w", err)
+	}
+
+	return locked, nil
+}
+
+func (limiter *RedisLoginLimiter) Reset(ctx context.Context,
+	account string) error {
+	err := limiter.client.Del(ctx,
+		loginFailureKeyPrefix+scopeAccount+account).Err()
+	if err != nil {
+		return fmt.Errorf("failed to clear login attempts: %w", err)
+	}
+
+	return nil
+}
+
+func (limiter *RedisLoginLimiter) Unlock(ctx context.Context,
+	account string) error {
+	err := limiter.client.Del(ctx,
+		loginFailureKeyPrefix+scopeAccount+account,
+		loginLockKeyPrefix+scopeAccount+account,
+	).Err()
+	if err != nil {
+		return fmt.Errorf("failed to unlock account: %w", err)
+	}
+
+	return nil
+}
+
+// counts a failure and returns the new count,
+// the counter expires a window after the last failure
+func (limiter *RedisLoginLimiter) record(ctx context.Context,
+	key string, policy Policy, now time.Time) (int64, error) {
+	key = loginFailureKeyPrefix + key
+
+	pipe := limiter.client.TxPipeline()
+	count := pipe.HIncrBy(ctx, key, "count", 1)
+	pipe.HSet(ctx, key, "last", now.UnixNano())
+	pipe.PExpire(ctx, key, policy.Window)
+
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record login failure: %w", err)
+	}
+
+	return count.Val(), nil
+}
+
+// values are the "count" and "last" fields of a failure hash
+func waitFromHash(values []interface{}, policy Policy,
+	now time.Time) time.Duration {
+	countValue, ok := values[0].(string)
+	if !ok {
+		return 0
+	}
+	lastValue, ok := values[1].(string)
+	if !ok {
+		return 0
+	}
+
+	count, err := strconv.ParseInt(countValue, 10, 64)
+	if err != nil {
+		return 0
+	}
+	last, err := strconv.ParseInt(lastValue, 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return policy.wait(count, time.Unix(0, last), now)
+}
@@ -0,0 +1,19 @@
+package limiter
+
+import (
+	"context"
+	"time"
+)
+
+// RateLimiter allows a number of requests per key and window
+type RateLimiter interface {
+	// counts a request, returns how long the caller has to wait
+	// before the next one is allowed, zero if this one is
+	Allow(ctx context.Context, key string) (time.Duration, error)
+}
+
+// Rate is Limit requests per Window
+type Rate struct {
+	Limit  int64
+	Window time.Duration
+}
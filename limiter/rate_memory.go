@@ -0,0 +1,59 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type window struct {
+	count int64
+	start time.Time
+}
+
+// MemoryRateLimiter counts requests in process memory,
+// for single-node deployments and tests
+type MemoryRateLimiter struct {
+	mu      sync.Mutex
+	rate    Rate
+	windows map[string]*window
+}
+
+func NewMemoryRateLimiter(rate Rate) RateLimiter {
+	return &MemoryRateLimiter{
+		rate:    rate,
+		windows: make(map[string]*window),
+	}
+}
+
+func (limiter *MemoryRateLimiter) Allow(ctx context.Context,
+	key string) (time.Duration, error) {
+	now := time.Now()
+
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	w, ok := limiter.windows[key]
+	if !ok || now.Sub(w.start) >= limiter.rate.Window {
+		// drop finished windows so the map doesn't grow forever
+		limiter.sweep(now)
+
+		w = &window{start: now}
+		limiter.windows[key] = w
+	}
+
+	w.count++
+	if w.count > limiter.rate.Limit {
+		return w.start.Add(limiter.rate.Window).Sub(now), nil
+	}
+
+	return 0, nil
+}
+
+func (limiter *MemoryRateLimiter) sweep(now time.Time) {
+	for key, w := range limiter.windows {
+		if now.Sub(w.start) >= limiter.rate.Window {
+			delete(limiter.windows, key)
+		}
+	}
+}
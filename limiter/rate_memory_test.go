@@ -0,0 +1,36 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryRateLimiter(t *testing.T) {
+	ctx := context.Background()
+	limiter := NewMemoryRateLimiter(Rate{Limit: 2, Window: 50 * time.Millisecond})
+
+	for range 2 {
+		wait, err := limiter.Allow(ctx, "key")
+		require.NoError(t, err)
+		require.Zero(t, wait)
+	}
+
+	wait, err := limiter.Allow(ctx, "key")
+	require.NoError(t, err)
+	require.Positive(t, wait)
+	require.LessOrEqual(t, wait, 50*time.Millisecond)
+
+	// keys are counted separately
+	wait, err = limiter.Allow(ctx, "other")
+	require.NoError(t, err)
+	require.Zero(t, wait)
+
+	time.Sleep(60 * time.Millisecond)
+
+	wait, err = limiter.Allow(ctx, "key")
+	require.NoError(t, err)
+	require.Zero(t, wait)
+}
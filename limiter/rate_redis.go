@@ -0,0 +1,47 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const rateKeyPrefix = "rate:"
+
+// RedisRateLimiter shares counts between all server instances
+type RedisRateLimiter struct {
+	client *redis.Client
+	rate   Rate
+}
+
+func NewRedisRateLimiter(client *redis.Client, rate Rate) RateLimiter {
+	return &RedisRateLimiter{
+		client: client,
+		rate:   rate,
+	}
+}
+
+func (limiter *RedisRateLimiter) Allow(ctx context.Context,
+	key string) (time.Duration, error) {
+	key = rateKeyPrefix + key
+
+	// the window starts with the first request, NX keeps later
+	// requests from pushing it back
+	pipe := limiter.client.TxPipeline()
+	count := pipe.Incr(ctx, key)
+	pipe.ExpireNX(ctx, key, limiter.rate.Window)
+	ttl := pipe.PTTL(ctx, key)
+
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count request: %w", err)
+	}
+
+	if count.Val() > limiter.rate.Limit {
+		return max(ttl.Val(), time.Millisecond), nil
+	}
+
+	return 0, nil
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/kratos069/message-app/api"
 	"github.com/kratos069/message-app/db/sqlc/db-gen"
 	"github.com/kratos069/message-app/mail"
+	"github.com/kratos069/message-app/storage"
 	"github.com/kratos069/message-app/util"
 	"github.com/kratos069/message-app/worker"
 	"github.com/rs/zerolog"
@@ -66,12 +67,31 @@ func main() {
 
 	// run Redis Task Processor
 	waitGroup, ctx := errgroup.WithContext(ctx)
-	go runTaskProcessor(ctx, waitGroup, config, redisOpts, store)
+	go runTaskProcessor(ctx, waitGroup, config, redisOpts, store, taskDistributor)
 
 	// Start main Gin server & debug server
 	ginServer := runGinServer(config, store, taskDistributor)
 	debugServer := runDebugServer(config)
 
+	// takes users offline once their heartbeats stop
+	waitGroup.Go(func() error {
+		ginServer.SweepPresence(ctx)
+		return nil
+	})
+
+	// rotated token keys are picked up without a restart
+	util.WatchConfig(func(newConfig util.Config, err error) {
+		if err == nil {
+			err = ginServer.ReloadTokenKeys(newConfig)
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("cannot reload token keys, keeping the old ones")
+			return
+		}
+		log.Info().Str("active_key_id", newConfig.TokenActiveKeyID).
+			Msg("Token keys reloaded")
+	})
+
 	// Wait for interrupt signal
 	<-ctx.Done()
 
@@ -160,9 +180,14 @@ func runDebugServer(config util.Config) *http.Server {
 }
 
 func runTaskProcessor(ctx context.Context, waitGroup *errgroup.Group,
-	config util.Config, redisOpt asynq.RedisClientOpt, store db.Store) {
+	config util.Config, redisOpt asynq.RedisClientOpt, store db.Store,
+	taskDistributor worker.TaskDistributor) {
 	mailer := mail.NewGmailSender(config.EmailSenderName, config.EmailSenderAddress, config.EmailSenderPassword)
-	taskProcessor := worker.NewRedisTaskProcessor(redisOpt, store, mailer)
+	webhooks := worker.NewWebhookSender(config.WebhookTimeout, config.WebhookAllowPrivate)
+	files := storage.NewLocalFileStore(config.FileStorageDir)
+	exports := worker.NewDataExporter(files, config.DataExportLinkTTL)
+	taskProcessor := worker.NewRedisTaskProcessor(redisOpt, store, mailer,
+		webhooks, exports, files, taskDistributor)
 
 	log.Info().Msg("start task processor")
 	err := taskProcessor.Start()
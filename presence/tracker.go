@@ -0,0 +1,29 @@
+package presence
+
+import (
+	"context"
+	"time"
+)
+
+// Tracker knows who is online. Users stay online for a TTL after
+// their last heartbeat.
+type Tracker interface {
+	// records a heartbeat, returns true if the user just came online
+	Heartbeat(ctx context.Context, userID int64) (bool, error)
+	// takes the user offline right away, returns false if they weren't online
+	Leave(ctx context.Context, userID int64) (bool, error)
+	// returns which of userIDs are online
+	Online(ctx context.Context, userIDs []int64) (map[int64]bool, error)
+	// counts the users online
+	Count(ctx context.Context) (int64, error)
+	// takes users whose TTL ran out offline, each one is returned
+	// by exactly one call even with several instances expiring
+	Expire(ctx context.Context) ([]Expiry, error)
+}
+
+// Expiry is a user who stopped sending heartbeats
+type Expiry struct {
+	UserID int64
+	// the last heartbeat
+	LastSeenAt time.Time
+}
@@ -0,0 +1,101 @@
+package presence
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryTracker keeps heartbeats in process memory,
+// for single-node deployments and tests
+type MemoryTracker struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	heartbeats map[int64]time.Time
+}
+
+func NewMemoryTracker(ttl time.Duration) Tracker {
+	return &MemoryTracker{
+		ttl:        ttl,
+		heartbeats: make(map[int64]time.Time),
+	}
+}
+
+func (tracker *MemoryTracker) Heartbeat(ctx context.Context,
+	userID int64) (bool, error) {
+	now := time.Now()
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	last, ok := tracker.heartbeats[userID]
+	tracker.heartbeats[userID] = now
+
+	return !ok || tracker.expired(last, now), nil
+}
+
+func (tracker *MemoryTracker) Leave(ctx context.Context,
+	userID int64) (bool, error) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	last, ok := tracker.heartbeats[userID]
+	delete(tracker.heartbeats, userID)
+
+	return ok && !tracker.expired(last, time.Now()), nil
+}
+
+func (tracker *MemoryTracker) Online(ctx context.Context,
+	userIDs []int64) (map[int64]bool, error) {
+	now := time.Now()
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	online := make(map[int64]bool, len(userIDs))
+	for _, userID := range userIDs {
+		last, ok := tracker.heartbeats[userID]
+		if ok && !tracker.expired(last, now) {
+			online[userID] = true
+		}
+	}
+
+	return online, nil
+}
+
+func (tracker *MemoryTracker) Count(ctx context.Context) (int64, error) {
+	now := time.Now()
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	var count int64
+	for _, last := range tracker.heartbeats {
+		if !tracker.expired(last, now) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (tracker *MemoryTracker) Expire(ctx context.Context) ([]Expiry, error) {
+	now := time.Now()
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	expiries := []Expiry{}
+	for userID, last := range tracker.heartbeats {
+		if tracker.expired(last, now) {
+			expiries = append(expiries, Expiry{UserID: userID, LastSeenAt: last})
+			delete(tracker.heartbeats, userID)
+		}
+	}
+
+	return expiries, nil
+}
+
+func (tracker *MemoryTracker) expired(last, now time.Time) bool {
+	return now.Sub(last) >= tracker.ttl
+}
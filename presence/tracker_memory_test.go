@@ -0,0 +1,71 @@
+package presence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryTracker(t *testing.T) {
+	ctx := context.Background()
+	tracker := NewMemoryTracker(50 * time.Millisecond)
+
+	came, err := tracker.Heartbeat(ctx, 1)
+	require.NoError(t, err)
+	require.True(t, came)
+
+	// later heartbeats only extend it
+	came, err = tracker.Heartbeat(ctx, 1)
+	require.NoError(t, err)
+	require.False(t, came)
+
+	_, err = tracker.Heartbeat(ctx, 2)
+	require.NoError(t, err)
+
+	online, err := tracker.Online(ctx, []int64{1, 2, 3})
+	require.NoError(t, err)
+	require.Equal(t, map[int64]bool{1: true, 2: true}, online)
+
+	count, err := tracker.Count(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+
+	left, err := tracker.Leave(ctx, 2)
+	require.NoError(t, err)
+	require.True(t, left)
+	left, err = tracker.Leave(ctx, 2)
+	require.NoError(t, err)
+	require.False(t, left)
+
+	// nothing to expire yet
+	expiries, err := tracker.Expire(ctx)
+	require.NoError(t, err)
+	require.Empty(t, expiries)
+
+	time.Sleep(60 * time.Millisecond)
+
+	online, err = tracker.Online(ctx, []int64{1})
+	require.NoError(t, err)
+	require.Empty(t, online)
+
+	count, err = tracker.Count(ctx)
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+	// each expiry is handed out once
+	expiries, err = tracker.Expire(ctx)
+	require.NoError(t, err)
+	require.Len(t, expiries, 1)
+	require.Equal(t, int64(1), expiries[0].UserID)
+	require.WithinDuration(t, time.Now(), expiries[0].LastSeenAt, time.Second)
+
+	expiries, err = tracker.Expire(ctx)
+	require.NoError(t, err)
+	require.Empty(t, expiries)
+
+	came, err = tracker.Heartbeat(ctx, 1)
+	require.NoError(t, err)
+	require.True(t, came)
+}
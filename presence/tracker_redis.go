@@ -0,0 +1,149 @@
+package presence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// members are user ids scored by their last heartbeat in milliseconds
+const onlineKey = "presence:online"
+
+// RedisTracker shares presence between all server instances
+type RedisTracker struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func NewRedisTracker(client *redis.Client, ttl time.Duration) Tracker {
+	return &RedisTracker{
+		client: client,
+		ttl:    ttl,
+	}
+}
+
+func (tracker *RedisTracker) Heartbeat(ctx context.Context,
+	userID int64) (bool, error) {
+	now := time.Now()
+	member := strconv.FormatInt(userID, 10)
+
+	// MULTI makes ZSCORE see the heartbeat before this one
+	pipe := tracker.client.TxPipeline()
+	last := pipe.ZScore(ctx, onlineKey, member)
+	pipe.ZAdd(ctx, onlineKey, redis.Z{Score: millis(now), Member: member})
+
+	_, err := pipe.Exec(ctx)
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return false, fmt.Errorf("failed to record heartbeat: %w", err)
+	}
+
+	return !tracker.online(last, now), nil
+}
+
+func (tracker *RedisTracker) Leave(ctx context.Context,
+	userID int64) (bool, error) {
+	now := time.Now()
+	member := strconv.FormatInt(userID, 10)
+
+	pipe := tracker.client.TxPipeline()
+	last := pipe.ZScore(ctx, onlineKey, member)
+	pipe.ZRem(ctx, onlineKey, member)
+
+	_, err := pipe.Exec(ctx)
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return false, fmt.Errorf("failed to leave: %w", err)
+	}
+
+	return tracker.online(last, now), nil
+}
+
+func (tracker *RedisTracker) Online(ctx context.Context,
+	userIDs []int64) (map[int64]bool, error) {
+	online := make(map[int64]bool, len(userIDs))
+	if len(userIDs) == 0 {
+		return online, nil
+	}
+
+	members := make([]string, 0, len(userIDs))
+	for _, userID := range userIDs {
+		members = append(members, strconv.FormatInt(userID, 10))
+	}
+
+	// missing members score 0
+	scores, err := tracker.client.ZMScore(ctx, onlineKey, members...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check presence: %w", err)
+	}
+
+	cutoff := tracker.cutoff(time.Now())
+	for i, score := range scores {
+		if score > cutoff {
+			online[userIDs[i]] = true
+		}
+	}
+
+	return online, nil
+}
+
+func (tracker *RedisTracker) Count(ctx context.Context) (int64, error) {
+	cutoff := tracker.cutoff(time.Now())
+
+	count, err := tracker.client.ZCount(ctx, onlineKey,
+		"("+formatScore(cutoff), "+inf").Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count presence: %w", err)
+	}
+
+	return count, nil
+}
+
+func (tracker *RedisTracker) Expire(ctx context.Context) ([]Expiry, error) {
+	cutoff := formatScore(tracker.cutoff(time.Now()))
+
+	// read and remove in one MULTI, so another instance can't get the same
+	pipe := tracker.client.TxPipeline()
+	expired := pipe.ZRangeByScoreWithScores(ctx, onlineKey,
+		&redis.ZRangeBy{Min: "-inf", Max: cutoff})
+	pipe.ZRemRangeByScore(ctx, onlineKey, "-inf", cutoff)
+
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire presence: %w", err)
+	}
+
+	expiries := make([]Expiry, 0, len(expired.Val()))
+	for _, z := range expired.Val() {
+		userID, err := strconv.ParseInt(z.Member.(string), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid presence entry: %w", err)
+		}
+		expiries = append(expiries, Expiry{
+			UserID:     userID,
+			LastSeenAt: time.UnixMilli(int64(z.Score)),
+		})
+	}
+
+	return expiries, nil
+}
+
+// heartbeats at or before the cutoff have expired
+func (tracker *RedisTracker) cutoff(now time.Time) float64 {
+	return millis(now.Add(-tracker.ttl))
+}
+
+func (tracker *RedisTracker) online(last *redis.FloatCmd, now time.Time) bool {
+	score, err := last.Result()
+	return err == nil && score > tracker.cutoff(now)
+}
+
+func millis(t time.Time) float64 {
+	return float64(t.UnixMilli())
+}
+
+func formatScore(score float64) string {
+	return strconv.FormatFloat(score, 'f', -1, 64)
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// directory used when none is configured
+const defaultLocalDir = "message-app-files"
+
+var errInvalidKey = errors.New("invalid file key")
+
+// LocalFileStore keeps files in a directory on local disk
+type LocalFileStore struct {
+	dir string
+}
+
+// NewLocalFileStore stores files under dir,
+// an empty dir uses a directory in the system temp directory
+func NewLocalFileStore(dir string) FileStore {
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), defaultLocalDir)
+	}
+
+	return &LocalFileStore{dir: dir}
+}
+
+func (store *LocalFileStore) Put(ctx context.Context, key string,
+	content io.Reader) error {
+	path, err := store.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o700)
+	if err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// written next to the target and renamed, so a reader
+	// never sees a half written file
+	file, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	_, err = io.Copy(file, content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	err = os.Rename(file.Name(), path)
+	if err != nil {
+		return fmt.Errorf("failed to move file: %w", err)
+	}
+
+	return nil
+}
+
+func (store *LocalFileStore) Open(ctx context.Context,
+	key string) (io.ReadCloser, error) {
+	path, err := store.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return file, nil
+}
+
+func (store *LocalFileStore) Delete(ctx context.Context, key string) error {
+	path, err := store.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// keys never point outside the directory
+func (store *LocalFileStore) path(key string) (string, error) {
+	path := filepath.FromSlash(key)
+	if !filepath.IsLocal(path) {
+		return "", errInvalidKey
+	}
+
+	return filepath.Join(store.dir, path), nil
+}
@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalFileStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewLocalFileStore(t.TempDir())
+
+	err := store.Put(ctx, "exports/1/archive.zip", strings.NewReader("first"))
+	require.NoError(t, err)
+
+	// replaces the file
+	err = store.Put(ctx, "exports/1/archive.zip", strings.NewReader("second"))
+	require.NoError(t, err)
+
+	file, err := store.Open(ctx, "exports/1/archive.zip")
+	require.NoError(t, err)
+	content, err := io.ReadAll(file)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+	require.Equal(t, "second", string(content))
+
+	require.NoError(t, store.Delete(ctx, "exports/1/archive.zip"))
+	_, err = store.Open(ctx, "exports/1/archive.zip")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	// deleting twice is fine
+	require.NoError(t, store.Delete(ctx, "exports/1/archive.zip"))
+}
+
+func TestLocalFileStoreInvalidKey(t *testing.T) {
+	ctx := context.Background()
+	store := NewLocalFileStore(t.TempDir())
+
+	for _, key := range []string{"", "../outside", "exports/../../outside", "/etc/passwd"} {
+		err := store.Put(ctx, key, strings.NewReader("nope"))
+		require.ErrorIs(t, err, errInvalidKey, key)
+
+		_, err = store.Open(ctx, key)
+		require.ErrorIs(t, err, errInvalidKey, key)
+	}
+}
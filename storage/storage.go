@@ -0,0 +1,22 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned when no file is stored under a key
+var ErrNotFound = errors.New("file not found")
+
+// FileStore keeps files the app generates, e.g. data export archives.
+// Keys are slash separated relative paths like "exports/1/2.zip".
+type FileStore interface {
+	// Put stores everything read from content under key,
+	// replacing the file that was there
+	Put(ctx context.Context, key string, content io.Reader) error
+	// Open returns the file stored under key, the caller closes it
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the file, a missing file is not an error
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,134 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+
+	"github.com/aead/chacha20poly1305"
+)
+
+// LegacyKeyID is the ID of the key used for tokens without a footer,
+// i.e. the ones issued before key IDs were introduced
+const LegacyKeyID = ""
+
+// Keyring holds the keys tokens are encrypted or signed with.
+// New tokens use the active key, the other keys only verify
+// tokens issued before a rotation until they are removed.
+// It can be updated at runtime, e.g. when the config changes.
+type Keyring[K any] struct {
+	mu       sync.RWMutex
+	activeID string
+	keys     map[string]K
+}
+
+func NewKeyring[K any](activeID string, keys map[string]K) (*Keyring[K], error) {
+	keyring := &Keyring[K]{}
+
+	err := keyring.Update(activeID, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return keyring, nil
+}
+
+// Update replaces every key at once,
+// keys left out are retired and their tokens stop verifying
+func (keyring *Keyring[K]) Update(activeID string, keys map[string]K) error {
+	if _, ok := keys[activeID]; !ok {
+		return fmt.Errorf("active key %q is not in the keyring", activeID)
+	}
+
+	keyring.mu.Lock()
+	defer keyring.mu.Unlock()
+
+	keyring.activeID = activeID
+	keyring.keys = maps.Clone(keys)
+
+	return nil
+}
+
+// Active returns the key new tokens are created with
+func (keyring *Keyring[K]) Active() (string, K) {
+	keyring.mu.RLock()
+	defer keyring.mu.RUnlock()
+
+	return keyring.activeID, keyring.keys[keyring.activeID]
+}
+
+// Key returns the key with the given ID, if it isn't retired
+func (keyring *Keyring[K]) Key(id string) (K, bool) {
+	keyring.mu.RLock()
+	defer keyring.mu.RUnlock()
+
+	key, ok := keyring.keys[id]
+	return key, ok
+}
+
+// All returns every key which is not retired, by ID
+func (keyring *Keyring[K]) All() map[string]K {
+	keyring.mu.RLock()
+	defer keyring.mu.RUnlock()
+
+	return maps.Clone(keyring.keys)
+}
+
+// ParseKeys reads keys in the "id1:key1,id2:key2" format of the config
+func ParseKeys(spec string) (map[string]string, error) {
+	keys := make(map[string]string)
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		id, key, ok := strings.Cut(entry, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("invalid key entry, expected id:key")
+		}
+		if _, exists := keys[id]; exists {
+			return nil, fmt.Errorf("duplicate key id %q", id)
+		}
+
+		keys[id] = key
+	}
+
+	return keys, nil
+}
+
+// SymmetricKeys checks the size of v2.local keys
+func SymmetricKeys(keys map[string]string) (map[string][]byte, error) {
+	parsed := make(map[string][]byte, len(keys))
+	for id, key := range keys {
+		if len(key) != chacha20poly1305.KeySize {
+			return nil, fmt.Errorf("invalid size of key %q: must be exactly %d characters",
+				id, chacha20poly1305.KeySize)
+		}
+		parsed[id] = []byte(key)
+	}
+
+	return parsed, nil
+}
+
+// Ed25519Keys decodes base64 encoded Ed25519 seeds into v2.public keys
+func Ed25519Keys(seeds map[string]string) (map[string]ed25519.PrivateKey, error) {
+	parsed := make(map[string]ed25519.PrivateKey, len(seeds))
+	for id, seed := range seeds {
+		raw, err := base64.StdEncoding.DecodeString(seed)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encoding of key %q: %w", id, err)
+		}
+		if len(raw) != ed25519.SeedSize {
+			return nil, fmt.Errorf("invalid size of key %q: must be a %d byte seed",
+				id, ed25519.SeedSize)
+		}
+		parsed[id] = ed25519.NewKeyFromSeed(raw)
+	}
+
+	return parsed, nil
+}
@@ -0,0 +1,144 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kratos069/message-app/util"
+	"github.com/o1egl/paseto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyringRotation(t *testing.T) {
+	oldKey := []byte(util.RandomString(32))
+	newKey := []byte(util.RandomString(32))
+
+	keyring, err := NewKeyring("k1", map[string][]byte{"k1": oldKey})
+	require.NoError(t, err)
+	maker := NewPasetoKeyringMaker(keyring)
+
+	oldToken, _, err := maker.CreateToken("user", 1, util.CustomerRole,
+		uuid.New(), time.Minute)
+	require.NoError(t, err)
+
+	// rotate, the old key still verifies
+	err = keyring.Update("k2", map[string][]byte{"k1": oldKey, "k2": newKey})
+	require.NoError(t, err)
+
+	newToken, _, err := maker.CreateToken("user", 1, util.CustomerRole,
+		uuid.New(), time.Minute)
+	require.NoError(t, err)
+
+	var footer tokenFooter
+	require.NoError(t, paseto.ParseFooter(newToken, &footer))
+	require.Equal(t, "k2", footer.KeyID)
+
+	_, err = maker.VerifyToken(oldToken)
+	require.NoError(t, err)
+	_, err = maker.VerifyToken(newToken)
+	require.NoError(t, err)
+
+	// retire the old key
+	err = keyring.Update("k2", map[string][]byte{"k2": newKey})
+	require.NoError(t, err)
+
+	_, err = maker.VerifyToken(oldToken)
+	require.ErrorIs(t, err, ErrInvalidToken)
+	_, err = maker.VerifyToken(newToken)
+	require.NoError(t, err)
+}
+
+func TestKeyringLegacyTokens(t *testing.T) {
+	legacyKey := util.RandomString(32)
+
+	legacyMaker, err := NewPasetoMaker(legacyKey)
+	require.NoError(t, err)
+
+	legacyToken, _, err := legacyMaker.CreateToken("user", 1,
+		util.CustomerRole, uuid.New(), time.Minute)
+	require.NoError(t, err)
+
+	keyring, err := NewKeyring("k1", map[string][]byte{
+		LegacyKeyID: []byte(legacyKey),
+		"k1":        []byte(util.RandomString(32)),
+	})
+	require.NoError(t, err)
+
+	_, err = NewPasetoKeyringMaker(keyring).VerifyToken(legacyToken)
+	require.NoError(t, err)
+}
+
+func TestKeyringInvalid(t *testing.T) {
+	_, err := NewKeyring("k2", map[string][]byte{"k1": []byte(util.RandomString(32))})
+	require.Error(t, err)
+
+	_, err = SymmetricKeys(map[string]string{"k1": "short"})
+	require.Error(t, err)
+	_, err = SymmetricKeys(map[string]string{"k1": util.RandomString(33)})
+	require.Error(t, err)
+
+	_, err = Ed25519Keys(map[string]string{"k1": "not base64"})
+	require.Error(t, err)
+
+	keys, err := ParseKeys("k1:a, k2:b")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"k1": "a", "k2": "b"}, keys)
+
+	_, err = ParseKeys("k1:a,k1:b")
+	require.Error(t, err)
+
+	_, err = ParseKeys("nokey")
+	require.Error(t, err)
+}
+
+func TestPasetoPublicMaker(t *testing.T) {
+	seed := make([]byte, ed25519.SeedSize)
+	_, err := rand.Read(seed)
+	require.NoError(t, err)
+
+	keys, err := Ed25519Keys(map[string]string{
+		"k1": base64.StdEncoding.EncodeToString(seed),
+	})
+	require.NoError(t, err)
+
+	keyring, err := NewKeyring("k1", keys)
+	require.NoError(t, err)
+	maker := NewPasetoPublicMaker(keyring)
+
+	sessionID := uuid.New()
+	token, payload, err := maker.CreateToken("user", 1, util.CustomerRole,
+		sessionID, time.Minute)
+	require.NoError(t, err)
+
+	verified, err := maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.Equal(t, payload.ID, verified.ID)
+	require.Equal(t, sessionID, verified.SessionID)
+
+	// anyone with the public key can verify offline
+	var offline Payload
+	err = paseto.NewV2().Verify(token, keys["k1"].Public(), &offline, nil)
+	require.NoError(t, err)
+	require.Equal(t, payload.ID, offline.ID)
+
+	// local tokens aren't accepted
+	localMaker, err := NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+	localToken, _, err := localMaker.CreateToken("user", 1, util.CustomerRole,
+		sessionID, time.Minute)
+	require.NoError(t, err)
+
+	_, err = maker.VerifyToken(localToken)
+	require.ErrorIs(t, err, ErrInvalidToken)
+
+	expired, _, err := maker.CreateToken("user", 1, util.CustomerRole,
+		sessionID, -time.Minute)
+	require.NoError(t, err)
+
+	_, err = maker.VerifyToken(expired)
+	require.ErrorIs(t, err, ErrExpiredToken)
+}
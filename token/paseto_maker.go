@@ -1,53 +1,86 @@
 package token
 
 import (
-	"fmt"
 	"time"
 
-	"github.com/aead/chacha20poly1305"
+	"github.com/google/uuid"
 	"github.com/o1egl/paseto"
 )
 
 // is a paseto token maker
 type PasetoMaker struct {
-	paseto       *paseto.V2
-	symmetricKey []byte
+	paseto  *paseto.V2
+	keyring *Keyring[[]byte]
 }
 
+// tells which key of the keyring encrypted the token
+type tokenFooter struct {
+	KeyID string `json:"kid"`
+}
+
+// creates a maker with a single key, tokens carry no key ID
 func NewPasetoMaker(symmetricKey string) (Maker, error) {
-	if len(symmetricKey) < chacha20poly1305.KeySize {
-		return nil,
-			fmt.Errorf("invalid key size: must be exactly %d characters",
-				chacha20poly1305.KeySize)
+	keys, err := SymmetricKeys(map[string]string{
+		LegacyKeyID: symmetricKey,
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	maker := &PasetoMaker{
-		paseto:       paseto.NewV2(),
-		symmetricKey: []byte(symmetricKey),
+	keyring, err := NewKeyring(LegacyKeyID, keys)
+	if err != nil {
+		return nil, err
 	}
 
-	return maker, nil
+	return NewPasetoKeyringMaker(keyring), nil
 }
 
-// creates a token for specific username and valid duration
+// creates a maker which supports key rotation
+func NewPasetoKeyringMaker(keyring *Keyring[[]byte]) Maker {
+	return &PasetoMaker{
+		paseto:  paseto.NewV2(),
+		keyring: keyring,
+	}
+}
+
+// creates a token for specific username, session and valid duration
 func (maker *PasetoMaker) CreateToken(username string,
-	userID int64, role string, duration time.Duration) (
-	string, *Payload, error) {
-	payload, err := NewPayload(username, userID, role, duration)
+	userID int64, role string, sessionID uuid.UUID,
+	duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, userID, role, sessionID, duration)
 	if err != nil {
 		return "", payload, err
 	}
 
-	token, err := maker.paseto.Encrypt(maker.symmetricKey, payload, nil)
+	keyID, key := maker.keyring.Active()
+
+	var footer interface{}
+	if keyID != LegacyKeyID {
+		footer = tokenFooter{KeyID: keyID}
+	}
+
+	token, err := maker.paseto.Encrypt(key, payload, footer)
 
 	return token, payload, err
 }
 
 // check if input token is valid or not
 func (maker *PasetoMaker) VerifyToken(token string) (*Payload, error) {
+	// footer is readable without the key, missing on legacy tokens
+	var footer tokenFooter
+	err := paseto.ParseFooter(token, &footer)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	key, ok := maker.keyring.Key(footer.KeyID)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
 	payload := &Payload{}
 
-	err := maker.paseto.Decrypt(token, maker.symmetricKey, payload, nil)
+	err = maker.paseto.Decrypt(token, key, payload, nil)
 	if err != nil {
 		return nil, ErrInvalidToken
 	}
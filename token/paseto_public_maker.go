@@ -0,0 +1,68 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/o1egl/paseto"
+)
+
+// is a paseto v2.public token maker, tokens are signed with Ed25519
+// so other services can verify them with the public keys alone
+type PasetoPublicMaker struct {
+	paseto  *paseto.V2
+	keyring *Keyring[ed25519.PrivateKey]
+}
+
+func NewPasetoPublicMaker(keyring *Keyring[ed25519.PrivateKey]) Maker {
+	return &PasetoPublicMaker{
+		paseto:  paseto.NewV2(),
+		keyring: keyring,
+	}
+}
+
+// creates a token for specific username, session and valid duration
+func (maker *PasetoPublicMaker) CreateToken(username string,
+	userID int64, role string, sessionID uuid.UUID,
+	duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, userID, role, sessionID, duration)
+	if err != nil {
+		return "", payload, err
+	}
+
+	keyID, privateKey := maker.keyring.Active()
+
+	token, err := maker.paseto.Sign(privateKey, payload,
+		tokenFooter{KeyID: keyID})
+
+	return token, payload, err
+}
+
+// check if input token is valid or not
+func (maker *PasetoPublicMaker) VerifyToken(token string) (*Payload, error) {
+	var footer tokenFooter
+	err := paseto.ParseFooter(token, &footer)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	privateKey, ok := maker.keyring.Key(footer.KeyID)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	payload := &Payload{}
+
+	err = maker.paseto.Verify(token, privateKey.Public(), payload, nil)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	err = payload.Valid()
+	if err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
@@ -15,6 +15,7 @@ var (
 // contains payload data of token
 type Payload struct {
 	ID        uuid.UUID `json:"id"`
+	SessionID uuid.UUID `json:"session_id"`
 	UserID    int64     `json:"user_id"`
 	Username  string    `json:"username"`
 	Role      string    `json:"role"`
@@ -22,9 +23,9 @@ type Payload struct {
 	ExpiredAt time.Time `json:"expired_at"`
 }
 
-// creates new payload with specific username and duration
+// creates new payload with specific username, session and duration
 func NewPayload(username string, userID int64, role string,
-	duration time.Duration) (*Payload, error) {
+	sessionID uuid.UUID, duration time.Duration) (*Payload, error) {
 	tokenID, err := uuid.NewRandom()
 	if err != nil {
 		return nil, err
@@ -32,6 +33,7 @@ func NewPayload(username string, userID int64, role string,
 
 	payload := &Payload{
 		ID:        tokenID,
+		SessionID: sessionID,
 		UserID:    userID,
 		Username:  username,
 		Role:      role,
@@ -0,0 +1,112 @@
+package token
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RevocationList records sessions and users whose tokens
+// must stop working before they expire
+type RevocationList interface {
+	// revokes every token issued for the session
+	RevokeSession(ctx context.Context, sessionID uuid.UUID,
+		ttl time.Duration) error
+
+	// revokes every token issued to the user up to now
+	RevokeUser(ctx context.Context, userID int64, ttl time.Duration) error
+
+	// check if payload was revoked by session or by user
+	IsRevoked(ctx context.Context, payload *Payload) (bool, error)
+}
+
+type revokedEntry struct {
+	revokedAt time.Time
+	expiresAt time.Time
+}
+
+// MemoryRevocationList keeps revocations in process memory,
+// for single-node deployments and tests
+type MemoryRevocationList struct {
+	mu       sync.RWMutex
+	sessions map[uuid.UUID]revokedEntry
+	users    map[int64]revokedEntry
+}
+
+func NewMemoryRevocationList() RevocationList {
+	return &MemoryRevocationList{
+		sessions: make(map[uuid.UUID]revokedEntry),
+		users:    make(map[int64]revokedEntry),
+	}
+}
+
+func (list *MemoryRevocationList) RevokeSession(ctx context.Context,
+	sessionID uuid.UUID, ttl time.Duration) error {
+	now := time.Now()
+
+	list.mu.Lock()
+	defer list.mu.Unlock()
+
+	list.sessions[sessionID] = revokedEntry{
+		revokedAt: now,
+		expiresAt: now.Add(ttl),
+	}
+	list.purgeExpired(now)
+
+	return nil
+}
+
+func (list *MemoryRevocationList) RevokeUser(ctx context.Context,
+	userID int64, ttl time.Duration) error {
+	now := time.Now()
+
+	list.mu.Lock()
+	defer list.mu.Unlock()
+
+	list.users[userID] = revokedEntry{
+		revokedAt: now,
+		expiresAt: now.Add(ttl),
+	}
+	list.purgeExpired(now)
+
+	return nil
+}
+
+func (list *MemoryRevocationList) IsRevoked(ctx context.Context,
+	payload *Payload) (bool, error) {
+	now := time.Now()
+
+	list.mu.RLock()
+	defer list.mu.RUnlock()
+
+	if entry, ok := list.sessions[payload.SessionID]; ok &&
+		now.Before(entry.expiresAt) {
+		return true, nil
+	}
+
+	// only tokens issued before the user was revoked are affected
+	if entry, ok := list.users[payload.UserID]; ok &&
+		now.Before(entry.expiresAt) &&
+		!payload.IssuedAt.After(entry.revokedAt) {
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// must be called with the write lock held
+func (list *MemoryRevocationList) purgeExpired(now time.Time) {
+	for id, entry := range list.sessions {
+		if now.After(entry.expiresAt) {
+			delete(list.sessions, id)
+		}
+	}
+
+	for id, entry := range list.users {
+		if now.After(entry.expiresAt) {
+			delete(list.users, id)
+		}
+	}
+}
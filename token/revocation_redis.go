@@ -0,0 +1,81 @@
+package token
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	revokedSessionKeyPrefix = "revoked:session:"
+	revokedUserKeyPrefix    = "revoked:user:"
+)
+
+// RedisRevocationList shares revocations between all server instances
+type RedisRevocationList struct {
+	client *redis.Client
+}
+
+func NewRedisRevocationList(client *redis.Client) RevocationList {
+	return &RedisRevocationList{
+		client: client,
+	}
+}
+
+func (list *RedisRevocationList) RevokeSession(ctx context.Context,
+	sessionID uuid.UUID, ttl time.Duration) error {
+	key := revokedSessionKeyPrefix + sessionID.String()
+
+	err := list.client.Set(ctx, key, time.Now().UnixNano(), ttl).Err()
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	return nil
+}
+
+func (list *RedisRevocationList) RevokeUser(ctx context.Context,
+	userID int64, ttl time.Duration) error {
+	key := revokedUserKeyPrefix + strconv.FormatInt(userID, 10)
+
+	err := list.client.Set(ctx, key, time.Now().UnixNano(), ttl).Err()
+	if err != nil {
+		return fmt.Errorf("failed to revoke user: %w", err)
+	}
+
+	return nil
+}
+
+func (list *RedisRevocationList) IsRevoked(ctx context.Context,
+	payload *Payload) (bool, error) {
+	values, err := list.client.MGet(ctx,
+		revokedSessionKeyPrefix+payload.SessionID.String(),
+		revokedUserKeyPrefix+strconv.FormatInt(payload.UserID, 10),
+	).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check revocation: %w", err)
+	}
+
+	// session key exists
+	if values[0] != nil {
+		return true, nil
+	}
+
+	// only tokens issued before the user was revoked are affected
+	if value, ok := values[1].(string); ok {
+		revokedAt, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return false, fmt.Errorf("invalid revocation entry: %w", err)
+		}
+
+		if payload.IssuedAt.UnixNano() <= revokedAt {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -1,12 +1,16 @@
 package token
 
-import "time"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 // Maker is an interface for managing tokens
 type Maker interface {
-	// creates a token for specific username and valid duration
+	// creates a token for specific username, session and valid duration
 	CreateToken(username string, userID int64, role string,
-		duration time.Duration) (string, *Payload, error)
+		sessionID uuid.UUID, duration time.Duration) (string, *Payload, error)
 
 	// check if input token is valid or not
 	VerifyToken(token string) (*Payload, error)
@@ -0,0 +1,24 @@
+package typing
+
+import (
+	"context"
+	"time"
+)
+
+// Store keeps who is typing in which conversation. A user stops
+// typing when told to or once the TTL passes without an update.
+type Store interface {
+	// marks the user as typing, or keeps them typing for another TTL
+	Start(ctx context.Context, conversationID, userID int64) error
+	// the user stopped typing, e.g. because they sent the message
+	Stop(ctx context.Context, conversationID, userID int64) error
+	// returns who is typing in the conversation, longest idle first
+	Typing(ctx context.Context, conversationID int64) ([]Typist, error)
+}
+
+// Typist is a user typing in a conversation
+type Typist struct {
+	UserID int64
+	// the last update, typing ends a TTL after it
+	StartedAt time.Time
+}
@@ -0,0 +1,92 @@
+package typing
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps typing state in process memory,
+// for single-node deployments and tests
+type MemoryStore struct {
+	mu  sync.Mutex
+	ttl time.Duration
+	// conversation id to user id to the last update
+	conversations map[int64]map[int64]time.Time
+}
+
+func NewMemoryStore(ttl time.Duration) Store {
+	return &MemoryStore{
+		ttl:           ttl,
+		conversations: make(map[int64]map[int64]time.Time),
+	}
+}
+
+func (store *MemoryStore) Start(ctx context.Context,
+	conversationID, userID int64) error {
+	now := time.Now()
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	typists, ok := store.conversations[conversationID]
+	if !ok {
+		// drop finished conversations so the map doesn't grow forever
+		store.sweep(now)
+
+		typists = make(map[int64]time.Time)
+		store.conversations[conversationID] = typists
+	}
+	typists[userID] = now
+
+	return nil
+}
+
+func (store *MemoryStore) Stop(ctx context.Context,
+	conversationID, userID int64) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	typists := store.conversations[conversationID]
+	delete(typists, userID)
+	if len(typists) == 0 {
+		delete(store.conversations, conversationID)
+	}
+
+	return nil
+}
+
+func (store *MemoryStore) Typing(ctx context.Context,
+	conversationID int64) ([]Typist, error) {
+	now := time.Now()
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	typing := []Typist{}
+	for userID, startedAt := range store.conversations[conversationID] {
+		if now.Sub(startedAt) < store.ttl {
+			typing = append(typing, Typist{UserID: userID, StartedAt: startedAt})
+		}
+	}
+
+	slices.SortFunc(typing, func(a, b Typist) int {
+		return a.StartedAt.Compare(b.StartedAt)
+	})
+
+	return typing, nil
+}
+
+func (store *MemoryStore) sweep(now time.Time) {
+	for conversationID, typists := range store.conversations {
+		for userID, startedAt := range typists {
+			if now.Sub(startedAt) >= store.ttl {
+				delete(typists, userID)
+			}
+		}
+		if len(typists) == 0 {
+			delete(store.conversations, conversationID)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package typing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(50 * time.Millisecond)
+
+	require.NoError(t, store.Start(ctx, 1, 10))
+	time.Sleep(time.Millisecond)
+	require.NoError(t, store.Start(ctx, 1, 20))
+	require.NoError(t, store.Start(ctx, 2, 30))
+
+	// longest idle first, conversations are separate
+	typing, err := store.Typing(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, typing, 2)
+	require.Equal(t, int64(10), typing[0].UserID)
+	require.Equal(t, int64(20), typing[1].UserID)
+
+	require.NoError(t, store.Stop(ctx, 1, 20))
+	typing, err = store.Typing(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, typing, 1)
+
+	// updates keep users typing
+	time.Sleep(30 * time.Millisecond)
+	require.NoError(t, store.Start(ctx, 1, 10))
+	time.Sleep(30 * time.Millisecond)
+
+	typing, err = store.Typing(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, typing, 1)
+
+	typing, err = store.Typing(ctx, 2)
+	require.NoError(t, err)
+	require.Empty(t, typing)
+}
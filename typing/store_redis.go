@@ -0,0 +1,93 @@
+package typing
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// one sorted set per conversation, user ids scored by their
+// last update in milliseconds
+const typingKeyPrefix = "typing:"
+
+// RedisStore shares typing state between all server instances
+type RedisStore struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func NewRedisStore(client *redis.Client, ttl time.Duration) Store {
+	return &RedisStore{
+		client: client,
+		ttl:    ttl,
+	}
+}
+
+func (store *RedisStore) Start(ctx context.Context,
+	conversationID, userID int64) error {
+	key := typingKey(conversationID)
+
+	// the set goes away a TTL after the last update in it
+	pipe := store.client.TxPipeline()
+	pipe.ZAdd(ctx, key, redis.Z{
+		Score:  float64(time.Now().UnixMilli()),
+		Member: strconv.FormatInt(userID, 10),
+	})
+	pipe.PExpire(ctx, key, store.ttl)
+
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start typing: %w", err)
+	}
+
+	return nil
+}
+
+func (store *RedisStore) Stop(ctx context.Context,
+	conversationID, userID int64) error {
+	err := store.client.ZRem(ctx, typingKey(conversationID),
+		strconv.FormatInt(userID, 10)).Err()
+	if err != nil {
+		return fmt.Errorf("failed to stop typing: %w", err)
+	}
+
+	return nil
+}
+
+func (store *RedisStore) Typing(ctx context.Context,
+	conversationID int64) ([]Typist, error) {
+	key := typingKey(conversationID)
+	cutoff := strconv.FormatInt(time.Now().Add(-store.ttl).UnixMilli(), 10)
+
+	// drop the ones that timed out while others kept typing
+	pipe := store.client.TxPipeline()
+	pipe.ZRemRangeByScore(ctx, key, "-inf", cutoff)
+	typists := pipe.ZRangeByScoreWithScores(ctx, key,
+		&redis.ZRangeBy{Min: "(" + cutoff, Max: "+inf"})
+
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get typing users: %w", err)
+	}
+
+	typing := make([]Typist, 0, len(typists.Val()))
+	for _, z := range typists.Val() {
+		userID, err := strconv.ParseInt(z.Member.(string), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid typing entry: %w", err)
+		}
+		typing = append(typing, Typist{
+			UserID:    userID,
+			StartedAt: time.UnixMilli(int64(z.Score)),
+		})
+	}
+
+	return typing, nil
+}
+
+func typingKey(conversationID int64) string {
+	return typingKeyPrefix + strconv.FormatInt(conversationID, 10)
+}
@@ -0,0 +1,90 @@
+package util
+
+import (
+	"strings"
+)
+
+// scopes an API key can be given
+const (
+	ScopeMessagesRead = "messages:read"
+	ScopeMessagesSend = "messages:send"
+)
+
+// APIKeyPrefix tells API keys apart from PASETO tokens
+const APIKeyPrefix = "mak_"
+
+// IncomingWebhookTokenPrefix starts the secret part of incoming webhook URLs
+const IncomingWebhookTokenPrefix = "miw_"
+
+// DataExportTokenPrefix starts the secret part of data export download links
+const DataExportTokenPrefix = "mde_"
+
+const (
+	apiKeyIDLength     = 12
+	apiKeySecretLength = 40
+)
+
+// IsValidScope reports whether scope is one API keys can have
+func IsValidScope(scope string) bool {
+	switch scope {
+	case ScopeMessagesRead, ScopeMessagesSend:
+		return true
+	}
+
+	return false
+}
+
+// GenerateAPIKey returns a new key like "mak_<id>_<secret>",
+// the id is stored in plain text to look the key up
+func GenerateAPIKey() (key string, id string) {
+	return generatePrefixedSecret(APIKeyPrefix)
+}
+
+// ParseAPIKey returns the id of a key, ok is false
+// if key doesn't have the format of an API key
+func ParseAPIKey(key string) (id string, ok bool) {
+	return parsePrefixedSecret(APIKeyPrefix, key)
+}
+
+// GenerateIncomingWebhookToken returns a new token like "miw_<id>_<secret>"
+func GenerateIncomingWebhookToken() (token string, id string) {
+	return generatePrefixedSecret(IncomingWebhookTokenPrefix)
+}
+
+// ParseIncomingWebhookToken returns the id of a token, ok is false
+// if token doesn't have the format of an incoming webhook token
+func ParseIncomingWebhookToken(token string) (id string, ok bool) {
+	return parsePrefixedSecret(IncomingWebhookTokenPrefix, token)
+}
+
+// GenerateDataExportToken returns a new token like "mde_<id>_<secret>"
+func GenerateDataExportToken() (token string, id string) {
+	return generatePrefixedSecret(DataExportTokenPrefix)
+}
+
+// ParseDataExportToken returns the id of a token, ok is false
+// if token doesn't have the format of a data export token
+func ParseDataExportToken(token string) (id string, ok bool) {
+	return parsePrefixedSecret(DataExportTokenPrefix, token)
+}
+
+func generatePrefixedSecret(prefix string) (secret string, id string) {
+	id = RandomString(apiKeyIDLength)
+	secret = prefix + id + "_" + RandomString(apiKeySecretLength)
+
+	return secret, id
+}
+
+func parsePrefixedSecret(prefix, secret string) (id string, ok bool) {
+	rest, found := strings.CutPrefix(secret, prefix)
+	if !found {
+		return "", false
+	}
+
+	id, random, found := strings.Cut(rest, "_")
+	if !found || len(id) != apiKeyIDLength || len(random) != apiKeySecretLength {
+		return "", false
+	}
+
+	return id, true
+}
@@ -0,0 +1,90 @@
+package util
+
+import (
+	"sync"
+	"time"
+)
+
+// expired entries are swept once the cache grows past this size
+const cacheSweepThreshold = 10000
+
+type cacheEntry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// TTLCache is a concurrency-safe in-memory cache
+// whose entries expire after a fixed duration
+type TTLCache[K comparable, V any] struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	entries map[K]cacheEntry[V]
+}
+
+// NewTTLCache creates a cache keeping every entry for ttl
+func NewTTLCache[K comparable, V any](ttl time.Duration) *TTLCache[K, V] {
+	return &TTLCache[K, V]{
+		ttl:     ttl,
+		entries: make(map[K]cacheEntry[V]),
+	}
+}
+
+// Get returns the cached value if present and not expired
+func (cache *TTLCache[K, V]) Get(key K) (V, bool) {
+	cache.mu.RLock()
+	entry, ok := cache.entries[key]
+	cache.mu.RUnlock()
+
+	if !ok || time.Now().After(entry.expiresAt) {
+		var zero V
+		return zero, false
+	}
+
+	return entry.value, true
+}
+
+// Set stores value under key for the cache TTL
+func (cache *TTLCache[K, V]) Set(key K, value V) {
+	now := time.Now()
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if len(cache.entries) >= cacheSweepThreshold {
+		for k, entry := range cache.entries {
+			if now.After(entry.expiresAt) {
+				delete(cache.entries, k)
+			}
+		}
+	}
+
+	cache.entries[key] = cacheEntry[V]{
+		value:     value,
+		expiresAt: now.Add(cache.ttl),
+	}
+}
+
+// Delete removes key from the cache
+func (cache *TTLCache[K, V]) Delete(key K) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	delete(cache.entries, key)
+}
+
+// Take removes key from the cache and returns its value,
+// so only one caller can ever get it
+func (cache *TTLCache[K, V]) Take(key K) (V, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	entry, ok := cache.entries[key]
+	delete(cache.entries, key)
+
+	if !ok || time.Now().After(entry.expiresAt) {
+		var zero V
+		return zero, false
+	}
+
+	return entry.value, true
+}
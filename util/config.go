@@ -3,6 +3,7 @@ package util
 import (
 	"time"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/viper"
 )
 
@@ -13,14 +14,36 @@ type Config struct {
 	DBSource             string        `mapstructure:"DB_SOURCE"`
 	MigrationURL         string        `mapstructure:"MIGRATION_URL"`
 	HTTPServerAddress    string        `mapstructure:"HTTP_SERVER_ADDRESS"`
+	TrustedProxies       string        `mapstructure:"TRUSTED_PROXIES"`
 	GRPCServerAddress    string        `mapstructure:"GRPC_SERVER_ADDRESS"`
 	RedisAddress         string        `mapstructure:"REDIS_ADDRESS"`
+	TokenType            string        `mapstructure:"TOKEN_TYPE"`
 	TokenSymmetricKey    string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
+	TokenKeys            string        `mapstructure:"TOKEN_KEYS"`
+	TokenSigningKeys     string        `mapstructure:"TOKEN_SIGNING_KEYS"`
+	TokenActiveKeyID     string        `mapstructure:"TOKEN_ACTIVE_KEY_ID"`
 	CloudName            string        `mapstructure:"CLOUD_NAME"`
 	CloudApiKey          string        `mapstructure:"CLOUD_API_KEY"`
 	CloudApiSecret       string        `mapstructure:"CLOUD_API_SECRET"`
+	FileStorageDir       string        `mapstructure:"FILE_STORAGE_DIR"`
 	AccessTokenDuration  time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
 	RefreshTokenDuration time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	AuthCacheTTL         time.Duration `mapstructure:"AUTH_CACHE_TTL"`
+	UnverifiedAccess     string        `mapstructure:"UNVERIFIED_ACCESS"`
+	MFAChallengeDuration time.Duration `mapstructure:"MFA_CHALLENGE_DURATION"`
+	LoginLockoutAfter    int64         `mapstructure:"LOGIN_LOCKOUT_AFTER"`
+	LoginLockoutDuration time.Duration `mapstructure:"LOGIN_LOCKOUT_DURATION"`
+	DeletionGracePeriod  time.Duration `mapstructure:"ACCOUNT_DELETION_GRACE_PERIOD"`
+	UsernameCooldown     time.Duration `mapstructure:"USERNAME_CHANGE_COOLDOWN"`
+	DataExportLinkTTL    time.Duration `mapstructure:"DATA_EXPORT_LINK_DURATION"`
+	PresenceTTL          time.Duration `mapstructure:"PRESENCE_TTL"`
+	OIDCIssuerURL        string        `mapstructure:"OIDC_ISSUER_URL"`
+	OIDCClientID         string        `mapstructure:"OIDC_CLIENT_ID"`
+	OIDCClientSecret     string        `mapstructure:"OIDC_CLIENT_SECRET"`
+	OIDCRedirectURL      string        `mapstructure:"OIDC_REDIRECT_URL"`
+	WebhookTimeout       time.Duration `mapstructure:"WEBHOOK_TIMEOUT"`
+	WebhookAllowPrivate  bool          `mapstructure:"WEBHOOK_ALLOW_PRIVATE_NETWORKS"`
+	IncomingHookLimit    int64         `mapstructure:"INCOMING_WEBHOOK_RATE_LIMIT"`
 	EmailSenderName      string        `mapstructure:"EMAIL_SENDER_NAME"`
 	EmailSenderAddress   string        `mapstructure:"EMAIL_SENDER_ADDRESS"`
 	EmailSenderPassword  string        `mapstructure:"EMAIL_SENDER_PASSWORD"`
@@ -44,3 +67,14 @@ func LoadConfig(path string) (config Config, err error) {
 	err = viper.Unmarshal(&config)
 	return
 }
+
+// calls onChange with the new configuration whenever the
+// config file changes (ENV variables still take precedence)
+func WatchConfig(onChange func(config Config, err error)) {
+	viper.OnConfigChange(func(event fsnotify.Event) {
+		var config Config
+		err := viper.Unmarshal(&config)
+		onChange(config, err)
+	})
+	viper.WatchConfig()
+}
@@ -1,6 +1,8 @@
 package util
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 
 	"golang.org/x/crypto/bcrypt"
@@ -22,3 +24,10 @@ func CheckPassword(password, hashPassword string) error {
 	return bcrypt.CompareHashAndPassword(
 		[]byte(hashPassword), []byte(password))
 }
+
+// returns sha256 hash of a high-entropy secret (recovery codes, api keys),
+// unlike passwords these don't need a slow hash and can be looked up directly
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,8 @@
+package util
+
+// what accounts with an unverified email address may do
+const (
+	UnverifiedAccessFull     = "full"
+	UnverifiedAccessReadOnly = "read_only"
+	UnverifiedAccessNone     = "none"
+)
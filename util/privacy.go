@@ -0,0 +1,16 @@
+package util
+
+// who a privacy setting lets see something or get in touch
+const (
+	AudienceEveryone = "everyone"
+	AudienceContacts = "contacts"
+	AudienceNobody   = "nobody"
+)
+
+// where a conversation shows up for a participant
+const (
+	InboxPrimary  = "primary"
+	InboxRequests = "requests"
+	// hidden from the recipient, the sender isn't told
+	InboxDeclined = "declined"
+)
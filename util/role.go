@@ -3,4 +3,7 @@ package util
 const (
 	CustomerRole = "customer"
 	AdminRole    = "admin"
+	// carried by the short-lived token between password and 2FA code,
+	// never accepted by the auth middleware
+	MFAChallengeRole = "mfa_challenge"
 )
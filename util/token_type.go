@@ -0,0 +1,9 @@
+package util
+
+const (
+	// v2.local, tokens are encrypted with a shared secret
+	TokenTypeLocal = "local"
+	// v2.public, tokens are signed with Ed25519 and can be
+	// verified by other services with the published public keys
+	TokenTypePublic = "public"
+)
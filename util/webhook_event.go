@@ -0,0 +1,22 @@
+package util
+
+// events webhooks can subscribe to
+const (
+	WebhookEventMessageSent  = "message.sent"
+	WebhookEventMemberJoined = "member.joined"
+	// not tied to a conversation, only webhooks
+	// of every conversation receive it
+	WebhookEventUserBanned = "user.banned"
+	// sent by the test endpoint, can't be subscribed to
+	WebhookEventPing = "ping"
+)
+
+// IsValidWebhookEvent reports whether webhooks can subscribe to event
+func IsValidWebhookEvent(event string) bool {
+	switch event {
+	case WebhookEventMessageSent, WebhookEventMemberJoined, WebhookEventUserBanned:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,143 @@
+package worker
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MaxAvatarSize is the largest upload accepted, in bytes
+	MaxAvatarSize = 5 << 20
+	// decoding a huge image takes a lot of memory,
+	// whatever its size on disk
+	maxAvatarDimension = 4096
+	// AvatarURLPrefix starts the URL avatars are served from
+	AvatarURLPrefix = "/avatars/"
+)
+
+// AvatarSizes are the widths of the square thumbnails, in pixels,
+// the largest one becomes the profile picture
+var AvatarSizes = []int{64, 256}
+
+var (
+	ErrAvatarFormat = errors.New("avatar must be a JPEG, PNG or GIF image")
+	ErrAvatarTooBig = errors.New("avatar is too large")
+)
+
+// ValidateAvatar checks the upload is an image in a supported format
+// and small enough to decode, only the header is read
+func ValidateAvatar(r io.Reader) error {
+	config, format, err := image.DecodeConfig(r)
+	if err != nil {
+		return ErrAvatarFormat
+	}
+
+	switch format {
+	case "jpeg", "png", "gif":
+	default:
+		return ErrAvatarFormat
+	}
+
+	if config.Width <= 0 || config.Height <= 0 {
+		return ErrAvatarFormat
+	}
+	if config.Width > maxAvatarDimension || config.Height > maxAvatarDimension {
+		return ErrAvatarTooBig
+	}
+
+	return nil
+}
+
+// AvatarOriginalKey is where an upload waits for its thumbnails
+func AvatarOriginalKey(userID int64, uploadID string) string {
+	return fmt.Sprintf("avatars/%d/%s/original", userID, uploadID)
+}
+
+// AvatarThumbnailKey is where a thumbnail of an upload is stored,
+// it is served at "/" + key
+func AvatarThumbnailKey(userID int64, uploadID string, size int) string {
+	return fmt.Sprintf("avatars/%d/%s/%d.png", userID, uploadID, size)
+}
+
+// returns the user and upload an avatar URL points to,
+// ok is false for pictures from elsewhere, e.g. an SSO provider
+func parseAvatarURL(url string) (userID int64, uploadID string, ok bool) {
+	rest, found := strings.CutPrefix(url, AvatarURLPrefix)
+	if !found {
+		return 0, "", false
+	}
+
+	parts := strings.Split(rest, "/")
+	if len(parts) != 3 || parts[1] == "" || !strings.HasSuffix(parts[2], ".png") {
+		return 0, "", false
+	}
+
+	userID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, "", false
+	}
+
+	return userID, parts[1], true
+}
+
+// encodes a size x size PNG of the center of src
+func avatarThumbnail(src image.Image, size int) ([]byte, error) {
+	var buf bytes.Buffer
+	err := png.Encode(&buf, resizeSquare(src, size))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// crops the largest centered square out of src and scales it to
+// size x size, every destination pixel is the average of the source
+// pixels it covers
+func resizeSquare(src image.Image, size int) *image.RGBA {
+	bounds := src.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	left := bounds.Min.X + (bounds.Dx()-side)/2
+	top := bounds.Min.Y + (bounds.Dy()-side)/2
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := range size {
+		y0 := top + y*side/size
+		y1 := max(top+(y+1)*side/size, y0+1)
+
+		for x := range size {
+			x0 := left + x*side/size
+			x1 := max(left+(x+1)*side/size, x0+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r += uint64(pr)
+					g += uint64(pg)
+					b += uint64(pb)
+					a += uint64(pa)
+					n++
+				}
+			}
+
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(b / n >> 8),
+				A: uint8(a / n >> 8),
+			})
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,137 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResizeSquare(t *testing.T) {
+	// left half red, right half blue, 40x20
+	src := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for y := range 20 {
+		for x := range 40 {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= 20 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			src.SetRGBA(x, y, c)
+		}
+	}
+
+	// the centered 20x20 square is half red, half blue
+	dst := resizeSquare(src, 4)
+	require.Equal(t, image.Rect(0, 0, 4, 4), dst.Bounds())
+	require.Equal(t, color.RGBA{R: 255, A: 255}, dst.RGBAAt(0, 0))
+	require.Equal(t, color.RGBA{B: 255, A: 255}, dst.RGBAAt(3, 3))
+
+	// smaller sources are scaled up
+	dst = resizeSquare(src, 64)
+	require.Equal(t, image.Rect(0, 0, 64, 64), dst.Bounds())
+}
+
+func TestValidateAvatar(t *testing.T) {
+	var small bytes.Buffer
+	require.NoError(t, png.Encode(&small, image.NewRGBA(image.Rect(0, 0, 8, 8))))
+	require.NoError(t, ValidateAvatar(bytes.NewReader(small.Bytes())))
+
+	var huge bytes.Buffer
+	require.NoError(t, png.Encode(&huge,
+		image.NewGray(image.Rect(0, 0, maxAvatarDimension+1, 1))))
+	require.ErrorIs(t, ValidateAvatar(bytes.NewReader(huge.Bytes())), ErrAvatarTooBig)
+
+	require.ErrorIs(t, ValidateAvatar(strings.NewReader("not an image")), ErrAvatarFormat)
+}
+
+func TestParseAvatarURL(t *testing.T) {
+	userID, uploadID, ok := parseAvatarURL("/" + AvatarThumbnailKey(7, "abc", 256))
+	require.True(t, ok)
+	require.Equal(t, int64(7), userID)
+	require.Equal(t, "abc", uploadID)
+
+	for _, url := range []string{
+		"", "https://example.com/avatar.png", "/avatars/7/abc", "/avatars/x/abc/256.png",
+	} {
+		_, _, ok := parseAvatarURL(url)
+		require.False(t, ok, url)
+	}
+}
+
+// fakeAvatarStore applies an avatar only while its upload is the latest
+type fakeAvatarStore struct {
+	db.Store
+	user db.User
+}
+
+func (store *fakeAvatarStore) ReplaceAvatar(ctx context.Context,
+	arg db.ReplaceAvatarParams) (pgtype.Text, error) {
+	if store.user.ID != arg.ID || store.user.AvatarUploadID != arg.UploadID {
+		return pgtype.Text{}, pgx.ErrNoRows
+	}
+	replaced := store.user.ProfilePictureUrl
+	store.user.ProfilePictureUrl = arg.ProfilePictureUrl
+	return replaced, nil
+}
+
+func TestProcessAvatarOutOfOrder(t *testing.T) {
+	ctx := context.Background()
+	store := &fakeAvatarStore{user: db.User{ID: 1}}
+	files := storage.NewLocalFileStore(t.TempDir())
+	processor := &RedisTaskProcessor{store: store, files: files}
+
+	var original bytes.Buffer
+	require.NoError(t, png.Encode(&original, image.NewRGBA(image.Rect(0, 0, 8, 8))))
+
+	process := func(uploadID string) {
+		payload, err := json.Marshal(PayloadProcessAvatar{UserID: 1, UploadID: uploadID})
+		require.NoError(t, err)
+		err = processor.ProcessTaskProcessAvatar(ctx,
+			asynq.NewTask(TaskProcessAvatar, payload))
+		require.NoError(t, err)
+	}
+	exists := func(key string) bool {
+		file, err := files.Open(ctx, key)
+		if errors.Is(err, storage.ErrNotFound) {
+			return false
+		}
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+		return true
+	}
+
+	// both uploads are accepted, "new" last
+	for _, uploadID := range []string{"old", "new"} {
+		require.NoError(t, files.Put(ctx, AvatarOriginalKey(1, uploadID),
+			bytes.NewReader(original.Bytes())))
+		store.user.AvatarUploadID = pgtype.Text{String: uploadID, Valid: true}
+	}
+
+	largest := AvatarSizes[len(AvatarSizes)-1]
+	process("new")
+	require.Equal(t, "/"+AvatarThumbnailKey(1, "new", largest),
+		store.user.ProfilePictureUrl.String)
+
+	// the older upload's retry finishes last and only cleans up after itself
+	process("old")
+	require.Equal(t, "/"+AvatarThumbnailKey(1, "new", largest),
+		store.user.ProfilePictureUrl.String)
+	for _, size := range AvatarSizes {
+		require.True(t, exists(AvatarThumbnailKey(1, "new", size)))
+		require.False(t, exists(AvatarThumbnailKey(1, "old", size)))
+	}
+	require.False(t, exists(AvatarOriginalKey(1, "old")))
+	require.False(t, exists(AvatarOriginalKey(1, "new")))
+}
@@ -0,0 +1,278 @@
+package worker
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/storage"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultDataExportLinkDuration = 24 * time.Hour
+	// conversations read from the database at once
+	dataExportPageSize = 100
+)
+
+// files in a data export archive
+const (
+	DataExportProfileFile       = "profile.json"
+	DataExportSessionsFile      = "sessions.json"
+	DataExportConversationsFile = "conversations/%d.json"
+)
+
+// DataExporter builds data export archives and keeps them in file storage
+type DataExporter struct {
+	files        storage.FileStore
+	linkDuration time.Duration
+}
+
+// NewDataExporter returns an exporter whose download links
+// stay valid for linkDuration, a day when it is zero
+func NewDataExporter(files storage.FileStore,
+	linkDuration time.Duration) *DataExporter {
+	if linkDuration == 0 {
+		linkDuration = defaultDataExportLinkDuration
+	}
+
+	return &DataExporter{
+		files:        files,
+		linkDuration: linkDuration,
+	}
+}
+
+// DataExportFileKey is where the archive of an export is stored
+func DataExportFileKey(userID, exportID int64) string {
+	return fmt.Sprintf("exports/%d/%d.zip", userID, exportID)
+}
+
+// save writes the archive of user straight into file storage
+func (exporter *DataExporter) save(ctx context.Context, store db.Store,
+	key string, user db.User) error {
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(writeDataExport(ctx, store, writer, user))
+	}()
+
+	err := exporter.files.Put(ctx, key, reader)
+	// stops the writer if storage gave up early
+	reader.CloseWithError(err)
+
+	return err
+}
+
+// deleteFiles removes the archives of exports, e.g. once the account is gone
+func (exporter *DataExporter) deleteFiles(ctx context.Context,
+	exports []db.DataExport) {
+	for _, export := range exports {
+		if !export.FileKey.Valid {
+			continue
+		}
+
+		err := exporter.files.Delete(ctx, export.FileKey.String)
+		if err != nil {
+			log.Error().Err(err).Int64("export_id", export.ExportID).
+				Msg("failed to delete data export")
+		}
+	}
+}
+
+type exportProfile struct {
+	ID                int64      `json:"id"`
+	Username          string     `json:"username"`
+	Email             string     `json:"email"`
+	IsEmailVerified   bool       `json:"is_email_verified"`
+	ProfilePictureUrl *string    `json:"profile_picture_url"`
+	Role              string     `json:"role"`
+	IsBanned          bool       `json:"is_banned"`
+	BannedReason      *string    `json:"banned_reason,omitempty"`
+	LastSeenAt        *time.Time `json:"last_seen_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+	ExportedAt        time.Time  `json:"exported_at"`
+}
+
+// refresh tokens stay out of the archive
+type exportSession struct {
+	ID        string    `json:"id"`
+	UserAgent string    `json:"user_agent"`
+	ClientIP  string    `json:"client_ip"`
+	IsBlocked bool      `json:"is_blocked"`
+	ExpiredAt time.Time `json:"expired_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type exportParticipant struct {
+	UserID     int64      `json:"user_id"`
+	Username   string     `json:"username"`
+	JoinedAt   time.Time  `json:"joined_at"`
+	LastReadAt *time.Time `json:"last_read_at"`
+}
+
+// messages are exported as stored, still end-to-end encrypted
+type exportMessage struct {
+	MessageID        int64     `json:"message_id"`
+	SenderID         int64     `json:"sender_id"`
+	SenderUsername   string    `json:"sender_username"`
+	EncryptedContent string    `json:"encrypted_content"`
+	SentAt           time.Time `json:"sent_at"`
+}
+
+type exportConversation struct {
+	ConversationID int64               `json:"conversation_id"`
+	CreatedAt      time.Time           `json:"created_at"`
+	UpdatedAt      time.Time           `json:"updated_at"`
+	Participants   []exportParticipant `json:"participants"`
+	Messages       []exportMessage     `json:"messages"`
+}
+
+// writes the ZIP archive: the profile, the sessions
+// and one file per conversation with all its messages
+func writeDataExport(ctx context.Context, store db.Store, w io.Writer,
+	user db.User) error {
+	archive := zip.NewWriter(w)
+
+	err := writeJSONFile(archive, DataExportProfileFile, exportProfile{
+		ID:                user.ID,
+		Username:          user.Username,
+		Email:             user.Email,
+		IsEmailVerified:   user.IsEmailVerified,
+		ProfilePictureUrl: textPtr(user.ProfilePictureUrl),
+		Role:              user.Role,
+		IsBanned:          user.IsBanned,
+		BannedReason:      textPtr(user.BannedReason),
+		LastSeenAt:        timestamptzPtr(user.LastSeenAt),
+		CreatedAt:         user.CreatedAt,
+		ExportedAt:        time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	sessions, err := store.ListUserSessions(ctx, user.Username)
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	exportSessions := make([]exportSession, 0, len(sessions))
+	for _, session := range sessions {
+		exportSessions = append(exportSessions, exportSession{
+			ID:        session.ID.String(),
+			UserAgent: session.UserAgent,
+			ClientIP:  session.ClientIp,
+			IsBlocked: session.IsBlocked,
+			ExpiredAt: session.ExpiredAt,
+			CreatedAt: session.CreatedAt,
+		})
+	}
+
+	err = writeJSONFile(archive, DataExportSessionsFile, exportSessions)
+	if err != nil {
+		return err
+	}
+
+	for offset := int32(0); ; offset += dataExportPageSize {
+		conversations, err := store.GetUserConversations(ctx,
+			db.GetUserConversationsParams{
+				UserID: user.ID,
+				Limit:  dataExportPageSize,
+				Offset: offset,
+			})
+		if err != nil {
+			return fmt.Errorf("failed to list conversations: %w", err)
+		}
+
+		for _, conversation := range conversations {
+			err = writeExportConversation(ctx, store, archive, conversation)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(conversations) < dataExportPageSize {
+			break
+		}
+	}
+
+	return archive.Close()
+}
+
+func writeExportConversation(ctx context.Context, store db.Store,
+	archive *zip.Writer, conversation db.GetUserConversationsRow) error {
+	participants, err := store.GetConversationWithParticipants(ctx,
+		conversation.ConversationsID)
+	if err != nil {
+		return fmt.Errorf("failed to get participants: %w", err)
+	}
+
+	// every message, oldest first
+	messages, err := store.GetMessagesSince(ctx, db.GetMessagesSinceParams{
+		ConversationID: conversation.ConversationsID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get messages: %w", err)
+	}
+
+	export := exportConversation{
+		ConversationID: conversation.ConversationsID,
+		CreatedAt:      conversation.CreatedAt,
+		UpdatedAt:      conversation.UpdatedAt,
+		Participants:   make([]exportParticipant, 0, len(participants)),
+		Messages:       make([]exportMessage, 0, len(messages)),
+	}
+	for _, participant := range participants {
+		export.Participants = append(export.Participants, exportParticipant{
+			UserID:     participant.ParticipantID,
+			Username:   participant.ParticipantUsername,
+			JoinedAt:   participant.JoinedAt,
+			LastReadAt: timestamptzPtr(participant.LastReadAt),
+		})
+	}
+	for _, message := range messages {
+		export.Messages = append(export.Messages, exportMessage{
+			MessageID:        message.MessagesID,
+			SenderID:         message.SenderID,
+			SenderUsername:   message.SenderUsername,
+			EncryptedContent: message.EncryptedContent,
+			SentAt:           message.SentAt,
+		})
+	}
+
+	return writeJSONFile(archive,
+		fmt.Sprintf(DataExportConversationsFile, conversation.ConversationsID), export)
+}
+
+func writeJSONFile(archive *zip.Writer, name string, v any) error {
+	file, err := archive.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to add %s: %w", name, err)
+	}
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(v)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+
+	return nil
+}
+
+func textPtr(value pgtype.Text) *string {
+	if !value.Valid {
+		return nil
+	}
+	return &value.String
+}
+
+func timestamptzPtr(value pgtype.Timestamptz) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Time
+}
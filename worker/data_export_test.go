@@ -0,0 +1,177 @@
+package worker
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/storage"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeExportStore returns one session and one conversation
+type fakeExportStore struct {
+	db.Store
+}
+
+func (store *fakeExportStore) ListUserSessions(ctx context.Context,
+	username string) ([]db.Session, error) {
+	return []db.Session{{
+		ID:           uuid.New(),
+		Username:     username,
+		RefreshToken: "refresh-token",
+		UserAgent:    "test",
+		ClientIp:     "127.0.0.1",
+		ExpiredAt:    time.Now().Add(time.Hour),
+		CreatedAt:    time.Now(),
+	}}, nil
+}
+
+func (store *fakeExportStore) GetUserConversations(ctx context.Context,
+	arg db.GetUserConversationsParams) ([]db.GetUserConversationsRow, error) {
+	if arg.Offset > 0 {
+		return nil, nil
+	}
+	return []db.GetUserConversationsRow{{ConversationsID: 7}}, nil
+}
+
+func (store *fakeExportStore) GetConversationWithParticipants(ctx context.Context,
+	conversationsID int64) ([]db.GetConversationWithParticipantsRow, error) {
+	return []db.GetConversationWithParticipantsRow{
+		{ConversationsID: conversationsID, ParticipantID: 1, ParticipantUsername: "alice"},
+		{ConversationsID: conversationsID, ParticipantID: 2, ParticipantUsername: "bob"},
+	}, nil
+}
+
+func (store *fakeExportStore) GetMessagesSince(ctx context.Context,
+	arg db.GetMessagesSinceParams) ([]db.GetMessagesSinceRow, error) {
+	return []db.GetMessagesSinceRow{
+		{MessagesID: 1, ConversationID: arg.ConversationID, SenderID: 1,
+			SenderUsername: "alice", EncryptedContent: "ciphertext-1"},
+		{MessagesID: 2, ConversationID: arg.ConversationID, SenderID: 2,
+			SenderUsername: "bob", EncryptedContent: "ciphertext-2"},
+	}, nil
+}
+
+func TestWriteDataExport(t *testing.T) {
+	user := db.User{
+		ID:           1,
+		Username:     "alice",
+		Email:        "alice@example.com",
+		PasswordHash: "password-hash",
+		Role:         "customer",
+	}
+
+	var buf bytes.Buffer
+	err := writeDataExport(context.Background(), &fakeExportStore{}, &buf, user)
+	require.NoError(t, err)
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	files := map[string][]byte{}
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		files[file.Name] = content
+	}
+	conversationFile := fmt.Sprintf(DataExportConversationsFile, 7)
+	require.Len(t, files, 3)
+	require.Contains(t, files, DataExportProfileFile)
+	require.Contains(t, files, DataExportSessionsFile)
+	require.Contains(t, files, conversationFile)
+
+	var profile exportProfile
+	require.NoError(t, json.Unmarshal(files[DataExportProfileFile], &profile))
+	require.Equal(t, user.Email, profile.Email)
+	require.NotContains(t, string(files[DataExportProfileFile]), "password-hash")
+
+	var sessions []exportSession
+	require.NoError(t, json.Unmarshal(files[DataExportSessionsFile], &sessions))
+	require.Len(t, sessions, 1)
+	require.NotContains(t, string(files[DataExportSessionsFile]), "refresh-token")
+
+	var conversation exportConversation
+	require.NoError(t, json.Unmarshal(files[conversationFile], &conversation))
+	require.Equal(t, int64(7), conversation.ConversationID)
+	require.Len(t, conversation.Participants, 2)
+	require.Len(t, conversation.Messages, 2)
+	require.Equal(t, "ciphertext-1", conversation.Messages[0].EncryptedContent)
+}
+
+// fakeExpiryStore has one ready export
+type fakeExpiryStore struct {
+	db.Store
+	export db.DataExport
+}
+
+func (store *fakeExpiryStore) GetDataExport(ctx context.Context,
+	exportID int64) (db.DataExport, error) {
+	return store.export, nil
+}
+
+func (store *fakeExpiryStore) ExpireDataExport(ctx context.Context,
+	exportID int64) error {
+	store.export.Status = DataExportExpired
+	return nil
+}
+
+// fakeExpiryDistributor records scheduled expiries
+type fakeExpiryDistributor struct {
+	TaskDistributor
+	expiries []PayloadExpireDataExport
+	opts     []asynq.Option
+}
+
+func (distributor *fakeExpiryDistributor) DistributeTaskExpireDataExport(
+	ctx context.Context, payload *PayloadExpireDataExport,
+	opts ...asynq.Option) error {
+	distributor.expiries = append(distributor.expiries, *payload)
+	distributor.opts = opts
+	return nil
+}
+
+func TestExpireDataExport(t *testing.T) {
+	ctx := context.Background()
+	files := storage.NewLocalFileStore(t.TempDir())
+	fileKey := DataExportFileKey(1, 3)
+	require.NoError(t, files.Put(ctx, fileKey, bytes.NewReader([]byte("archive"))))
+
+	expiresAt := time.Now().Add(time.Hour)
+	store := &fakeExpiryStore{export: db.DataExport{
+		ExportID:  3,
+		UserID:    1,
+		Status:    DataExportReady,
+		FileKey:   pgtype.Text{String: fileKey, Valid: true},
+		ExpiresAt: pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	}}
+	distributor := &fakeExpiryDistributor{}
+	processor := &RedisTaskProcessor{store: store, files: files,
+		distributor: distributor}
+
+	// the archive goes when the link does
+	require.NoError(t, processor.scheduleDataExportExpiry(ctx, store.export))
+	require.Equal(t, []PayloadExpireDataExport{{ExportID: 3}}, distributor.expiries)
+	require.Contains(t, distributor.opts, asynq.ProcessAt(expiresAt))
+
+	payload, err := json.Marshal(PayloadExpireDataExport{ExportID: 3})
+	require.NoError(t, err)
+	err = processor.ProcessTaskExpireDataExport(ctx,
+		asynq.NewTask(TaskExpireDataExport, payload))
+	require.NoError(t, err)
+	require.Equal(t, DataExportExpired, store.export.Status)
+
+	_, err = files.Open(ctx, fileKey)
+	require.ErrorIs(t, err, storage.ErrNotFound)
+}
@@ -14,6 +14,46 @@ type TaskDistributor interface {
 		payload *PayloadSendVerifyEmail,
 		opts ...asynq.Option,
 	) error
+	DistributeTaskSendResetPassword(
+		ctx context.Context,
+		payload *PayloadSendResetPassword,
+		opts ...asynq.Option,
+	) error
+	DistributeTaskSendEmailChanged(
+		ctx context.Context,
+		payload *PayloadSendEmailChanged,
+		opts ...asynq.Option,
+	) error
+	DistributeTaskSendAccountLocked(
+		ctx context.Context,
+		payload *PayloadSendAccountLocked,
+		opts ...asynq.Option,
+	) error
+	DistributeTaskDeliverWebhook(
+		ctx context.Context,
+		payload *PayloadDeliverWebhook,
+		opts ...asynq.Option,
+	) error
+	DistributeTaskPurgeAccount(
+		ctx context.Context,
+		payload *PayloadPurgeAccount,
+		opts ...asynq.Option,
+	) error
+	DistributeTaskExportUserData(
+		ctx context.Context,
+		payload *PayloadExportUserData,
+		opts ...asynq.Option,
+	) error
+	DistributeTaskProcessAvatar(
+		ctx context.Context,
+		payload *PayloadProcessAvatar,
+		opts ...asynq.Option,
+	) error
+	DistributeTaskExpireDataExport(
+		ctx context.Context,
+		payload *PayloadExpireDataExport,
+		opts ...asynq.Option,
+	) error
 }
 
 type RedisTaskDistributor struct {
@@ -2,10 +2,12 @@ package worker
 
 import (
 	"context"
+	"time"
 
 	"github.com/hibiken/asynq"
 	"github.com/kratos069/message-app/db/sqlc/db-gen"
 	"github.com/kratos069/message-app/mail"
+	"github.com/kratos069/message-app/storage"
 	"github.com/rs/zerolog/log"
 )
 
@@ -22,16 +24,54 @@ type TaskProcessor interface {
 		ctx context.Context,
 		task *asynq.Task,
 	) error
+	ProcessTaskSendResetPassword(
+		ctx context.Context,
+		task *asynq.Task,
+	) error
+	ProcessTaskSendEmailChanged(
+		ctx context.Context,
+		task *asynq.Task,
+	) error
+	ProcessTaskSendAccountLocked(
+		ctx context.Context,
+		task *asynq.Task,
+	) error
+	ProcessTaskDeliverWebhook(
+		ctx context.Context,
+		task *asynq.Task,
+	) error
+	ProcessTaskPurgeAccount(
+		ctx context.Context,
+		task *asynq.Task,
+	) error
+	ProcessTaskExportUserData(
+		ctx context.Context,
+		task *asynq.Task,
+	) error
+	ProcessTaskProcessAvatar(
+		ctx context.Context,
+		task *asynq.Task,
+	) error
+	ProcessTaskExpireDataExport(
+		ctx context.Context,
+		task *asynq.Task,
+	) error
 }
 
 type RedisTaskProcessor struct {
-	server *asynq.Server
-	store  db.Store
-	mailer mail.EmailSender
+	server      *asynq.Server
+	store       db.Store
+	mailer      mail.EmailSender
+	webhooks    *WebhookSender
+	exports     *DataExporter
+	files       storage.FileStore
+	distributor TaskDistributor
 }
 
 func NewRedisTaskProcessor(redisOpt asynq.RedisClientOpt,
-	store db.Store, mailer mail.EmailSender) TaskProcessor {
+	store db.Store, mailer mail.EmailSender,
+	webhooks *WebhookSender, exports *DataExporter,
+	files storage.FileStore, distributor TaskDistributor) TaskProcessor {
 	server := asynq.NewServer(
 		redisOpt,
 		asynq.Config{
@@ -44,14 +84,25 @@ func NewRedisTaskProcessor(redisOpt asynq.RedisClientOpt,
 					log.Error().Err(err).Str("type", task.Type()).
 						Bytes("payload", task.Payload()).Msg("process task failed")
 				}),
+			// webhooks back off exponentially, see webhookRetryDelay
+			RetryDelayFunc: func(n int, err error, task *asynq.Task) time.Duration {
+				if task.Type() == TaskDeliverWebhook {
+					return webhookRetryDelay(n)
+				}
+				return asynq.DefaultRetryDelayFunc(n, err, task)
+			},
 			Logger: NewLogger(),
 		}, //asynq default config wil be used when empty
 	)
 
 	return &RedisTaskProcessor{
-		server: server,
-		store:  store,
-		mailer: mailer,
+		server:      server,
+		store:       store,
+		mailer:      mailer,
+		webhooks:    webhooks,
+		exports:     exports,
+		files:       files,
+		distributor: distributor,
 	}
 }
 
@@ -64,6 +115,14 @@ func (processor *RedisTaskProcessor) Start() error {
 	mux := asynq.NewServeMux()
 
 	mux.HandleFunc(TaskSendVerifyEmail, processor.ProcessTaskSendVerifyEmail)
+	mux.HandleFunc(TaskSendResetPassword, processor.ProcessTaskSendResetPassword)
+	mux.HandleFunc(TaskSendEmailChanged, processor.ProcessTaskSendEmailChanged)
+	mux.HandleFunc(TaskSendAccountLocked, processor.ProcessTaskSendAccountLocked)
+	mux.HandleFunc(TaskDeliverWebhook, processor.ProcessTaskDeliverWebhook)
+	mux.HandleFunc(TaskPurgeAccount, processor.ProcessTaskPurgeAccount)
+	mux.HandleFunc(TaskExportUserData, processor.ProcessTaskExportUserData)
+	mux.HandleFunc(TaskProcessAvatar, processor.ProcessTaskProcessAvatar)
+	mux.HandleFunc(TaskExpireDataExport, processor.ProcessTaskExpireDataExport)
 
 	return processor.server.Start(mux)
 }
@@ -0,0 +1,139 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/rs/zerolog/log"
+)
+
+const TaskDeliverWebhook = "task:deliver_webhook"
+
+// status of a delivery in the delivery log
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// the event itself is in the delivery log, the task only points to it
+type PayloadDeliverWebhook struct {
+	DeliveryID int64 `json:"delivery_id"`
+}
+
+// will add tasks to the queue
+func (distributor *RedisTaskDistributor) DistributeTaskDeliverWebhook(
+	ctx context.Context,
+	payload *PayloadDeliverWebhook,
+	opts ...asynq.Option,
+) error {
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	// created task
+	task := asynq.NewTask(TaskDeliverWebhook, jsonPayload, opts...)
+
+	// enqueued task
+	taskInfo, err := distributor.client.EnqueueContext(ctx, task)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue task: %w", err)
+	}
+
+	log.Info().Str("type", task.Type()).Bytes("payload", task.Payload()).
+		Str("queue", taskInfo.Queue).Int("max_retry", taskInfo.MaxRetry).
+		Msg("enqueued task")
+
+	return nil
+}
+
+// will take tasks from the queue and process them,
+// every attempt is recorded in the delivery log
+func (processor *RedisTaskProcessor) ProcessTaskDeliverWebhook(
+	ctx context.Context,
+	task *asynq.Task,
+) error {
+	var payload PayloadDeliverWebhook
+
+	err := json.Unmarshal(task.Payload(), &payload)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", asynq.SkipRetry)
+	}
+
+	delivery, err := processor.store.GetWebhookDelivery(ctx, payload.DeliveryID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("delivery doesn't exist: %w", asynq.SkipRetry)
+		}
+		return fmt.Errorf("failed to get delivery: %w", err)
+	}
+	// e.g. the task ran again after the result was recorded
+	if delivery.Status != WebhookDeliveryPending {
+		return nil
+	}
+
+	webhook, err := processor.store.GetWebhook(ctx, delivery.WebhookID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("webhook doesn't exist: %w", asynq.SkipRetry)
+		}
+		return fmt.Errorf("failed to get webhook: %w", err)
+	}
+
+	// disabled while the delivery was waiting
+	if !webhook.IsActive {
+		_, err = processor.store.RecordWebhookAttempt(ctx, db.RecordWebhookAttemptParams{
+			DeliveryID: delivery.DeliveryID,
+			Status:     WebhookDeliveryFailed,
+			LastError:  pgtype.Text{String: "webhook is disabled", Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record attempt: %w", err)
+		}
+		return nil
+	}
+
+	statusCode, sendErr := processor.webhooks.Send(ctx, webhook.Url, webhook.Secret,
+		delivery.DeliveryID, delivery.Event, delivery.Payload)
+
+	arg := db.RecordWebhookAttemptParams{
+		DeliveryID: delivery.DeliveryID,
+		Status:     WebhookDeliverySucceeded,
+		ResponseStatus: pgtype.Int4{
+			Int32: int32(statusCode),
+			Valid: statusCode != 0,
+		},
+	}
+	if sendErr != nil {
+		arg.Status = WebhookDeliveryPending
+		arg.LastError = pgtype.Text{String: sendErr.Error(), Valid: true}
+
+		// asynq gives up after this attempt
+		retried, _ := asynq.GetRetryCount(ctx)
+		maxRetry, _ := asynq.GetMaxRetry(ctx)
+		if retried >= maxRetry {
+			arg.Status = WebhookDeliveryFailed
+		}
+	}
+
+	_, err = processor.store.RecordWebhookAttempt(ctx, arg)
+	if err != nil {
+		return fmt.Errorf("failed to record attempt: %w", err)
+	}
+
+	if sendErr != nil {
+		return sendErr
+	}
+
+	log.Info().Str("type", task.Type()).Bytes("payload", task.Payload()).
+		Int64("webhook_id", webhook.WebhookID).Int("status", statusCode).
+		Msg("processed task")
+
+	return nil
+}
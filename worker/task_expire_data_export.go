@@ -0,0 +1,109 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/rs/zerolog/log"
+)
+
+const TaskExpireDataExport = "task:expire_data_export"
+
+// removes an export's archive once its download link expired
+type PayloadExpireDataExport struct {
+	ExportID int64 `json:"export_id"`
+}
+
+// will add tasks to the queue
+func (distributor *RedisTaskDistributor) DistributeTaskExpireDataExport(
+	ctx context.Context,
+	payload *PayloadExpireDataExport,
+	opts ...asynq.Option,
+) error {
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	// created task
+	task := asynq.NewTask(TaskExpireDataExport, jsonPayload, opts...)
+
+	// enqueued task
+	taskInfo, err := distributor.client.EnqueueContext(ctx, task)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue task: %w", err)
+	}
+
+	log.Info().Str("type", task.Type()).Bytes("payload", task.Payload()).
+		Str("queue", taskInfo.Queue).Int("max_retry", taskInfo.MaxRetry).
+		Msg("enqueued task")
+
+	return nil
+}
+
+// will take tasks from the queue and process them
+func (processor *RedisTaskProcessor) ProcessTaskExpireDataExport(
+	ctx context.Context,
+	task *asynq.Task,
+) error {
+	var payload PayloadExpireDataExport
+
+	err := json.Unmarshal(task.Payload(), &payload)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", asynq.SkipRetry)
+	}
+
+	export, err := processor.store.GetDataExport(ctx, payload.ExportID)
+	if err != nil {
+		// the user was purged, and the archive with them
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("failed to get data export: %w", err)
+	}
+	if export.Status != DataExportReady || !export.FileKey.Valid {
+		return nil
+	}
+
+	// the row is only marked once the archive is really gone,
+	// a failed delete is retried
+	err = processor.files.Delete(ctx, export.FileKey.String)
+	if err != nil {
+		return fmt.Errorf("failed to delete data export: %w", err)
+	}
+
+	err = processor.store.ExpireDataExport(ctx, export.ExportID)
+	if err != nil {
+		return fmt.Errorf("failed to expire data export: %w", err)
+	}
+
+	log.Info().Str("type", task.Type()).Bytes("payload", task.Payload()).
+		Int64("user_id", export.UserID).Msg("processed task")
+
+	return nil
+}
+
+// the archive is deleted when the download link expires
+func (processor *RedisTaskProcessor) scheduleDataExportExpiry(
+	ctx context.Context, export db.DataExport) error {
+	opts := []asynq.Option{
+		asynq.MaxRetry(10),
+		asynq.ProcessAt(export.ExpiresAt.Time),
+		asynq.Queue(QueueDefault),
+		// scheduled again when the export task is retried
+		asynq.TaskID(fmt.Sprintf("expire_data_export:%d", export.ExportID)),
+	}
+
+	err := processor.distributor.DistributeTaskExpireDataExport(ctx,
+		&PayloadExpireDataExport{ExportID: export.ExportID}, opts...)
+	if err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+		return fmt.Errorf("failed to schedule data export expiry: %w", err)
+	}
+
+	return nil
+}
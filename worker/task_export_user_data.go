@@ -0,0 +1,167 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/util"
+	"github.com/rs/zerolog/log"
+)
+
+const TaskExportUserData = "task:export_user_data"
+
+// status of a data export
+const (
+	DataExportPending = "pending"
+	DataExportReady   = "ready"
+	DataExportFailed  = "failed"
+	DataExportExpired = "expired"
+)
+
+type PayloadExportUserData struct {
+	ExportID int64 `json:"export_id"`
+}
+
+// will add tasks to the queue
+func (distributor *RedisTaskDistributor) DistributeTaskExportUserData(
+	ctx context.Context,
+	payload *PayloadExportUserData,
+	opts ...asynq.Option,
+) error {
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	// created task
+	task := asynq.NewTask(TaskExportUserData, jsonPayload, opts...)
+
+	// enqueued task
+	taskInfo, err := distributor.client.EnqueueContext(ctx, task)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue task: %w", err)
+	}
+
+	log.Info().Str("type", task.Type()).Bytes("payload", task.Payload()).
+		Str("queue", taskInfo.Queue).Int("max_retry", taskInfo.MaxRetry).
+		Msg("enqueued task")
+
+	return nil
+}
+
+// will take tasks from the queue and process them, builds the archive
+// and emails the user a link to download it
+func (processor *RedisTaskProcessor) ProcessTaskExportUserData(
+	ctx context.Context,
+	task *asynq.Task,
+) error {
+	var payload PayloadExportUserData
+
+	err := json.Unmarshal(task.Payload(), &payload)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", asynq.SkipRetry)
+	}
+
+	export, err := processor.store.GetDataExport(ctx, payload.ExportID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("data export doesn't exist: %w", asynq.SkipRetry)
+		}
+		return fmt.Errorf("failed to get data export: %w", err)
+	}
+	// e.g. the task ran again after the link was sent, only the
+	// expiry may still have to be scheduled
+	if export.Status == DataExportReady {
+		return processor.scheduleDataExportExpiry(ctx, export)
+	}
+	if export.Status != DataExportPending {
+		return nil
+	}
+
+	user, err := processor.store.GetUserByID(ctx, export.UserID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("user doesn't exist: %w", asynq.SkipRetry)
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	fileKey := DataExportFileKey(user.ID, export.ExportID)
+	err = processor.exports.save(ctx, processor.store, fileKey, user)
+	if err != nil {
+		return processor.failDataExport(ctx, export,
+			fmt.Errorf("failed to save data export: %w", err))
+	}
+
+	exportToken, prefix := util.GenerateDataExportToken()
+	expiresAt := time.Now().Add(processor.exports.linkDuration)
+
+	// the link is sent before it is stored, a failed email is
+	// retried with a new link instead of leaving one nobody got
+	subject := "Your Message App data export is ready"
+	downloadUrl := fmt.Sprintf("http://localhost:8080/exports/%s", exportToken)
+	content := fmt.Sprintf(`Hello %s, <br/>
+	The copy of your data you asked for is ready. <br/>
+	Please <a href="%s"> click here</a> to download it, the link expires on %s. <br/>
+	If you didn't ask for this, please change your password. <br/>
+	`, user.Username, downloadUrl, expiresAt.UTC().Format(time.RFC1123))
+	to := []string{user.Email}
+
+	err = processor.mailer.SendEmail(subject, content, to, nil, nil, nil)
+	if err != nil {
+		return processor.failDataExport(ctx, export,
+			fmt.Errorf("failed to send data export email: %w", err))
+	}
+
+	export, err = processor.store.CompleteDataExport(ctx, db.CompleteDataExportParams{
+		ExportID:  export.ExportID,
+		FileKey:   pgtype.Text{String: fileKey, Valid: true},
+		Prefix:    pgtype.Text{String: prefix, Valid: true},
+		TokenHash: pgtype.Text{String: util.HashSecret(exportToken), Valid: true},
+		ExpiresAt: pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to complete data export: %w", err)
+	}
+
+	err = processor.scheduleDataExportExpiry(ctx, export)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("type", task.Type()).Bytes("payload", task.Payload()).
+		Str("email", user.Email).Msg("processed task")
+
+	return nil
+}
+
+// marks the export failed once asynq gives up on it,
+// so the user can ask for a new one
+func (processor *RedisTaskProcessor) failDataExport(ctx context.Context,
+	export db.DataExport, taskErr error) error {
+	retried, _ := asynq.GetRetryCount(ctx)
+	maxRetry, _ := asynq.GetMaxRetry(ctx)
+	if retried < maxRetry {
+		return taskErr
+	}
+
+	err := processor.store.FailDataExport(ctx, export.ExportID)
+	if err != nil {
+		return fmt.Errorf("failed to mark data export failed: %w", err)
+	}
+
+	// the archive may have been saved before the email failed
+	export.FileKey = pgtype.Text{
+		String: DataExportFileKey(export.UserID, export.ExportID),
+		Valid:  true,
+	}
+	processor.exports.deleteFiles(ctx, []db.DataExport{export})
+
+	return taskErr
+}
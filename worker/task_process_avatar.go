@@ -0,0 +1,174 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/storage"
+	"github.com/rs/zerolog/log"
+)
+
+const TaskProcessAvatar = "task:process_avatar"
+
+// the upload itself waits in file storage, see AvatarOriginalKey
+type PayloadProcessAvatar struct {
+	UserID   int64  `json:"user_id"`
+	UploadID string `json:"upload_id"`
+}
+
+// will add tasks to the queue
+func (distributor *RedisTaskDistributor) DistributeTaskProcessAvatar(
+	ctx context.Context,
+	payload *PayloadProcessAvatar,
+	opts ...asynq.Option,
+) error {
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	// created task
+	task := asynq.NewTask(TaskProcessAvatar, jsonPayload, opts...)
+
+	// enqueued task
+	taskInfo, err := distributor.client.EnqueueContext(ctx, task)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue task: %w", err)
+	}
+
+	log.Info().Str("type", task.Type()).Bytes("payload", task.Payload()).
+		Str("queue", taskInfo.Queue).Int("max_retry", taskInfo.MaxRetry).
+		Msg("enqueued task")
+
+	return nil
+}
+
+// will take tasks from the queue and process them, resizes the upload
+// into thumbnails and makes the largest one the profile picture
+func (processor *RedisTaskProcessor) ProcessTaskProcessAvatar(
+	ctx context.Context,
+	task *asynq.Task,
+) error {
+	var payload PayloadProcessAvatar
+
+	err := json.Unmarshal(task.Payload(), &payload)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", asynq.SkipRetry)
+	}
+
+	originalKey := AvatarOriginalKey(payload.UserID, payload.UploadID)
+	original, err := processor.readAvatar(ctx, originalKey)
+	if err != nil {
+		// e.g. the task ran again after the original was removed
+		if errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("avatar upload doesn't exist: %w", asynq.SkipRetry)
+		}
+		return err
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(original))
+	if err != nil {
+		processor.deleteFile(ctx, originalKey)
+		return fmt.Errorf("failed to decode avatar: %w", asynq.SkipRetry)
+	}
+
+	for _, size := range AvatarSizes {
+		thumbnail, err := avatarThumbnail(src, size)
+		if err != nil {
+			return err
+		}
+
+		err = processor.files.Put(ctx,
+			AvatarThumbnailKey(payload.UserID, payload.UploadID, size),
+			bytes.NewReader(thumbnail))
+		if err != nil {
+			return fmt.Errorf("failed to save thumbnail: %w", err)
+		}
+	}
+
+	// tasks finish out of order after retries, an older upload must
+	// neither replace a newer one nor delete its thumbnails
+	largest := AvatarSizes[len(AvatarSizes)-1]
+	replaced, err := processor.store.ReplaceAvatar(ctx, db.ReplaceAvatarParams{
+		ID:       payload.UserID,
+		UploadID: pgtype.Text{String: payload.UploadID, Valid: true},
+		ProfilePictureUrl: pgtype.Text{
+			String: "/" + AvatarThumbnailKey(payload.UserID, payload.UploadID, largest),
+			Valid:  true,
+		},
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// a newer upload was accepted since, or the user is gone
+			processor.deleteAvatar(ctx, payload.UserID, payload.UploadID)
+			processor.deleteFile(ctx, originalKey)
+			log.Info().Str("type", task.Type()).Bytes("payload", task.Payload()).
+				Msg("dropped stale avatar upload")
+			return nil
+		}
+		return fmt.Errorf("failed to update profile picture: %w", err)
+	}
+
+	processor.deleteFile(ctx, originalKey)
+
+	// the previous avatar is no longer linked from anywhere
+	if userID, uploadID, ok := parseAvatarURL(replaced.String); ok &&
+		userID == payload.UserID && uploadID != payload.UploadID {
+		processor.deleteAvatar(ctx, userID, uploadID)
+	}
+
+	log.Info().Str("type", task.Type()).Bytes("payload", task.Payload()).
+		Int64("user_id", payload.UserID).Msg("processed task")
+
+	return nil
+}
+
+func (processor *RedisTaskProcessor) readAvatar(ctx context.Context,
+	key string) ([]byte, error) {
+	file, err := processor.files.Open(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	original, err := io.ReadAll(io.LimitReader(file, MaxAvatarSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read avatar: %w", err)
+	}
+
+	// checked again, the header decides how much memory decoding takes
+	if len(original) > MaxAvatarSize {
+		processor.deleteFile(ctx, key)
+		return nil, fmt.Errorf("%w: %w", ErrAvatarTooBig, asynq.SkipRetry)
+	}
+	if err := ValidateAvatar(bytes.NewReader(original)); err != nil {
+		processor.deleteFile(ctx, key)
+		return nil, fmt.Errorf("%w: %w", err, asynq.SkipRetry)
+	}
+
+	return original, nil
+}
+
+// removes the thumbnails of an upload
+func (processor *RedisTaskProcessor) deleteAvatar(ctx context.Context,
+	userID int64, uploadID string) {
+	for _, size := range AvatarSizes {
+		processor.deleteFile(ctx, AvatarThumbnailKey(userID, uploadID, size))
+	}
+}
+
+func (processor *RedisTaskProcessor) deleteFile(ctx context.Context, key string) {
+	err := processor.files.Delete(ctx, key)
+	if err != nil {
+		log.Error().Err(err).Str("key", key).Msg("failed to delete file")
+	}
+}
@@ -0,0 +1,95 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/rs/zerolog/log"
+)
+
+const TaskPurgeAccount = "task:purge_account"
+
+// removes an account once its deletion grace period is over
+type PayloadPurgeAccount struct {
+	UserID int64 `json:"user_id"`
+	// deletion_requested_at in unix microseconds, a newer
+	// request schedules a purge of its own
+	RequestedAt int64 `json:"requested_at"`
+}
+
+// will add tasks to the queue
+func (distributor *RedisTaskDistributor) DistributeTaskPurgeAccount(
+	ctx context.Context,
+	payload *PayloadPurgeAccount,
+	opts ...asynq.Option,
+) error {
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	// created task
+	task := asynq.NewTask(TaskPurgeAccount, jsonPayload, opts...)
+
+	// enqueued task
+	taskInfo, err := distributor.client.EnqueueContext(ctx, task)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue task: %w", err)
+	}
+
+	log.Info().Str("type", task.Type()).Bytes("payload", task.Payload()).
+		Str("queue", taskInfo.Queue).Int("max_retry", taskInfo.MaxRetry).
+		Msg("enqueued task")
+
+	return nil
+}
+
+// will take tasks from the queue and process them
+func (processor *RedisTaskProcessor) ProcessTaskPurgeAccount(
+	ctx context.Context,
+	task *asynq.Task,
+) error {
+	var payload PayloadPurgeAccount
+
+	err := json.Unmarshal(task.Payload(), &payload)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", asynq.SkipRetry)
+	}
+
+	// the rows go with the user, files in storage have to be removed separately
+	exports, err := processor.store.ListDataExportsByUser(ctx, payload.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to list data exports: %w", err)
+	}
+
+	result, err := processor.store.PurgeUserTx(ctx, db.PurgeUserTxParams{
+		UserID:      payload.UserID,
+		RequestedAt: time.UnixMicro(payload.RequestedAt),
+	})
+	if err != nil {
+		// cancelled by logging in, or already purged
+		if errors.Is(err, db.ErrDeletionCancelled) || errors.Is(err, pgx.ErrNoRows) {
+			log.Info().Str("type", task.Type()).Bytes("payload", task.Payload()).
+				Msg("account deletion no longer pending, skipped")
+			return nil
+		}
+		return fmt.Errorf("failed to purge account: %w", err)
+	}
+
+	processor.exports.deleteFiles(ctx, exports)
+	if userID, uploadID, ok := parseAvatarURL(
+		result.User.ProfilePictureUrl.String); ok && userID == result.User.ID {
+		processor.deleteAvatar(ctx, userID, uploadID)
+	}
+
+	log.Info().Str("type", task.Type()).Bytes("payload", task.Payload()).
+		Str("username", result.User.Username).Msg("processed task")
+
+	return nil
+}
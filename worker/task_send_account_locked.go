@@ -0,0 +1,93 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+)
+
+const TaskSendAccountLocked = "task:send_account_locked"
+
+// tells the user their account was locked after too many failed logins
+type PayloadSendAccountLocked struct {
+	Username    string `json:"username"`
+	LockedUntil int64  `json:"locked_until"`
+}
+
+// will add tasks to the queue
+func (distributor *RedisTaskDistributor) DistributeTaskSendAccountLocked(
+	ctx context.Context,
+	payload *PayloadSendAccountLocked,
+	opts ...asynq.Option,
+) error {
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	// created task
+	task := asynq.NewTask(TaskSendAccountLocked, jsonPayload, opts...)
+
+	// enqueued task
+	taskInfo, err := distributor.client.EnqueueContext(ctx, task)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue task: %w", err)
+	}
+
+	log.Info().Str("type", task.Type()).Bytes("payload", task.Payload()).
+		Str("queue", taskInfo.Queue).Int("max_retry", taskInfo.MaxRetry).
+		Msg("enqueued task")
+
+	return nil
+}
+
+// will take tasks from the queue and process them
+func (processor *RedisTaskProcessor) ProcessTaskSendAccountLocked(
+	ctx context.Context,
+	task *asynq.Task,
+) error {
+	var payload PayloadSendAccountLocked
+
+	err := json.Unmarshal(task.Payload(), &payload)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", asynq.SkipRetry)
+	}
+
+	// get user from database
+	user, err := processor.store.GetUserByUsername(ctx, payload.Username)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("user doesn't exist: %w", asynq.SkipRetry)
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	// Send Email to user
+	subject := "Your Message App account was locked"
+	content := fmt.Sprintf(`Hello %s, <br/>
+	We locked your account after too many failed login attempts. <br/>
+	You can log in again after %s (UTC). <br/>
+	If this wasn't you, someone may be guessing your password,
+	consider resetting it. <br/>
+	`, user.Username, formatUnixUTC(payload.LockedUntil))
+	to := []string{user.Email}
+
+	err = processor.mailer.SendEmail(subject, content, to, nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to send account locked email: %w", err)
+	}
+
+	log.Info().Str("type", task.Type()).Bytes("payload", task.Payload()).
+		Str("email", user.Email).Msg("processed task")
+
+	return nil
+}
+
+func formatUnixUTC(seconds int64) string {
+	return time.Unix(seconds, 0).UTC().Format("2006-01-02 15:04")
+}
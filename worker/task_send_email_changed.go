@@ -0,0 +1,89 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog/log"
+)
+
+const TaskSendEmailChanged = "task:send_email_changed"
+
+// notifies the previous address that the account email was changed
+type PayloadSendEmailChanged struct {
+	Username string `json:"username"`
+	OldEmail string `json:"old_email"`
+	NewEmail string `json:"new_email"`
+}
+
+// will add tasks to the queue
+func (distributor *RedisTaskDistributor) DistributeTaskSendEmailChanged(
+	ctx context.Context,
+	payload *PayloadSendEmailChanged,
+	opts ...asynq.Option,
+) error {
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	// created task
+	task := asynq.NewTask(TaskSendEmailChanged, jsonPayload, opts...)
+
+	// enqueued task
+	taskInfo, err := distributor.client.EnqueueContext(ctx, task)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue task: %w", err)
+	}
+
+	log.Info().Str("type", task.Type()).Bytes("payload", task.Payload()).
+		Str("queue", taskInfo.Queue).Int("max_retry", taskInfo.MaxRetry).
+		Msg("enqueued task")
+
+	return nil
+}
+
+// will take tasks from the queue and process them
+func (processor *RedisTaskProcessor) ProcessTaskSendEmailChanged(
+	ctx context.Context,
+	task *asynq.Task,
+) error {
+	var payload PayloadSendEmailChanged
+
+	err := json.Unmarshal(task.Payload(), &payload)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", asynq.SkipRetry)
+	}
+
+	// Send Email to the previous address
+	subject := "Your Message App email was changed"
+	content := fmt.Sprintf(`Hello %s, <br/>
+	The email address of your account was changed to %s. <br/>
+	If you didn't make this change, please reset your password
+	and contact support right away. <br/>
+	`, payload.Username, maskEmail(payload.NewEmail))
+	to := []string{payload.OldEmail}
+
+	err = processor.mailer.SendEmail(subject, content, to, nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to send email changed notice: %w", err)
+	}
+
+	log.Info().Str("type", task.Type()).Bytes("payload", task.Payload()).
+		Str("email", payload.OldEmail).Msg("processed task")
+
+	return nil
+}
+
+// hides most of the local part, e.g. "jo***@example.com"
+func maskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at <= 2 {
+		return "***" + email[max(at, 0):]
+	}
+
+	return email[:2] + "***" + email[at:]
+}
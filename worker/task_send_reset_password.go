@@ -0,0 +1,99 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+	"github.com/kratos069/message-app/db/sqlc/db-gen"
+	"github.com/kratos069/message-app/util"
+	"github.com/rs/zerolog/log"
+)
+
+const TaskSendResetPassword = "task:send_reset_password"
+
+type PayloadSendResetPassword struct {
+	Username string `json:"username"`
+}
+
+// will add tasks to the queue
+func (distributor *RedisTaskDistributor) DistributeTaskSendResetPassword(
+	ctx context.Context,
+	payload *PayloadSendResetPassword,
+	opts ...asynq.Option,
+) error {
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	// created task
+	task := asynq.NewTask(TaskSendResetPassword, jsonPayload, opts...)
+
+	// enqueued task
+	taskInfo, err := distributor.client.EnqueueContext(ctx, task)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue task: %w", err)
+	}
+
+	log.Info().Str("type", task.Type()).Bytes("payload", task.Payload()).
+		Str("queue", taskInfo.Queue).Int("max_retry", taskInfo.MaxRetry).
+		Msg("enqueued task")
+
+	return nil
+}
+
+// will take tasks from the queue and process them
+func (processor *RedisTaskProcessor) ProcessTaskSendResetPassword(
+	ctx context.Context,
+	task *asynq.Task,
+) error {
+	var payload PayloadSendResetPassword
+
+	err := json.Unmarshal(task.Payload(), &payload)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", asynq.SkipRetry)
+	}
+
+	// get user from database
+	user, err := processor.store.GetUserByUsername(ctx, payload.Username)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("user doesn't exist: %w", asynq.SkipRetry)
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	// create single-use reset code in DB
+	passwordReset, err := processor.store.CreatePasswordReset(ctx, db.CreatePasswordResetParams{
+		Username:   user.Username,
+		Email:      user.Email,
+		SecretCode: util.RandomString(10),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create password reset: %w", err)
+	}
+
+	// Send Email to user, only the code: the reset itself is a POST
+	// the app makes together with the new password
+	subject := "Reset your Message App password"
+	content := fmt.Sprintf(`Hello %s, <br/>
+	We received a request to reset your password. <br/>
+	Your reset code is <b>%s</b>, it expires in 15 minutes. <br/>
+	Enter it in the app to choose a new password. <br/>
+	If you didn't ask for this, you can ignore this email. <br/>
+	`, user.Username, passwordReset.SecretCode)
+	to := []string{user.Email}
+
+	err = processor.mailer.SendEmail(subject, content, to, nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to send reset password email: %w", err)
+	}
+
+	log.Info().Str("type", task.Type()).Bytes("payload", task.Payload()).
+		Str("email", user.Email).Msg("processed task")
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// headers of every webhook delivery
+const (
+	WebhookHeaderEvent     = "X-Webhook-Event"
+	WebhookHeaderDelivery  = "X-Webhook-Delivery"
+	WebhookHeaderTimestamp = "X-Webhook-Timestamp"
+	WebhookHeaderSignature = "X-Webhook-Signature"
+)
+
+const (
+	defaultWebhookTimeout = 10 * time.Second
+	webhookBaseDelay      = 10 * time.Second
+	webhookMaxDelay       = time.Hour
+	// part of a response body kept in the delivery log
+	webhookMaxErrorBody = 512
+)
+
+var errPrivateNetwork = errors.New("webhook address is in a private network")
+
+// WebhookSender posts signed webhook deliveries
+type WebhookSender struct {
+	client *http.Client
+}
+
+// NewWebhookSender returns a sender which refuses to connect to loopback,
+// private and link-local addresses unless allowPrivateNetworks is set,
+// so webhooks can't be used to reach internal services
+func NewWebhookSender(timeout time.Duration,
+	allowPrivateNetworks bool) *WebhookSender {
+	if timeout == 0 {
+		timeout = defaultWebhookTimeout
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivateNetworks {
+		// checked on the resolved address, so DNS can't sneak one in
+		dialer.Control = func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || isPrivateIP(ip) {
+				return errPrivateNetwork
+			}
+			return nil
+		}
+	}
+
+	return &WebhookSender{
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				TLSHandshakeTimeout: timeout,
+			},
+			// a redirect could point anywhere, receivers get
+			// the delivery at the registered URL or not at all
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Send posts body to url and returns the response status,
+// any status other than 2xx is an error
+func (sender *WebhookSender) Send(ctx context.Context, url, secret string,
+	deliveryID int64, event string, body []byte) (int, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url,
+		bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "MessageApp-Webhooks/1.0")
+	req.Header.Set(WebhookHeaderEvent, event)
+	req.Header.Set(WebhookHeaderDelivery, strconv.FormatInt(deliveryID, 10))
+	req.Header.Set(WebhookHeaderTimestamp, timestamp)
+	req.Header.Set(WebhookHeaderSignature, SignWebhook(secret, timestamp, body))
+
+	resp, err := sender.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, webhookMaxErrorBody))
+		return resp.StatusCode, fmt.Errorf("webhook responded with %d: %s",
+			resp.StatusCode, respBody)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// SignWebhook returns the signature header of a delivery,
+// receivers recompute it over "<timestamp>.<body>" with their secret
+// and should reject old timestamps to stop replays
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// exponential backoff between delivery attempts: 10s, 20s, 40s...
+func webhookRetryDelay(retried int) time.Duration {
+	if retried > 16 {
+		return webhookMaxDelay
+	}
+
+	return min(webhookBaseDelay<<retried, webhookMaxDelay)
+}
+
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast()
+}
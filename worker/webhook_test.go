@@ -0,0 +1,106 @@
+package worker
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookSenderSend(t *testing.T) {
+	secret := "secret"
+	body := []byte(`{"event":"ping"}`)
+
+	var received *http.Request
+	var receivedBody []byte
+	receiver := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			received = r
+			receivedBody, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+	defer receiver.Close()
+
+	sender := NewWebhookSender(time.Second, true)
+
+	status, err := sender.Send(context.Background(), receiver.URL, secret,
+		42, "ping", body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNoContent, status)
+
+	require.Equal(t, http.MethodPost, received.Method)
+	require.Equal(t, body, receivedBody)
+	require.Equal(t, "ping", received.Header.Get(WebhookHeaderEvent))
+	require.Equal(t, "42", received.Header.Get(WebhookHeaderDelivery))
+
+	// the receiver can verify the signature with its copy of the secret
+	timestamp := received.Header.Get(WebhookHeaderTimestamp)
+	require.NotEmpty(t, timestamp)
+	require.Equal(t, SignWebhook(secret, timestamp, body),
+		received.Header.Get(WebhookHeaderSignature))
+	require.NotEqual(t, SignWebhook("other", timestamp, body),
+		received.Header.Get(WebhookHeaderSignature))
+}
+
+func TestWebhookSenderErrorStatus(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "boom", http.StatusInternalServerError)
+		}))
+	defer receiver.Close()
+
+	sender := NewWebhookSender(time.Second, true)
+
+	status, err := sender.Send(context.Background(), receiver.URL, "secret",
+		1, "ping", []byte(`{}`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "boom")
+	require.Equal(t, http.StatusInternalServerError, status)
+}
+
+func TestWebhookSenderNoRedirects(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			t.Error("redirect was followed")
+		}))
+	defer target.Close()
+
+	receiver := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, target.URL, http.StatusTemporaryRedirect)
+		}))
+	defer receiver.Close()
+
+	sender := NewWebhookSender(time.Second, true)
+
+	status, err := sender.Send(context.Background(), receiver.URL, "secret",
+		1, "ping", []byte(`{}`))
+	require.Error(t, err)
+	require.Equal(t, http.StatusTemporaryRedirect, status)
+}
+
+func TestWebhookSenderPrivateNetwork(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			t.Error("private address was reached")
+		}))
+	defer receiver.Close()
+
+	sender := NewWebhookSender(time.Second, false)
+
+	_, err := sender.Send(context.Background(), receiver.URL, "secret",
+		1, "ping", []byte(`{}`))
+	require.ErrorIs(t, err, errPrivateNetwork)
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	require.Equal(t, webhookBaseDelay, webhookRetryDelay(0))
+	require.Equal(t, 2*webhookBaseDelay, webhookRetryDelay(1))
+	require.Equal(t, 4*webhookBaseDelay, webhookRetryDelay(2))
+	require.Equal(t, webhookMaxDelay, webhookRetryDelay(10))
+	require.Equal(t, webhookMaxDelay, webhookRetryDelay(100))
+}